@@ -11,19 +11,28 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/vitistack/gslb-operator/internal/api/handlers/deletions"
+	"github.com/vitistack/gslb-operator/internal/api/handlers/dnsdist"
 	"github.com/vitistack/gslb-operator/internal/api/handlers/failover"
+	"github.com/vitistack/gslb-operator/internal/api/handlers/history"
+	"github.com/vitistack/gslb-operator/internal/api/handlers/maintenance"
+	"github.com/vitistack/gslb-operator/internal/api/handlers/rejections"
 	"github.com/vitistack/gslb-operator/internal/api/handlers/spoofs"
 	"github.com/vitistack/gslb-operator/internal/api/routes"
 	"github.com/vitistack/gslb-operator/internal/config"
 	"github.com/vitistack/gslb-operator/internal/dns"
 	"github.com/vitistack/gslb-operator/internal/dns/update"
+	"github.com/vitistack/gslb-operator/internal/kubernetes"
 	"github.com/vitistack/gslb-operator/internal/manager"
 	"github.com/vitistack/gslb-operator/internal/model"
 	"github.com/vitistack/gslb-operator/internal/repositories/service"
+	"github.com/vitistack/gslb-operator/internal/source"
 	"github.com/vitistack/gslb-operator/pkg/auth"
 	"github.com/vitistack/gslb-operator/pkg/auth/jwt"
 	"github.com/vitistack/gslb-operator/pkg/bslog"
 	"github.com/vitistack/gslb-operator/pkg/lua"
+	maintenanceModel "github.com/vitistack/gslb-operator/pkg/models/maintenance"
+	spoofsModel "github.com/vitistack/gslb-operator/pkg/models/spoofs"
 	"github.com/vitistack/gslb-operator/pkg/persistence/store/file"
 	"github.com/vitistack/gslb-operator/pkg/rest/middleware"
 )
@@ -41,7 +50,22 @@ func main() {
 	cfg := config.GetInstance()
 
 	// initialize lua execution environment
-	if err := lua.LoadSandboxConfig(cfg.Server().LuaSandbox()); err != nil {
+	luaPoolWait, err := cfg.Server().LuaPoolWait()
+	if err != nil {
+		bslog.Fatal("invalid lua pool wait", slog.Any("reason", err))
+	}
+	luaTimeout, err := cfg.Server().LuaTimeout()
+	if err != nil {
+		bslog.Fatal("invalid lua timeout", slog.Any("reason", err))
+	}
+	if err := lua.LoadSandboxConfig(cfg.Server().LuaSandbox(),
+		lua.WithPoolSize(cfg.Server().LuaPoolSize()),
+		lua.WithPoolWait(time.Duration(luaPoolWait)),
+		lua.WithTimeout(time.Duration(luaTimeout)),
+		lua.WithMaxInstructions(cfg.Server().LuaMaxInstructions()),
+		lua.WithMaxStackSize(cfg.Server().LuaMaxStack()),
+		lua.WithMaxStringSize(cfg.Server().LuaMaxString()),
+	); err != nil {
 		bslog.Fatal("could not load lua configuration", slog.Any("reason", err))
 	}
 
@@ -51,12 +75,25 @@ func main() {
 	}
 	svcRepo := service.NewServiceRepo(serviceFileStore)
 
-	// creating dns - handler objects
-	zoneFetcher := dns.NewZoneFetcherWithAutoPoll()
+	maintenanceFileStore, err := file.NewStore[maintenanceModel.Window]("./data/maintenance.json")
+	if err != nil {
+		bslog.Fatal("could not create persistent storage", slog.String("reason", err.Error()))
+	}
+
+	roundtripImprovement, err := cfg.GSLB().RoundtripMinImprovement()
+	if err != nil {
+		bslog.Fatal("invalid roundtrip min improvement", slog.Any("reason", err))
+	}
+	roundtripDwell, err := cfg.GSLB().RoundtripDwell()
+	if err != nil {
+		bslog.Fatal("invalid roundtrip dwell", slog.Any("reason", err))
+	}
 	mgr := manager.NewManager(
 		manager.WithMinRunningWorkers(80),
 		manager.WithNonBlockingBufferSize(50),
 		manager.WithServiceRepository(svcRepo),
+		manager.WithMaintenanceStore(maintenanceFileStore),
+		manager.WithRoundtripHysteresis(roundtripImprovement, time.Duration(roundtripDwell)),
 		//manager.WithDryRun(true),
 	)
 
@@ -65,10 +102,43 @@ func main() {
 		bslog.Fatal("unable to create updater", slog.String("error", err.Error()))
 	}
 
+	// sources of GSLB - configs, the first source wins on conflicting service ids
+	configSources := make([]source.ConfigSource, 0)
+	if cfg.GSLB().Zone() != "" {
+		tsig, err := dns.NewTSIGKey(cfg.GSLB().TSIGKeyName(), cfg.GSLB().TSIGAlgorithm(), cfg.GSLB().TSIGSecret())
+		if err != nil {
+			bslog.Fatal("invalid TSIG key", slog.String("reason", err.Error()))
+		}
+		configSources = append(configSources, dns.NewZoneFetcherWithAutoPoll(dns.WithTSIG(tsig)))
+	}
+
+	var controller *kubernetes.Controller
+	if cfg.Kubernetes().Enabled() {
+		client, err := kubernetes.NewInClusterClient(kubernetes.WithNamespace(cfg.Kubernetes().Namespace()))
+		if err != nil {
+			bslog.Fatal("unable to create kubernetes client", slog.String("reason", err.Error()))
+		}
+		controller = kubernetes.NewController(client, mgr)
+		configSources = append(configSources, controller)
+	}
+
+	if dir := cfg.GSLB().ConfigDir(); dir != "" {
+		configSources = append(configSources, source.NewDirectorySource(dir, source.DEFAULT_DIRECTORY_POLL_INTERVAL))
+	}
+
+	if file := cfg.GSLB().ConfigFile(); file != "" {
+		configSources = append(configSources, source.NewFileSource(file))
+	}
+
+	if len(configSources) == 0 {
+		bslog.Fatal("no source of GSLB - configs configured")
+	}
+
 	dnsHandler := dns.NewHandler(
-		zoneFetcher,
+		source.NewMergedSource(configSources...),
 		mgr,
 		updater,
+		dns.WithDeleteGuard(cfg.GSLB().DeleteGuardPercent()),
 	)
 
 	background := context.Background()
@@ -87,17 +157,44 @@ func main() {
 	api := http.NewServeMux()
 
 	// routes handlers
-	spoofsApiService := spoofs.NewSpoofsService(serviceFileStore, mgr)
+	overrideFileStore, err := file.NewStore[spoofsModel.Override]("./data/overrides.json")
+	if err != nil {
+		bslog.Fatal("could not create persistent storage", slog.String("reason", err.Error()))
+	}
+
+	overrideHistoryFileStore, err := file.NewStore[spoofsModel.OverrideEvent]("./data/override-history.json")
+	if err != nil {
+		bslog.Fatal("could not create persistent storage", slog.String("reason", err.Error()))
+	}
+
+	spoofsApiService := spoofs.NewSpoofsService(serviceFileStore, overrideFileStore, overrideHistoryFileStore, mgr, updater)
+	go spoofsApiService.ExpireOverrides(ctx)
 
 	failoverApiService := failover.NewFailoverService(mgr)
 
+	deletionsApiService := deletions.NewDeletionsService(dnsHandler)
+
+	rejectionsApiService := rejections.NewRejectionsService(dnsHandler)
+
+	historyApiService := history.NewHistoryService(mgr)
+
+	maintenanceApiService := maintenance.NewMaintenanceService(mgr)
+
+	dnsdistApiService := dnsdist.NewDNSDISTService(updater)
+
 	// initializing the service jwt self signer
 	jwt.InitServiceTokenManager(cfg.JWT().Secret(), cfg.JWT().User())
 
 	api.HandleFunc(routes.POST_FAILOVER, middleware.Chain(
 		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
 	)(failoverApiService.FailoverService))
 
+	api.HandleFunc(routes.DELETE_FAILOVER, middleware.Chain(
+		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
+	)(failoverApiService.ReleaseFailover))
+
 	api.HandleFunc(routes.GET_SPOOFS, middleware.Chain(
 		middleware.WithIncomingRequestLogging(slog.Default()),
 		auth.WithTokenValidation(slog.Default()),
@@ -113,22 +210,80 @@ func main() {
 		auth.WithTokenValidation(slog.Default()),
 	)(spoofsApiService.GetSpoofsHash))
 
-	// spoofs/override
-	// TODO: add auth!
+	api.HandleFunc(routes.GET_DELETIONS, middleware.Chain(
+		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
+	)(deletionsApiService.GetPendingDeletion))
+
+	api.HandleFunc(routes.POST_DELETIONS_CONFIRM, middleware.Chain(
+		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
+	)(deletionsApiService.ConfirmDeletion))
+
+	api.HandleFunc(routes.GET_REJECTIONS, middleware.Chain(
+		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
+	)(rejectionsApiService.GetRejections))
+
+	api.HandleFunc(routes.GET_HISTORY, middleware.Chain(
+		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
+	)(historyApiService.GetHistory))
+
+	api.HandleFunc(routes.GET_MAINTENANCE, middleware.Chain(
+		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
+	)(maintenanceApiService.GetMaintenance))
+
+	api.HandleFunc(routes.POST_MAINTENANCE, middleware.Chain(
+		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
+	)(maintenanceApiService.CreateMaintenance))
+
+	api.HandleFunc(routes.DELETE_MAINTENANCE, middleware.Chain(
+		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
+	)(maintenanceApiService.DeleteMaintenance))
+
+	api.HandleFunc(routes.GET_DNSDIST_SERVERS, middleware.Chain(
+		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
+	)(dnsdistApiService.GetServers))
+
+	api.HandleFunc(routes.POST_DNSDIST_RESYNC, middleware.Chain(
+		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
+	)(dnsdistApiService.ResyncServers))
+
+	api.HandleFunc(routes.POST_DNSDIST_RESYNCID, middleware.Chain(
+		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
+	)(dnsdistApiService.ResyncServer))
+
+	// spoofs/override, authenticated so every change is attributed to a user
 	api.HandleFunc(routes.GET_OVERRIDE, middleware.Chain(
 		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
 	)(spoofsApiService.GetOverride))
 
+	api.HandleFunc(routes.GET_OVERRIDE_HISTORY, middleware.Chain(
+		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
+	)(spoofsApiService.GetOverrideHistory))
+
 	api.HandleFunc(routes.PUT_OVERRIDE, middleware.Chain(
 		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
 	)(spoofsApiService.UpdateOverride))
 
 	api.HandleFunc(routes.POST_OVERRIDE, middleware.Chain(
 		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
 	)(spoofsApiService.CreateOverride))
 
 	api.HandleFunc(routes.DELETE_OVERRIDE, middleware.Chain(
 		middleware.WithIncomingRequestLogging(slog.Default()),
+		auth.WithTokenValidation(slog.Default()),
 	)(spoofsApiService.DeleteOverride))
 
 	// metrics
@@ -161,6 +316,9 @@ func main() {
 	defer cancel()
 
 	dnsHandler.Stop(shutdown)
+	if controller != nil {
+		controller.Stop()
+	}
 	if err := server.Shutdown(shutdown); err != nil {
 		panic("error shutting down server: " + err.Error())
 	}
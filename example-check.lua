@@ -0,0 +1,35 @@
+--[[
+    - a lua check runs the script to probe the service itself, for protocols without a built-in check.
+      the script has the sandbox, and these tables to probe the address of the service with:
+        - tcp.connect([port]): conn, with conn:send(data), conn:receive(), conn:expect(text) and conn:close()
+        - http.get(path [, headers]) and http.post(path, body [, headers]): {status, body, headers}
+        - dns.query(name [, qtype [, port]]): {rcode, answers}, sent to port 53 of the service unless another port is given
+      every call returns nil and the reason when it fails, and is bounded by the timeout of the check.
+      the json and regexp helpers of validation scripts can be used too.
+
+    NOTE: the script must return true when the service is healthy, and may return false and a reason when it is not
+    NOTE: the script is stored in the GSLB - config, with check_type LUA
+]]
+
+-- SMTP banner
+local conn, err = tcp.connect()
+if not conn then return false, err end
+local banner, err = conn:expect("220")
+return banner ~= nil, err
+
+-- Redis PING
+local conn, err = tcp.connect()
+if not conn then return false, err end
+conn:send("PING\r\n")
+local pong, err = conn:expect("+PONG")
+return pong ~= nil, err
+
+-- HTTP with a token, sent to the service with the fqdn as Host header
+local resp, err = http.get("/healthz", { Authorization = "Bearer token" })
+if not resp then return false, err end
+return resp.status == 200 and string.find(resp.body, "ok") ~= nil
+
+-- DNS server of the service answers for its zone
+local resp, err = dns.query("example.com", "SOA")
+if not resp then return false, err end
+return resp.rcode == "NOERROR" and #resp.answers > 0
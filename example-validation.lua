@@ -7,6 +7,11 @@
     this allows the users to customize what a healthy response from the service looks like:
     NOTE: the script must return true/false 
     NOTE: the script is stored in the GSLB - config
+
+    every script also has these helpers on top of the sandbox:
+        - json.decode(text) and json.encode(value), which return nil and the reason when they fail
+        - regexp.match(pattern, text) and regexp.find(pattern, text), with RE2 patterns
+    and is stopped when it runs longer, more instructions or with more memory than the lua limits of the operator.
 */
 
 -- Check status code only
@@ -23,3 +28,11 @@ local status_ok = status_code >= 200 and status_code < 300
 local has_json = headers["Content-Type"] == "application/json"
 local valid_body = string.find(body, '"status":"ok"') ~= nil
 return status_ok and has_json and valid_body
+
+-- Check a field of a json body
+local health = json.decode(body)
+return status_code == 200 and health ~= nil and health.status == "ok"
+
+-- Check the version with a regexp
+local version = regexp.find([[^v(\d+)\.(\d+)]], headers["X-Version"] or "")
+return version ~= nil and tonumber(version[2]) >= 2
@@ -10,7 +10,9 @@ require (
 	github.com/prometheus/client_golang v1.23.2
 	github.com/tevino/tcp-shaker v0.0.0-20260210162928-fb888f26451b
 	github.com/yuin/gopher-lua v1.1.1
-	golang.org/x/crypto v0.48.0
+	go.yaml.in/yaml/v2 v2.4.3
+	golang.org/x/crypto v0.54.0
+	google.golang.org/grpc v1.84.0
 )
 
 require (
@@ -21,8 +23,9 @@ require (
 	github.com/prometheus/client_model v0.6.2 // indirect
 	github.com/prometheus/common v0.67.5 // indirect
 	github.com/prometheus/procfs v0.19.2 // indirect
-	go.yaml.in/yaml/v2 v2.4.3 // indirect
-	golang.org/x/net v0.50.0 // indirect
-	golang.org/x/sys v0.41.0 // indirect
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
 	google.golang.org/protobuf v1.36.11 // indirect
 )
@@ -0,0 +1,57 @@
+package deletions
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/vitistack/gslb-operator/internal/dns"
+	"github.com/vitistack/gslb-operator/pkg/bslog"
+	"github.com/vitistack/gslb-operator/pkg/models/deletions"
+	"github.com/vitistack/gslb-operator/pkg/rest/response"
+)
+
+// holds back removal of too many services, until it is confirmed
+type DeleteGuard interface {
+	PendingDeletion() (deletions.PendingDeletion, bool)
+	ConfirmDeletion() ([]string, error)
+}
+
+type DeletionsService struct {
+	guard DeleteGuard
+}
+
+func NewDeletionsService(guard DeleteGuard) *DeletionsService {
+	return &DeletionsService{
+		guard: guard,
+	}
+}
+
+func (ds *DeletionsService) GetPendingDeletion(w http.ResponseWriter, r *http.Request) {
+	pending, ok := ds.guard.PendingDeletion()
+	if !ok {
+		response.Err(w, response.ErrNotFound, "no pending deletion")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, pending)
+}
+
+func (ds *DeletionsService) ConfirmDeletion(w http.ResponseWriter, r *http.Request) {
+	logger := bslog.With(slog.Any("request_id", r.Context().Value("id")))
+
+	removed, err := ds.guard.ConfirmDeletion()
+	if err != nil {
+		if errors.Is(err, dns.ErrNoPendingDeletion) {
+			response.Err(w, response.ErrNotFound, "no pending deletion")
+			return
+		}
+
+		logger.Error("could not confirm pending deletion", slog.String("reason", err.Error()))
+		response.Err(w, response.ErrInternalError, "unable to confirm pending deletion")
+		return
+	}
+
+	logger.Info("confirmed pending deletion", slog.Int("removed", len(removed)))
+	response.JSON(w, http.StatusOK, deletions.Confirmation{Removed: removed})
+}
@@ -0,0 +1,68 @@
+package dnsdist
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/vitistack/gslb-operator/internal/dns/update"
+	"github.com/vitistack/gslb-operator/pkg/bslog"
+	"github.com/vitistack/gslb-operator/pkg/models/servers"
+	"github.com/vitistack/gslb-operator/pkg/rest/response"
+)
+
+// keeps the dnsdist servers in sync with the desired spoofs
+type Synchronizer interface {
+	Servers() []servers.ServerStatus
+	Resync(name string) (servers.ServerStatus, error)
+	ResyncAll() ([]servers.ServerStatus, error)
+}
+
+type DNSDISTService struct {
+	synchronizer Synchronizer
+}
+
+func NewDNSDISTService(synchronizer Synchronizer) *DNSDISTService {
+	return &DNSDISTService{
+		synchronizer: synchronizer,
+	}
+}
+
+// lists the reconciliation state of every dnsdist server
+func (ds *DNSDISTService) GetServers(w http.ResponseWriter, r *http.Request) {
+	response.JSON(w, http.StatusOK, ds.synchronizer.Servers())
+}
+
+// synchronizes a single dnsdist server without waiting for the next synchronization
+func (ds *DNSDISTService) ResyncServer(w http.ResponseWriter, r *http.Request) {
+	logger := bslog.With(slog.Any("request_id", r.Context().Value("id")))
+	name := r.PathValue("server")
+
+	status, err := ds.synchronizer.Resync(name)
+	if err != nil {
+		if errors.Is(err, update.ErrServerNotFound) {
+			response.Err(w, response.ErrNotFound, "server: "+name)
+			return
+		}
+
+		logger.Error("could not resync dnsdist server", slog.String("server_name", name), slog.String("reason", err.Error()))
+		response.Err(w, response.ErrInternalError, "unable to resync dnsdist server")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, status)
+}
+
+// synchronizes every dnsdist server without waiting for the next synchronization
+func (ds *DNSDISTService) ResyncServers(w http.ResponseWriter, r *http.Request) {
+	logger := bslog.With(slog.Any("request_id", r.Context().Value("id")))
+
+	statuses, err := ds.synchronizer.ResyncAll()
+	if err != nil {
+		logger.Error("could not resync dnsdist servers", slog.String("reason", err.Error()))
+		response.Err(w, response.ErrInternalError, "unable to resync dnsdist servers")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, statuses)
+}
@@ -1,6 +1,7 @@
 package failover
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 
@@ -42,9 +43,39 @@ func (fs *FailoverService) FailoverService(w http.ResponseWriter, r *http.Reques
 	err = fs.serviceManager.Failover(fqdn, failover)
 	if err != nil {
 		logger.Error("could not perform failover action", slog.String("reason", err.Error()))
+		if errors.Is(err, manager.ErrServiceGroupNotFound) {
+			response.Err(w, response.ErrNotFound, "group: "+fqdn)
+			return
+		}
+
 		response.Err(w, response.ErrInvalidInput, "unable to perform failover")
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 }
+
+func (fs *FailoverService) ReleaseFailover(w http.ResponseWriter, r *http.Request) {
+	logger := bslog.With(slog.Any("request_id", r.Context().Value("id")))
+	fqdn := r.PathValue("fqdn")
+
+	if fqdn == "" {
+		logger.Error("skipping request due to insufficient input parameters", slog.String("reason", "missing fqdn"))
+		response.Err(w, response.ErrInvalidInput, "missing fqdn")
+		return
+	}
+
+	err := fs.serviceManager.ReleaseFailover(fqdn)
+	if err != nil {
+		logger.Error("could not release failover", slog.String("reason", err.Error()))
+		if errors.Is(err, manager.ErrServiceGroupNotFound) || errors.Is(err, manager.ErrNoActiveFailover) {
+			response.Err(w, response.ErrNotFound, "no active failover for group: "+fqdn)
+			return
+		}
+
+		response.Err(w, response.ErrInternalError, "unable to release failover")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
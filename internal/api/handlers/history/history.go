@@ -0,0 +1,48 @@
+package history
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/vitistack/gslb-operator/internal/api/routes"
+	"github.com/vitistack/gslb-operator/internal/manager"
+	"github.com/vitistack/gslb-operator/pkg/bslog"
+	"github.com/vitistack/gslb-operator/pkg/models/history"
+	"github.com/vitistack/gslb-operator/pkg/rest/response"
+)
+
+// knows the health-check history of the members of a service group
+type HistoryLister interface {
+	History(memberOf string) ([]history.MemberHistory, error)
+}
+
+type HistoryService struct {
+	lister HistoryLister
+}
+
+func NewHistoryService(lister HistoryLister) *HistoryService {
+	return &HistoryService{
+		lister: lister,
+	}
+}
+
+// lists the last checks and the flap state of every member of a service group
+func (hs *HistoryService) GetHistory(w http.ResponseWriter, r *http.Request) {
+	logger := bslog.With(slog.Any("request_id", r.Context().Value("id")))
+	memberOf := r.PathValue(routes.MemberOf)
+
+	members, err := hs.lister.History(memberOf)
+	if err != nil {
+		if errors.Is(err, manager.ErrServiceGroupNotFound) {
+			response.Err(w, response.ErrNotFound, "group: "+memberOf)
+			return
+		}
+
+		logger.Error("could not get health-check history", slog.String("reason", err.Error()))
+		response.Err(w, response.ErrInternalError, "unable to get health-check history")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, members)
+}
@@ -0,0 +1,86 @@
+package maintenance
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/vitistack/gslb-operator/internal/manager"
+	"github.com/vitistack/gslb-operator/pkg/bslog"
+	"github.com/vitistack/gslb-operator/pkg/models/maintenance"
+	"github.com/vitistack/gslb-operator/pkg/rest/request"
+	"github.com/vitistack/gslb-operator/pkg/rest/response"
+)
+
+// takes members out of rotation for maintenance windows
+type Scheduler interface {
+	MaintenanceWindows() ([]maintenance.Window, error)
+	CreateMaintenance(window maintenance.Window) (maintenance.Window, error)
+	DeleteMaintenance(id string) error
+}
+
+type MaintenanceService struct {
+	scheduler Scheduler
+}
+
+func NewMaintenanceService(scheduler Scheduler) *MaintenanceService {
+	return &MaintenanceService{
+		scheduler: scheduler,
+	}
+}
+
+func (ms *MaintenanceService) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	logger := bslog.With(slog.Any("request_id", r.Context().Value("id")))
+
+	windows, err := ms.scheduler.MaintenanceWindows()
+	if err != nil {
+		logger.Error("could not list maintenance windows", slog.String("reason", err.Error()))
+		response.Err(w, response.ErrInternalError, "unable to list maintenance windows")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, windows)
+}
+
+func (ms *MaintenanceService) CreateMaintenance(w http.ResponseWriter, r *http.Request) {
+	logger := bslog.With(slog.Any("request_id", r.Context().Value("id")))
+
+	window := maintenance.Window{}
+	if err := request.JSONDECODE(r.Body, &window); err != nil {
+		logger.Error("could not decode request body", slog.String("reason", err.Error()))
+		response.Err(w, response.ErrInvalidInput, "invalid request format")
+		return
+	}
+
+	window, err := ms.scheduler.CreateMaintenance(window)
+	if err != nil {
+		if errors.Is(err, manager.ErrInvalidMaintenance) {
+			response.Err(w, response.ErrInvalidInput, err.Error())
+			return
+		}
+
+		logger.Error("could not create maintenance window", slog.String("reason", err.Error()))
+		response.Err(w, response.ErrInternalError, "unable to create maintenance window")
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, window)
+}
+
+func (ms *MaintenanceService) DeleteMaintenance(w http.ResponseWriter, r *http.Request) {
+	logger := bslog.With(slog.Any("request_id", r.Context().Value("id")))
+	id := r.PathValue("id")
+
+	if err := ms.scheduler.DeleteMaintenance(id); err != nil {
+		if errors.Is(err, manager.ErrMaintenanceWindowNotFound) {
+			response.Err(w, response.ErrNotFound, "maintenance window: "+id)
+			return
+		}
+
+		logger.Error("could not delete maintenance window", slog.String("reason", err.Error()))
+		response.Err(w, response.ErrInternalError, "unable to delete maintenance window")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,40 @@
+package rejections
+
+import (
+	"net/http"
+
+	"github.com/vitistack/gslb-operator/pkg/models/rejections"
+	"github.com/vitistack/gslb-operator/pkg/rest/response"
+)
+
+// knows the GSLB - configs that are not used, and why
+type RejectionLister interface {
+	Rejections() []rejections.Rejection
+}
+
+type RejectionsService struct {
+	lister RejectionLister
+}
+
+func NewRejectionsService(lister RejectionLister) *RejectionsService {
+	return &RejectionsService{
+		lister: lister,
+	}
+}
+
+// lists the rejected configs, optionally only the ones of a single service with ?serviceId=
+func (rs *RejectionsService) GetRejections(w http.ResponseWriter, r *http.Request) {
+	rejected := rs.lister.Rejections()
+
+	if serviceID := r.URL.Query().Get("serviceId"); serviceID != "" {
+		filtered := make([]rejections.Rejection, 0, 1)
+		for _, rejection := range rejected {
+			if rejection.ServiceID == serviceID {
+				filtered = append(filtered, rejection)
+			}
+		}
+		rejected = filtered
+	}
+
+	response.JSON(w, http.StatusOK, rejected)
+}
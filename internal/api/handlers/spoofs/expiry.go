@@ -0,0 +1,58 @@
+package spoofs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/vitistack/gslb-operator/pkg/bslog"
+	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
+)
+
+const DEFAULT_OVERRIDE_EXPIRY_PERIOD = time.Second * 10 // how often expired overrides are removed
+
+// removes expired overrides until ctx is done, the service groups fall back to their health-driven active member.
+// an override without an expiry is permanent, it stays until it is deleted
+func (ss *SpoofsService) ExpireOverrides(ctx context.Context) {
+	ticker := time.NewTicker(DEFAULT_OVERRIDE_EXPIRY_PERIOD)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			ss.expireOverrides(now)
+		}
+	}
+}
+
+func (ss *SpoofsService) expireOverrides(now time.Time) {
+	expired, err := ss.overrideRepo.Expired(now)
+	if err != nil {
+		bslog.Error("could not read expired overrides", slog.String("reason", err.Error()))
+		return
+	}
+
+	for _, override := range expired {
+		if err := ss.expireOverride(override.MemberOf, now); err != nil {
+			bslog.Error("could not remove expired override", slog.String("reason", err.Error()), slog.Any("override", override))
+		}
+	}
+}
+
+// removes the override of the service group if it is still expired at now, it may have been extended or deleted since it was read
+func (ss *SpoofsService) expireOverride(memberOf string, now time.Time) error {
+	defer ss.lockOverride(memberOf)()
+
+	override, found, err := ss.overrideRepo.Read(memberOf)
+	if err != nil {
+		return err
+	}
+	if !found || override.ExpiresAt.IsZero() || now.Before(override.ExpiresAt) {
+		return nil
+	}
+
+	_, err = ss.removeOverride(memberOf, spoofs.OVERRIDE_EXPIRED, "")
+	return err
+}
@@ -0,0 +1,159 @@
+package spoofs
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/dns/update"
+	"github.com/vitistack/gslb-operator/internal/manager"
+	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/internal/service"
+	"github.com/vitistack/gslb-operator/internal/utils/timesutil"
+	"github.com/vitistack/gslb-operator/pkg/models/failover"
+	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
+	"github.com/vitistack/gslb-operator/pkg/persistence/store/memory"
+)
+
+type activeManager struct {
+	manager.QueryManager
+	active *service.Service
+}
+
+func (m activeManager) GetActiveForMemberOf(string) *service.Service { return m.active }
+
+func (m activeManager) Failover(string, failover.Failover) error { return nil }
+
+// records the spoofs of the service groups that are pushed to dnsdist
+type recordingUpdater struct {
+	update.Updater
+	store  *memory.Store[model.GSLBServiceGroup]
+	pushed []spoofs.Spoof
+}
+
+func (u *recordingUpdater) OnOverride(memberOf string) (spoofs.FleetUpdate, error) {
+	group, _ := u.store.Load(memberOf)
+	u.pushed = append(u.pushed, group.Spoofs()...)
+	return spoofs.FleetUpdate{
+		MemberOf: memberOf,
+		Servers:  []spoofs.ServerUpdate{{Server: "dnsdist-1", Success: true}},
+		Quorum:   1,
+		Applied:  true,
+	}, nil
+}
+
+func TestSpoofsService_ExpireOverrides(t *testing.T) {
+	active, err := service.NewServiceFromGSLBConfig(model.GSLBConfig{
+		ServiceID:        "app-dc1",
+		MemberOf:         "app.example.com",
+		Ip:               "10.0.0.1",
+		Port:             "443",
+		Datacenter:       "DC1",
+		Interval:         timesutil.Duration(time.Second * 5),
+		FailureThreshold: 1,
+		CheckType:        "TCP-FULL",
+	})
+	if err != nil {
+		t.Fatalf("could not create service: %v", err)
+	}
+	active.OnSuccess()
+
+	store := memory.NewStore[model.GSLBServiceGroup]()
+	gslbService := active.GSLBService()
+	gslbService.IsActive = true
+	store.Save(gslbService.MemberOf, model.GSLBServiceGroup{*gslbService})
+
+	updater := &recordingUpdater{store: store}
+	ss := NewSpoofsService(store, memory.NewStore[spoofs.Override](), memory.NewStore[spoofs.OverrideEvent](), activeManager{active: active}, updater)
+
+	resp, err := ss.newOverride(spoofs.Override{
+		MemberOf:  "app.example.com",
+		IP:        net.ParseIP("10.0.0.99"),
+		ExpiresAt: time.Now().Add(time.Minute),
+		Reason:    "incident",
+	}, "OVERRIDER")
+	if err != nil {
+		t.Fatalf("could not create override: %v", err)
+	}
+	if servers := resp.Update.Servers; len(servers) != 1 || !servers[0].Success {
+		t.Errorf("expected the outcome per dnsdist server, got: %+v", servers)
+	}
+
+	ss.expireOverrides(time.Now())
+	if current, _ := ss.svcRepo.GetActive("app.example.com"); !current.HasOverride || current.IP != "10.0.0.99" {
+		t.Errorf("did not expect an override to expire early, got: %+v", current)
+	}
+
+	ss.expireOverrides(time.Now().Add(2 * time.Minute))
+	current, err := ss.svcRepo.GetActive("app.example.com")
+	if err != nil || current.HasOverride || current.IP != "10.0.0.1" {
+		t.Errorf("expected the health-driven active member to be restored, got: %+v: %v", current, err)
+	}
+	if _, found, _ := ss.overrideRepo.Read("app.example.com"); found {
+		t.Error("expected the expired override to be removed")
+	}
+	if len(updater.pushed) != 2 || updater.pushed[0].IP != "10.0.0.99" || updater.pushed[1].IP != "10.0.0.1" {
+		t.Errorf("expected both the override and its expiry to be pushed to dnsdist, got: %v", updater.pushed)
+	}
+
+	events, err := ss.overrideRepo.History(spoofs.OverrideHistoryParams{MemberOf: "app.example.com"})
+	if err != nil {
+		t.Fatalf("could not read override history: %v", err)
+	}
+	if len(events) != 2 || events[0].Action != spoofs.OVERRIDE_EXPIRED || events[1].User != "OVERRIDER" || events[1].Reason != "incident" {
+		t.Errorf("expected the creation and the expiry to be recorded, got: %+v", events)
+	}
+}
+
+func TestSpoofsService_PermanentOverride(t *testing.T) {
+	active, err := service.NewServiceFromGSLBConfig(model.GSLBConfig{
+		ServiceID:        "app-dc1",
+		MemberOf:         "app.example.com",
+		Ip:               "10.0.0.1",
+		Port:             "443",
+		Datacenter:       "DC1",
+		Interval:         timesutil.Duration(time.Second * 5),
+		FailureThreshold: 1,
+		CheckType:        "TCP-FULL",
+	})
+	if err != nil {
+		t.Fatalf("could not create service: %v", err)
+	}
+	active.OnSuccess()
+
+	store := memory.NewStore[model.GSLBServiceGroup]()
+	gslbService := active.GSLBService()
+	gslbService.IsActive = true
+	store.Save(gslbService.MemberOf, model.GSLBServiceGroup{*gslbService})
+
+	ss := NewSpoofsService(store, memory.NewStore[spoofs.Override](), memory.NewStore[spoofs.OverrideEvent](), activeManager{active: active}, &recordingUpdater{store: store})
+	if _, err := ss.newOverride(spoofs.Override{MemberOf: "app.example.com", IP: net.ParseIP("10.0.0.99")}, "OVERRIDER"); err != nil {
+		t.Fatalf("could not create override: %v", err)
+	}
+
+	ss.expireOverrides(time.Now().AddDate(1, 0, 0))
+	if current, _ := ss.svcRepo.GetActive("app.example.com"); !current.HasOverride || current.IP != "10.0.0.99" {
+		t.Errorf("expected an override without an expiry to stay, got: %+v", current)
+	}
+	if override, found, _ := ss.overrideRepo.Read("app.example.com"); !found || !override.ExpiresAt.IsZero() {
+		t.Errorf("expected the override to be kept without an expiry, got: %+v", override)
+	}
+
+	// the override is extended after the expiry read it as expired
+	later := time.Now().Add(2 * time.Minute)
+	if _, err := ss.updateOverride(spoofs.Override{MemberOf: "app.example.com", IP: net.ParseIP("10.0.0.99"), ExpiresAt: time.Now().Add(time.Minute)}, "OVERRIDER"); err != nil {
+		t.Fatalf("could not update override: %v", err)
+	}
+	if expired, _ := ss.overrideRepo.Expired(later); len(expired) != 1 {
+		t.Fatalf("expected the override to be expired, got: %+v", expired)
+	}
+	if _, err := ss.updateOverride(spoofs.Override{MemberOf: "app.example.com", IP: net.ParseIP("10.0.0.99"), ExpiresAt: time.Now().Add(time.Hour)}, "OVERRIDER"); err != nil {
+		t.Fatalf("could not update override: %v", err)
+	}
+	if err := ss.expireOverride("app.example.com", later); err != nil {
+		t.Fatalf("could not expire override: %v", err)
+	}
+	if current, _ := ss.svcRepo.GetActive("app.example.com"); !current.HasOverride {
+		t.Errorf("expected the extended override to stay, got: %+v", current)
+	}
+}
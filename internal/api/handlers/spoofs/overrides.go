@@ -5,17 +5,23 @@ package spoofs
 * this is meant to only be used in an emergency. and is generally considered a disruptive action, due to it being no checking.
 * be cautious when using this.
 * for a more gracefull approach, see failover.
+* overrides with an expiry expire, see expiry.go, overrides without one stay until they are deleted.
+* every change to them is kept in the override history.
+* changes are pushed to every dnsdist server right away, the response reports the outcome per server.
  */
 
 import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/vitistack/gslb-operator/internal/api/routes"
 	"github.com/vitistack/gslb-operator/internal/model"
 	spoofRepo "github.com/vitistack/gslb-operator/internal/repositories/spoof"
+	"github.com/vitistack/gslb-operator/pkg/auth"
 	"github.com/vitistack/gslb-operator/pkg/bslog"
 	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
 	"github.com/vitistack/gslb-operator/pkg/rest/request"
@@ -45,7 +51,40 @@ func (ss *SpoofsService) GetOverride(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = response.JSON(w, http.StatusOK, exist)
+	override, _, err := ss.overrideRepo.Read(memberOf)
+	if err != nil {
+		logger.Error("could not read override", slog.String("reason", err.Error()))
+		response.Err(w, response.ErrInternalError, "")
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, struct {
+		model.GSLBService
+		Override spoofs.Override `json:"override"`
+	}{exist, override})
+	if err != nil {
+		logger.Error("unable to create json response", slog.String("reason", err.Error()))
+	}
+}
+
+func (ss *SpoofsService) GetOverrideHistory(w http.ResponseWriter, r *http.Request) {
+	logger := bslog.With(slog.Any("request_id", r.Context().Value("id")))
+
+	params := spoofs.OverrideHistoryParams{}
+	if err := request.UnMarshallParams(r.URL.Query(), &params); err != nil {
+		logger.Error("unable to parse request parameters", slog.String("reason", err.Error()))
+		response.Err(w, response.ErrInvalidInput, "could not parse request parameters")
+		return
+	}
+
+	events, err := ss.overrideRepo.History(params)
+	if err != nil {
+		logger.Error("could not read override history", slog.String("reason", err.Error()))
+		response.Err(w, response.ErrInternalError, "unable to read override history")
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, events)
 	if err != nil {
 		logger.Error("unable to create json response", slog.String("reason", err.Error()))
 	}
@@ -62,7 +101,7 @@ func (ss *SpoofsService) CreateOverride(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = ss.newOverride(override)
+	resp, err := ss.newOverride(override, auth.User(r.Context()))
 	if err != nil {
 		logger.Error("could not override spoof", slog.String("reason", err.Error()))
 		if errors.Is(err, spoofRepo.ErrSpoofInServiceGroupNotFound) {
@@ -74,7 +113,10 @@ func (ss *SpoofsService) CreateOverride(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	err = response.JSON(w, http.StatusCreated, resp)
+	if err != nil {
+		logger.Error("unable to create json response", slog.String("reason", err.Error()))
+	}
 }
 
 func (ss *SpoofsService) UpdateOverride(w http.ResponseWriter, r *http.Request) {
@@ -88,7 +130,7 @@ func (ss *SpoofsService) UpdateOverride(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = ss.updateOverride(override)
+	resp, err := ss.updateOverride(override, auth.User(r.Context()))
 	if err != nil {
 		logger.Error("could not update spoof", slog.String("reason", err.Error()))
 		if errors.Is(err, spoofRepo.ErrSpoofInServiceGroupNotFound) {
@@ -100,7 +142,10 @@ func (ss *SpoofsService) UpdateOverride(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	err = response.JSON(w, http.StatusOK, resp)
+	if err != nil {
+		logger.Error("unable to create json response", slog.String("reason", err.Error()))
+	}
 }
 
 func (ss *SpoofsService) DeleteOverride(w http.ResponseWriter, r *http.Request) {
@@ -114,86 +159,202 @@ func (ss *SpoofsService) DeleteOverride(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = ss.deleteOverride(override)
+	resp, err := ss.deleteOverride(override.MemberOf, spoofs.OVERRIDE_DELETED, auth.User(r.Context()))
 	if err != nil {
 		logger.Error("could not delete overridden spoof", slog.String("reason", err.Error()))
 		response.Err(w, response.ErrInvalidInput, "unable to delete override")
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	err = response.JSON(w, http.StatusOK, resp)
+	if err != nil {
+		logger.Error("unable to create json response", slog.String("reason", err.Error()))
+	}
 }
 
-func (ss *SpoofsService) newOverride(override spoofs.Override) error {
+// overrides the spoofed ip of the service group, until override.ExpiresAt or for good if it is zero
+func (ss *SpoofsService) newOverride(override spoofs.Override, user string) (spoofs.OverrideResponse, error) {
+	defer ss.lockOverride(override.MemberOf)()
+
+	now := time.Now()
+	if !override.ExpiresAt.IsZero() && !override.ExpiresAt.After(now) {
+		return spoofs.OverrideResponse{}, fmt.Errorf("override for %s expires in the past: %s", override.MemberOf, override.ExpiresAt)
+	}
+
 	exist, err := ss.svcRepo.GetActive(override.MemberOf)
 	if err != nil {
-		return fmt.Errorf("unable to get active service for group: %s: %w", override.MemberOf, err)
+		return spoofs.OverrideResponse{}, fmt.Errorf("unable to get active service for group: %s: %w", override.MemberOf, err)
 	}
 
 	if exist.HasOverride {
-		return fmt.Errorf("service already has active override: %s", exist.MemberOf)
+		return spoofs.OverrideResponse{}, fmt.Errorf("service already has active override: %s", exist.MemberOf)
+	}
+
+	// the record is saved before the service is flagged, so a flagged service always has an override record
+	override.CreatedBy = user
+	override.CreatedAt = now
+	if err := ss.overrideRepo.Save(override); err != nil {
+		return spoofs.OverrideResponse{}, err
 	}
 
 	exist.IP = override.IP.String()
+	exist.IPs = nil // an override spoofs a single address
 	exist.HasOverride = true
 
 	err = ss.svcRepo.Update(&exist)
 	if err != nil {
-		return fmt.Errorf("failed to update GSLB service with override flag: %w", err)
+		if err := ss.overrideRepo.Delete(override.MemberOf); err != nil {
+			bslog.Error("could not remove override after flagging the service failed", slog.String("reason", err.Error()), slog.Any("override", override))
+		}
+		return spoofs.OverrideResponse{}, fmt.Errorf("failed to update GSLB service with override flag: %w", err)
 	}
+	bslog.Info("created override", slog.Any("override", override))
 
-	return nil
+	if err := ss.overrideRepo.Record(spoofs.OVERRIDE_CREATED, user, override); err != nil {
+		return spoofs.OverrideResponse{}, err
+	}
+	return ss.publish(override), nil
 }
 
-func (ss *SpoofsService) updateOverride(override spoofs.Override) error {
+func (ss *SpoofsService) updateOverride(override spoofs.Override, user string) (spoofs.OverrideResponse, error) {
+	defer ss.lockOverride(override.MemberOf)()
+
+	if !override.ExpiresAt.IsZero() && !override.ExpiresAt.After(time.Now()) {
+		return spoofs.OverrideResponse{}, fmt.Errorf("override for %s expires in the past: %s", override.MemberOf, override.ExpiresAt)
+	}
+
 	active, err := ss.svcRepo.GetActive(override.MemberOf)
 	if err != nil {
-		return fmt.Errorf("unable to get active service for group: %s: %w", override.MemberOf, err)
+		return spoofs.OverrideResponse{}, fmt.Errorf("unable to get active service for group: %s: %w", override.MemberOf, err)
 	}
 
-	if active.HasOverride {
-		return fmt.Errorf("service already has active override: %s", active.MemberOf)
+	if !active.HasOverride {
+		return spoofs.OverrideResponse{}, fmt.Errorf("%s does not have an override currently set", override.MemberOf)
+	}
+
+	// the record is saved before the service, and restored if the service can not be updated
+	previous, found, err := ss.overrideRepo.Read(override.MemberOf)
+	if err != nil {
+		return spoofs.OverrideResponse{}, err
+	}
+	current := previous
+	current.MemberOf = override.MemberOf
+	current.IP = override.IP
+	if !override.ExpiresAt.IsZero() { // a zero expiry keeps the current one
+		current.ExpiresAt = override.ExpiresAt
+	}
+	if override.Reason != "" {
+		current.Reason = override.Reason
+	}
+	if err := ss.overrideRepo.Save(current); err != nil {
+		return spoofs.OverrideResponse{}, err
 	}
 
 	active.IP = override.IP.String()
 
 	err = ss.svcRepo.UpdateOverride(override.IP.String(), &active)
 	if err != nil {
-		return fmt.Errorf("failed to update GSLB service with override flag: %w", err)
+		var undoErr error
+		if found {
+			undoErr = ss.overrideRepo.Save(previous)
+		} else {
+			undoErr = ss.overrideRepo.Delete(override.MemberOf)
+		}
+		if undoErr != nil {
+			bslog.Error("could not restore override after updating the service failed", slog.String("reason", undoErr.Error()), slog.Any("override", previous))
+		}
+		return spoofs.OverrideResponse{}, fmt.Errorf("failed to update GSLB service with override flag: %w", err)
+	}
+	bslog.Info("updated override", slog.Any("override", current), slog.String("user", user))
+
+	if err := ss.overrideRepo.Record(spoofs.OVERRIDE_UPDATED, user, current); err != nil {
+		return spoofs.OverrideResponse{}, err
 	}
+	return ss.publish(current), nil
+}
 
-	return nil
+// removes the override of the service group and restores the health-driven active member,
+// action is recorded in the override history
+func (ss *SpoofsService) deleteOverride(memberOf, action, user string) (spoofs.OverrideResponse, error) {
+	defer ss.lockOverride(memberOf)()
+	return ss.removeOverride(memberOf, action, user)
 }
 
-func (ss *SpoofsService) deleteOverride(override spoofs.Override) error {
-	exist, err := ss.svcRepo.GetActive(override.MemberOf)
+// see deleteOverride, expects the caller to hold the lock of the override
+func (ss *SpoofsService) removeOverride(memberOf, action, user string) (spoofs.OverrideResponse, error) {
+	exist, err := ss.svcRepo.GetActive(memberOf)
 	if err != nil {
-		return fmt.Errorf("unable to get active service for group: %s: %w", override.MemberOf, err)
+		return spoofs.OverrideResponse{}, fmt.Errorf("unable to get active service for group: %s: %w", memberOf, err)
+	}
+
+	override, found, err := ss.overrideRepo.Read(memberOf)
+	if err != nil {
+		return spoofs.OverrideResponse{}, err
 	}
 
 	if !exist.HasOverride {
-		return fmt.Errorf("%s does not have an override currently set", override.MemberOf)
+		if found { // the override is already gone, e.g. the service group was rebuilt
+			return spoofs.OverrideResponse{Override: override}, ss.overrideRepo.Delete(memberOf)
+		}
+		return spoofs.OverrideResponse{}, fmt.Errorf("%s does not have an override currently set", memberOf)
 	}
 
-	err = ss.svcRepo.RemoveOverrideFlag(override.MemberOf)
+	err = ss.svcRepo.RemoveOverrideFlag(memberOf)
 	if err != nil {
-		return fmt.Errorf("failed to remove override flag: %w", err)
+		return spoofs.OverrideResponse{}, fmt.Errorf("failed to remove override flag: %w", err)
+	}
+
+	active := ss.restoreActive(memberOf)
+	if active == nil || active.ID != exist.ID { // the overridden service no longer spoofs the group
+		exist.IsActive = false
+		exist.HasOverride = false
+		err = ss.svcRepo.Update(&exist)
+		if err != nil {
+			return spoofs.OverrideResponse{}, fmt.Errorf("could not deactivate overridden service after override flag has been removed: %w", err)
+		}
+	}
+	if active != nil {
+		err = ss.svcRepo.Update(active)
+		if err != nil {
+			return spoofs.OverrideResponse{}, fmt.Errorf("could not restore active service in group after override flag has been removed: %w", err)
+		}
+	}
+
+	if !found {
+		override = spoofs.Override{MemberOf: memberOf, IP: net.ParseIP(exist.IP)}
+	}
+	if err := ss.overrideRepo.Delete(memberOf); err != nil {
+		return spoofs.OverrideResponse{}, err
 	}
+	bslog.Info("removed override", slog.Any("override", override), slog.String("action", action), slog.String("user", user))
 
-	active := ss.restoreActive(override)
-	err = ss.svcRepo.Update(active)
+	if err := ss.overrideRepo.Record(action, user, override); err != nil {
+		return spoofs.OverrideResponse{}, err
+	}
+	return ss.publish(override), nil
+}
+
+// pushes the spoofs of the service group to every dnsdist server, instead of leaving them to the synchronization.
+// stragglers are retried, and a change too few servers took is rolled back by the updater, so the override itself stands
+func (ss *SpoofsService) publish(override spoofs.Override) spoofs.OverrideResponse {
+	update, err := ss.updater.OnOverride(override.MemberOf)
 	if err != nil {
-		return fmt.Errorf("could not restore active service in group after override flag has been removed: %w", err)
+		bslog.Warn("override did not reach every dnsdist server", slog.String("memberOf", override.MemberOf), slog.String("reason", err.Error()))
 	}
 
-	return nil
+	return spoofs.OverrideResponse{
+		Override: override,
+		Update:   update,
+	}
 }
 
-func (ss *SpoofsService) restoreActive(override spoofs.Override) *model.GSLBService {
-	svc := ss.serviceManager.GetActiveForMemberOf(override.MemberOf)
+func (ss *SpoofsService) restoreActive(memberOf string) *model.GSLBService {
+	svc := ss.serviceManager.GetActiveForMemberOf(memberOf)
 	if svc == nil { // no active service: e.g. no spoof should be there
 		return nil
 	}
 
-	return svc.GSLBService()
+	active := svc.GSLBService()
+	active.IsActive = true
+	return active
 }
@@ -1,23 +1,60 @@
 package spoofs
 
 import (
+	"sync"
+
+	"github.com/vitistack/gslb-operator/internal/dns/update"
 	"github.com/vitistack/gslb-operator/internal/manager"
 	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/internal/repositories/override"
 	"github.com/vitistack/gslb-operator/internal/repositories/service"
 	"github.com/vitistack/gslb-operator/internal/repositories/spoof"
+	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
 	"github.com/vitistack/gslb-operator/pkg/persistence"
 )
 
 type SpoofsService struct {
 	svcRepo        *service.ServiceRepo
 	spoofRepo      *spoof.SpoofRepo
+	overrideRepo   *override.OverrideRepo
 	serviceManager manager.QueryManager
+	updater        update.Updater
+
+	// by memberOf, held while the override of a service group is created, updated, deleted or expired,
+	// so the expiry never removes an override that was just extended
+	overrideLocksMu sync.Mutex
+	overrideLocks   map[string]*sync.Mutex
 }
 
-func NewSpoofsService(store persistence.Store[model.GSLBServiceGroup], svcManager manager.QueryManager) *SpoofsService {
+func NewSpoofsService(
+	store persistence.Store[model.GSLBServiceGroup],
+	overrides persistence.Store[spoofs.Override],
+	overrideHistory persistence.Store[spoofs.OverrideEvent],
+	svcManager manager.QueryManager,
+	updater update.Updater,
+) *SpoofsService {
 	return &SpoofsService{
 		svcRepo:        service.NewServiceRepo(store),
 		spoofRepo:      spoof.NewSpoofRepo(store), // create read-only
+		overrideRepo:   override.NewOverrideRepo(overrides, overrideHistory),
 		serviceManager: svcManager,
+		updater:        updater,
 	}
 }
+
+// locks the override of a service group, and returns the function that unlocks it
+func (ss *SpoofsService) lockOverride(memberOf string) func() {
+	ss.overrideLocksMu.Lock()
+	if ss.overrideLocks == nil {
+		ss.overrideLocks = make(map[string]*sync.Mutex)
+	}
+	lock, ok := ss.overrideLocks[memberOf]
+	if !ok {
+		lock = &sync.Mutex{}
+		ss.overrideLocks[memberOf] = lock
+	}
+	ss.overrideLocksMu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
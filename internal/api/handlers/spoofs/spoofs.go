@@ -1,13 +1,8 @@
 package spoofs
 
 import (
-	"cmp"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
 	"log/slog"
 	"net/http"
-	"slices"
 
 	"github.com/vitistack/gslb-operator/pkg/bslog"
 	"github.com/vitistack/gslb-operator/pkg/models/pagination"
@@ -55,29 +50,16 @@ func (ss *SpoofsService) GetFQDNSpoof(w http.ResponseWriter, r *http.Request) {
 }
 
 func (ss *SpoofsService) GetSpoofsHash(w http.ResponseWriter, r *http.Request) {
-	data, err := ss.spoofRepo.ReadAll()
-	if err != nil {
-		response.Err(w, response.ErrInternalError, "unable to fetch spoofs from storage")
-		bslog.Error("unable to read spoofs from storage", slog.String("reason", err.Error()))
-		return
-	}
-
-	// IMPORTANT!! that spoofs are sorted alphabetically sorted on fqdn.
-	// To get consistent hashes
-	slices.SortFunc(data, func(a, b spoofs.Spoof) int {
-		return cmp.Compare(a.FQDN, b.FQDN)
-	})
-
-	marshalledSpoofs, err := json.Marshal(data)
+	// same hash as the one the dnsdist - servers are synchronized against
+	rawHash, err := ss.spoofRepo.Hash()
 	if err != nil {
 		response.Err(w, response.ErrInternalError, "could not create spoofs-hash")
-		bslog.Error("unable to marshall spoofs", slog.String("reason", err.Error()))
+		bslog.Error("unable to create spoofs-hash", slog.String("reason", err.Error()))
 		return
 	}
 
-	rawHash := sha256.Sum256(marshalledSpoofs) // creating bytes representation of spoofs
 	hash := spoofs.Hash{
-		Hash: hex.EncodeToString(rawHash[:]),
+		Hash: rawHash,
 	}
 
 	if err = response.JSON(w, http.StatusOK, hash); err != nil {
@@ -15,14 +15,38 @@ const (
 	GET_SPOOFS_HASH = http.MethodGet + " " + SPOOFS_HASH // Route to hash all spoofs, for config validation
 	POST_SPOOF      = http.MethodPost + " " + SPOOFS     // Route POST
 
-	OVERRIDE        = SPOOFS + "/override"                                    // override DNSDIST configuration
-	GET_OVERRIDE    = http.MethodGet + " " + OVERRIDE + "/{" + MemberOf + "}" // Route GET
-	POST_OVERRIDE   = http.MethodPost + " " + OVERRIDE                        // Route POST
-	PUT_OVERRIDE    = http.MethodPut + " " + OVERRIDE + "/{fqdn}"
-	DELETE_OVERRIDE = http.MethodDelete + " " + OVERRIDE // Route DELETE
-
-	FAILOVER      = ROOT + "failover"
-	POST_FAILOVER = http.MethodPost + " " + FAILOVER + "/{fqdn}"
+	OVERRIDE             = SPOOFS + "/override"                                    // override DNSDIST configuration
+	OVERRIDE_HISTORY     = OVERRIDE + "/history"                                   // audit trail of overrides
+	GET_OVERRIDE         = http.MethodGet + " " + OVERRIDE + "/{" + MemberOf + "}" // Route GET
+	GET_OVERRIDE_HISTORY = http.MethodGet + " " + OVERRIDE_HISTORY
+	POST_OVERRIDE        = http.MethodPost + " " + OVERRIDE // Route POST
+	PUT_OVERRIDE         = http.MethodPut + " " + OVERRIDE + "/{fqdn}"
+	DELETE_OVERRIDE      = http.MethodDelete + " " + OVERRIDE // Route DELETE
+
+	FAILOVER        = ROOT + "failover"
+	POST_FAILOVER   = http.MethodPost + " " + FAILOVER + "/{fqdn}"
+	DELETE_FAILOVER = http.MethodDelete + " " + FAILOVER + "/{fqdn}" // release a manual failover
+
+	DELETIONS              = ROOT + "deletions" // removal of services held back by the delete guard
+	GET_DELETIONS          = http.MethodGet + " " + DELETIONS
+	POST_DELETIONS_CONFIRM = http.MethodPost + " " + DELETIONS + "/confirm"
+
+	REJECTIONS     = ROOT + "rejections" // GSLB - configs that are not used, and why
+	GET_REJECTIONS = http.MethodGet + " " + REJECTIONS
+
+	HISTORY     = ROOT + "history" // last health checks and flap state of the members of a service group
+	GET_HISTORY = http.MethodGet + " " + HISTORY + "/{" + MemberOf + "}"
+
+	MAINTENANCE        = ROOT + "maintenance" // windows that take members out of rotation
+	GET_MAINTENANCE    = http.MethodGet + " " + MAINTENANCE
+	POST_MAINTENANCE   = http.MethodPost + " " + MAINTENANCE
+	DELETE_MAINTENANCE = http.MethodDelete + " " + MAINTENANCE + "/{id}"
+
+	DNSDIST               = ROOT + "dnsdist"
+	DNSDIST_SERVERS       = DNSDIST + "/servers" // reconciliation state of the dnsdist servers
+	GET_DNSDIST_SERVERS   = http.MethodGet + " " + DNSDIST_SERVERS
+	POST_DNSDIST_RESYNC   = http.MethodPost + " " + DNSDIST_SERVERS + "/resync" // synchronize every server right away
+	POST_DNSDIST_RESYNCID = http.MethodPost + " " + DNSDIST_SERVERS + "/{server}/resync"
 
 	AUTH            = ROOT + "auth"
 	AUTH_LOGIN      = AUTH + "/login"
@@ -6,3 +6,13 @@ type Checker interface {
 	Check() error
 	Roundtrip() time.Duration
 }
+
+// implemented by checks that connect with TLS
+type CertificateChecker interface {
+	CertificateExpiry() time.Time // of the certificate of the service, zero if unknown
+}
+
+// implemented by checks that combine several checks
+type ResultReporter interface {
+	Results() []Result // of every check of the last check
+}
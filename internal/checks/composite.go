@@ -0,0 +1,94 @@
+package checks
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrQuorumNotReached = errors.New("not enough checks succeeded")
+
+// a named check of a composite check
+type SubCheck struct {
+	Name    string
+	Checker Checker
+}
+
+// result of a sub-check of the last check
+type Result struct {
+	Name string
+	Err  error
+}
+
+// CompositeChecker runs several checks against a service at once,
+// and succeeds when at least quorum of them succeed.
+type CompositeChecker struct {
+	checks []SubCheck
+	quorum int
+
+	mu      sync.Mutex
+	results []Result
+}
+
+// quorum is clamped to 1-len(checks), so every check must succeed if it is larger
+func NewCompositeChecker(quorum int, checks ...SubCheck) *CompositeChecker {
+	return &CompositeChecker{
+		checks: checks,
+		quorum: min(max(quorum, 1), len(checks)),
+	}
+}
+
+func (c *CompositeChecker) Check() error {
+	results := make([]Result, len(c.checks))
+	wg := sync.WaitGroup{}
+	for i, check := range c.checks {
+		wg.Go(func() {
+			results[i] = Result{Name: check.Name, Err: check.Checker.Check()}
+		})
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	c.results = results
+	c.mu.Unlock()
+
+	succeeded := 0
+	errs := make([]error, 0)
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Name, result.Err))
+			continue
+		}
+		succeeded++
+	}
+
+	if succeeded < c.quorum {
+		return fmt.Errorf("%w: %d of %d, expected %d: %w", ErrQuorumNotReached, succeeded, len(c.checks), c.quorum, errors.Join(errs...))
+	}
+	return nil
+}
+
+// the roundtrip of the slowest check
+func (c *CompositeChecker) Roundtrip() time.Duration {
+	roundtrip := time.Duration(0)
+	for _, check := range c.checks {
+		roundtrip = max(roundtrip, check.Checker.Roundtrip())
+	}
+	return roundtrip
+}
+
+func (c *CompositeChecker) Results() []Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.results
+}
+
+// the expiry of the first certificate to expire
+func (c *CompositeChecker) CertificateExpiry() time.Time {
+	checkers := make([]Checker, 0, len(c.checks))
+	for _, check := range c.checks {
+		checkers = append(checkers, check.Checker)
+	}
+	return earliestExpiry(checkers...)
+}
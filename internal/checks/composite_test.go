@@ -0,0 +1,58 @@
+package checks
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// checker with a fixed result
+type stubChecker struct {
+	err error
+}
+
+func (s stubChecker) Check() error {
+	return s.err
+}
+
+func (s stubChecker) Roundtrip() time.Duration {
+	return time.Millisecond
+}
+
+func TestCompositeChecker(t *testing.T) {
+	failed := errors.New("connection refused")
+	subChecks := []SubCheck{
+		{Name: "tcp", Checker: stubChecker{}},
+		{Name: "http", Checker: stubChecker{err: failed}},
+		{Name: "dns", Checker: stubChecker{}},
+	}
+
+	tests := []struct {
+		name    string
+		quorum  int
+		wantErr bool
+	}{
+		{"all", len(subChecks), true},
+		{"any", 1, false},
+		{"two of three", 2, false},
+		{"larger than the number of checks", 5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewCompositeChecker(tt.quorum, subChecks...)
+			err := checker.Check()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr && (!errors.Is(err, ErrQuorumNotReached) || !errors.Is(err, failed)) {
+				t.Errorf("expected the failed check to be reported, got: %v", err)
+			}
+
+			results := checker.Results()
+			if len(results) != len(subChecks) || results[1].Name != "http" || !errors.Is(results[1].Err, failed) || results[0].Err != nil {
+				t.Errorf("expected the result of every check, got: %+v", results)
+			}
+		})
+	}
+}
@@ -10,8 +10,12 @@ const (
 )
 
 const (
-	HTTP     = "HTTP"
-	HTTPS    = "HTTPS"
-	TCP_FULL = "TCP-FULL"
-	TCP_HALF = "TCP-HALF"
+	HTTP      = "HTTP"
+	HTTPS     = "HTTPS"
+	TCP_FULL  = "TCP-FULL"
+	TCP_HALF  = "TCP-HALF"
+	DNS       = "DNS"
+	GRPC      = "GRPC"
+	LUA       = "LUA"       // the script probes the service itself
+	COMPOSITE = "COMPOSITE" // combines the checks of the service
 )
@@ -0,0 +1,150 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"codeberg.org/miekg/dns"
+	"codeberg.org/miekg/dns/dnsutil"
+)
+
+var (
+	ErrUnknownQType       = errors.New("unknown qtype")
+	ErrUnknownRcode       = errors.New("unknown rcode")
+	ErrUnsupportedDNSNet  = errors.New("unsupported network for DNS check, expected udp or tcp")
+	ErrUnexpectedRcode    = errors.New("unexpected rcode")
+	ErrMissingDNSAnswer   = errors.New("answer is missing expected record")
+	ErrMissingDNSQuestion = errors.New("name to query is missing")
+)
+
+// query sent by a DNS check, and the answer it expects
+type DNSQuery struct {
+	Name    string
+	Type    string   // A if empty
+	Net     string   // udp if empty
+	Rcode   string   // NOERROR if empty
+	Answers []string // rdata that must be in the answer, not checked if empty
+}
+
+// checks that the query can be sent, and returns its qtype and expected rcode
+func (q DNSQuery) parse() (uint16, uint16, error) {
+	if q.Name == "" {
+		return 0, 0, ErrMissingDNSQuestion
+	}
+
+	qtype := dns.TypeA
+	if q.Type != "" {
+		var ok bool
+		if qtype, ok = dns.StringToType[strings.ToUpper(q.Type)]; !ok {
+			return 0, 0, fmt.Errorf("%w: %s", ErrUnknownQType, q.Type)
+		}
+	}
+
+	rcode := uint16(dns.RcodeSuccess)
+	if q.Rcode != "" {
+		var ok bool
+		if rcode, ok = dns.StringToRcode[strings.ToUpper(q.Rcode)]; !ok {
+			return 0, 0, fmt.Errorf("%w: %s", ErrUnknownRcode, q.Rcode)
+		}
+	}
+
+	switch strings.ToLower(q.Net) {
+	case "", "udp", "tcp":
+	default:
+		return 0, 0, fmt.Errorf("%w: %s", ErrUnsupportedDNSNet, q.Net)
+	}
+
+	return qtype, rcode, nil
+}
+
+// returns why the query can not be sent, if any
+func (q DNSQuery) Validate() error {
+	_, _, err := q.parse()
+	return err
+}
+
+// DNSChecker sends a query to the address of the service, and validates the rcode and the records in the answer
+type DNSChecker struct {
+	*RoundTripper
+	addr    string
+	net     string
+	name    string
+	qtype   uint16
+	rcode   uint16
+	answers []string
+	timeout time.Duration
+	client  *dns.Client
+}
+
+func NewDNSChecker(addr string, timeout time.Duration, query DNSQuery) (*DNSChecker, error) {
+	qtype, rcode, err := query.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	network := strings.ToLower(query.Net)
+	if network == "" {
+		network = "udp"
+	}
+
+	answers := make([]string, 0, len(query.Answers))
+	for _, answer := range query.Answers {
+		answers = append(answers, normalizeRdata(answer))
+	}
+
+	return &DNSChecker{
+		RoundTripper: NewRoundtripper(),
+		addr:         addr,
+		net:          network,
+		name:         dnsutil.Fqdn(query.Name),
+		qtype:        qtype,
+		rcode:        rcode,
+		answers:      answers,
+		timeout:      timeout,
+		client:       new(dns.Client),
+	}, nil
+}
+
+func (c *DNSChecker) Check() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	c.startRecording()
+	resp, _, err := c.client.Exchange(ctx, dns.NewMsg(c.name, c.qtype), c.net, c.addr)
+	c.endRecording()
+	if err != nil {
+		return err
+	}
+
+	if resp.Rcode != c.rcode {
+		return fmt.Errorf("%w: %s, expected: %s", ErrUnexpectedRcode, dns.RcodeToString[resp.Rcode], dns.RcodeToString[c.rcode])
+	}
+
+	answered := make([]string, 0, len(resp.Answer))
+	for _, record := range resp.Answer {
+		if dns.RRToType(record) == c.qtype {
+			answered = append(answered, normalizeRdata(record.Data().String()))
+		}
+	}
+
+	for _, expected := range c.answers {
+		if !slices.Contains(answered, expected) {
+			return fmt.Errorf("%w: %s", ErrMissingDNSAnswer, expected)
+		}
+	}
+
+	return nil
+}
+
+func (c *DNSChecker) Roundtrip() time.Duration {
+	return c.AverageRoundtripTime()
+}
+
+// names in rdata are compared case-insensitive, with or without the trailing dot
+func normalizeRdata(rdata string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(rdata)), ".")
+}
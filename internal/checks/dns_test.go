@@ -0,0 +1,78 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"codeberg.org/miekg/dns"
+	"codeberg.org/miekg/dns/dnstest"
+	"codeberg.org/miekg/dns/dnsutil"
+)
+
+// answers app.example.com with an A record, and every other name with NXDOMAIN
+func serveTestZone(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) {
+	r.Unpack()
+	resp := new(dns.Msg)
+	dnsutil.SetReply(resp, r)
+
+	name, qtype := dnsutil.Question(r)
+	switch {
+	case name != "app.example.com.":
+		resp.Rcode = dns.RcodeNameError
+	case qtype == dns.TypeA:
+		resp.Answer = []dns.RR{dnstest.New("app.example.com. 60 IN A 10.0.0.1")}
+	}
+	io.Copy(w, resp)
+}
+
+func TestDNSChecker(t *testing.T) {
+	handler := func(s *dns.Server) { s.Handler = dns.HandlerFunc(serveTestZone) }
+	cancelUDP, addr, err := dnstest.UDPServer("127.0.0.1:0", handler)
+	if err != nil {
+		t.Fatalf("could not start udp server: %v", err)
+	}
+	defer cancelUDP()
+
+	cancelTCP, _, err := dnstest.TCPServer(addr, handler)
+	if err != nil {
+		t.Fatalf("could not start tcp server: %v", err)
+	}
+	defer cancelTCP()
+
+	tests := []struct {
+		name    string
+		query   DNSQuery
+		wantErr error
+	}{
+		{"answer", DNSQuery{Name: "app.example.com", Answers: []string{"10.0.0.1"}}, nil},
+		{"over tcp", DNSQuery{Name: "app.example.com", Net: "tcp"}, nil},
+		{"missing answer", DNSQuery{Name: "app.example.com", Answers: []string{"10.0.0.2"}}, ErrMissingDNSAnswer},
+		{"unexpected rcode", DNSQuery{Name: "other.example.com"}, ErrUnexpectedRcode},
+		{"expected rcode", DNSQuery{Name: "other.example.com", Rcode: "nxdomain"}, nil},
+		{"no answer of the qtype", DNSQuery{Name: "app.example.com", Type: "AAAA", Answers: []string{"10.0.0.1"}}, ErrMissingDNSAnswer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := NewDNSChecker(addr, time.Second, tt.query)
+			if err != nil {
+				t.Fatalf("NewDNSChecker() failed: %v", err)
+			}
+
+			err = checker.Check()
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("expected check to succeed, got: %v", err)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected: %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := NewDNSChecker(addr, time.Second, DNSQuery{Name: "app.example.com", Type: "NOPE"}); !errors.Is(err, ErrUnknownQType) {
+		t.Errorf("expected unknown qtype to be rejected, got: %v", err)
+	}
+}
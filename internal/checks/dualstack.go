@@ -0,0 +1,57 @@
+package checks
+
+import (
+	"errors"
+	"time"
+)
+
+// DualStackChecker checks every address of a service, e.g. both the IPv4 and IPv6 address.
+// the check only succeeds if it succeeds for every address.
+type DualStackChecker struct {
+	checkers []Checker
+}
+
+func NewDualStackChecker(checkers ...Checker) *DualStackChecker {
+	return &DualStackChecker{
+		checkers: checkers,
+	}
+}
+
+func (d *DualStackChecker) Check() error {
+	errs := make([]error, 0)
+	for _, checker := range d.checkers {
+		if err := checker.Check(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// the roundtrip of the slowest address
+func (d *DualStackChecker) Roundtrip() time.Duration {
+	roundtrip := time.Duration(0)
+	for _, checker := range d.checkers {
+		roundtrip = max(roundtrip, checker.Roundtrip())
+	}
+	return roundtrip
+}
+
+// the expiry of the first certificate to expire
+func (d *DualStackChecker) CertificateExpiry() time.Time {
+	return earliestExpiry(d.checkers...)
+}
+
+// the expiry of the first certificate to expire of the checkers that connect with TLS
+func earliestExpiry(checkers ...Checker) time.Time {
+	expiry := time.Time{}
+	for _, checker := range checkers {
+		certChecker, ok := checker.(CertificateChecker)
+		if !ok {
+			continue
+		}
+		if next := certChecker.CertificateExpiry(); !next.IsZero() && (expiry.IsZero() || next.Before(expiry)) {
+			expiry = next
+		}
+	}
+	return expiry
+}
@@ -0,0 +1,90 @@
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+)
+
+var ErrNotServing = errors.New("grpc service is not serving")
+
+// GRPCChecker asks the service for its health with the standard grpc.health.v1.Health/Check protocol
+type GRPCChecker struct {
+	*RoundTripper
+	addr    string
+	service string      // empty asks for the health of the server as a whole
+	tls     *TLSOptions // nil connects without TLS
+	creds   credentials.TransportCredentials
+	timeout time.Duration
+	certificateExpiry
+}
+
+// creates a gRPC health checker, that connects with TLS for serverName if tlsOptions is set
+func NewGRPCChecker(addr, service, serverName string, tlsOptions *TLSOptions, timeout time.Duration) (*GRPCChecker, error) {
+	creds := insecure.NewCredentials()
+	if tlsOptions != nil {
+		config, err := tlsOptions.Config(serverName)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(config)
+	}
+
+	return &GRPCChecker{
+		RoundTripper: NewRoundtripper(),
+		addr:         addr,
+		service:      service,
+		tls:          tlsOptions,
+		creds:        creds,
+		timeout:      timeout,
+	}, nil
+}
+
+// every check connects again, so the check also covers that the server accepts new connections
+func (c *GRPCChecker) Check() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	conn, err := grpc.NewClient(c.addr, grpc.WithTransportCredentials(c.creds))
+	if err != nil {
+		return fmt.Errorf("could not create grpc client: %w", err)
+	}
+	defer conn.Close()
+
+	connection := peer.Peer{}
+	c.startRecording()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: c.service}, grpc.Peer(&connection))
+	c.endRecording()
+	if err != nil {
+		return fmt.Errorf("grpc health check failed: %w", err)
+	}
+
+	if c.tls != nil {
+		var state *tls.ConnectionState
+		if info, ok := connection.AuthInfo.(credentials.TLSInfo); ok {
+			state = &info.State
+		}
+		if err := c.record(*c.tls, state, slog.String("addr", c.addr)); err != nil {
+			return err
+		}
+	}
+
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("%w: %s", ErrNotServing, resp.GetStatus())
+	}
+
+	return nil
+}
+
+func (c *GRPCChecker) Roundtrip() time.Duration {
+	return c.AverageRoundtripTime()
+}
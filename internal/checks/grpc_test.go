@@ -0,0 +1,124 @@
+package checks
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestGRPCChecker(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("app.Backend", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("app.Draining", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	addr := listener.Addr().String()
+	tests := []struct {
+		name    string
+		service string
+		wantErr error
+	}{
+		{"server", "", nil},
+		{"serving service", "app.Backend", nil},
+		{"service not serving", "app.Draining", ErrNotServing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkGRPC(t, addr, tt.service)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("expected check to succeed, got: %v", err)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected: %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if err := checkGRPC(t, addr, "app.Unknown"); err == nil {
+		t.Error("expected check of an unknown service to fail")
+	}
+}
+
+func checkGRPC(t *testing.T, addr, service string) error {
+	t.Helper()
+	checker, err := NewGRPCChecker(addr, service, "", nil, time.Second)
+	if err != nil {
+		t.Fatalf("NewGRPCChecker() failed: %v", err)
+	}
+	return checker.Check()
+}
+
+func TestGRPCChecker_TLS(t *testing.T) {
+	// only used for its certificate, which is valid for example.com and *.example.com until 2084
+	certificates := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer certificates.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{Certificates: certificates.TLS.Certificates})))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	defer server.Stop()
+	addr := listener.Addr().String()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificates.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatalf("could not write ca bundle: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		serverName string
+		options    TLSOptions
+		wantFail   bool
+		wantErr    error
+	}{
+		{"verified", "example.com", TLSOptions{CAFile: caFile}, false, nil},
+		{"wrong server name", "app.example.org", TLSOptions{CAFile: caFile}, true, nil},
+		{"unknown authority", "example.com", TLSOptions{Verify: true}, true, nil},
+		{"not verified", "app.example.com", TLSOptions{}, false, nil},
+		{"expires within threshold", "example.com", TLSOptions{ExpiryFailure: 100 * 365 * 24 * time.Hour}, true, ErrCertificateExpiring},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := NewGRPCChecker(addr, "", tt.serverName, &tt.options, time.Second)
+			if err != nil {
+				t.Fatalf("NewGRPCChecker() failed: %v", err)
+			}
+
+			err = checker.Check()
+			if (err != nil) != tt.wantFail || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Fatalf("unexpected result of check: %v", err)
+			}
+			if !tt.wantFail && !checker.CertificateExpiry().Equal(certificates.Certificate().NotAfter) {
+				t.Errorf("expected the expiry of the certificate to be recorded, got: %s", checker.CertificateExpiry())
+			}
+		})
+	}
+}
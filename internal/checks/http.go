@@ -1,58 +1,163 @@
 package checks
 
 import (
-	"crypto/tls"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 )
 
+var (
+	ErrInvalidHTTPMethod = errors.New("invalid http method")
+	ErrInvalidHTTPPath   = errors.New("http path must start with /")
+	ErrInvalidHTTPStatus = errors.New("expected status out of range, expected 100-599")
+)
+
+// request sent by a HTTP or HTTPS check, and the status it expects
+type HTTPRequest struct {
+	Scheme         string // http or https
+	Host           string // Host header, and the server name of the TLS handshake unless SNI is set
+	SNI            string
+	Method         string // GET if empty
+	Path           string // / if empty
+	Headers        map[string]string
+	Body           string
+	ExpectedStatus []int // every status but 503 is healthy if empty
+	TLS            TLSOptions
+}
+
+func (r HTTPRequest) Validate() error {
+	errs := make([]error, 0)
+	for _, c := range r.Method {
+		if c < '!' || c > '~' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) { // a token, as defined by RFC 9110
+			errs = append(errs, fmt.Errorf("%w: %q", ErrInvalidHTTPMethod, r.Method))
+			break
+		}
+	}
+
+	if r.Path != "" && r.Path[0] != '/' {
+		errs = append(errs, fmt.Errorf("%w: %q", ErrInvalidHTTPPath, r.Path))
+	}
+
+	for _, status := range r.ExpectedStatus {
+		if status < 100 || status > 599 {
+			errs = append(errs, fmt.Errorf("%w: %d", ErrInvalidHTTPStatus, status))
+		}
+	}
+
+	if err := r.TLS.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// HTTPChecker connects to the address of the service, and sends the request as if it was sent to its host.
+// so every member of a service group is checked, no matter what its fqdn currently resolves to.
 type HTTPChecker struct {
 	*RoundTripper
+	request   HTTPRequest
 	url       string
 	client    *http.Client
 	validator *LuaValidator
+	certificateExpiry
 }
 
-func NewHTTPChecker(url string, timeout time.Duration, validationScripts ...string) *HTTPChecker {
-	transport := http.DefaultTransport.(*http.Transport)
-	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+func NewHTTPChecker(addr string, request HTTPRequest, timeout time.Duration, validationScripts ...string) (*HTTPChecker, error) {
+	if request.Method == "" {
+		request.Method = http.MethodGet
+	}
+	if request.Path == "" {
+		request.Path = "/"
+	}
+	if request.SNI == "" {
+		request.SNI = request.Host
+	}
+
+	tlsConfig, err := request.TLS.Config(request.SNI)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr) // the url only decides the Host header
+		},
+		TLSClientConfig:   tlsConfig,
+		DisableKeepAlives: true, // every check connects again
+	}
 
 	var validator *LuaValidator
 	for _, script := range validationScripts {
 		if script != "" {
-			validator = &LuaValidator{script: script}
+			validator = NewLuaValidator(script)
 		}
 	}
 
+	host := request.Host
+	if host == "" { // no name to send, address the service by its address
+		host = addr
+	}
+
 	return &HTTPChecker{
 		RoundTripper: NewRoundtripper(),
-		url:          url,
+		request:      request,
+		url:          request.Scheme + "://" + host + request.Path, // the path may carry a query
 		client: &http.Client{
 			Timeout:   timeout,
 			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse // the redirect is the answer of the service
+			},
 		},
 		validator: validator,
-	}
+	}, nil
 }
 
 func (c *HTTPChecker) Check() error {
+	req, err := http.NewRequest(c.request.Method, c.url, strings.NewReader(c.request.Body))
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+	for name, value := range c.request.Headers {
+		req.Header.Set(name, value)
+	}
+
 	c.startRecording()
-	resp, err := c.client.Get(c.url)
+	resp, err := c.client.Do(req)
 	c.endRecording()
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
+
+	if err := c.record(c.request.TLS, resp.TLS, slog.String("host", c.request.Host), slog.String("url", c.url)); err != nil {
+		return err
+	}
 
 	if c.validator != nil { // run custom validation instead
 		return c.validator.Validate(resp)
 	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodySize))
+
+	if len(c.request.ExpectedStatus) > 0 {
+		if !slices.Contains(c.request.ExpectedStatus, resp.StatusCode) {
+			return fmt.Errorf("unexpected status: %d, expected one of: %v", resp.StatusCode, c.request.ExpectedStatus)
+		}
+		return nil
+	}
 
 	if resp.StatusCode == http.StatusServiceUnavailable {
 		return fmt.Errorf("service un-available: %d", resp.StatusCode)
 	}
 
-	resp.Body.Close()
 	return nil
 }
 
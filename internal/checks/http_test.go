@@ -0,0 +1,163 @@
+package checks
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHTTPChecker(t *testing.T) {
+	var serverName string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Host != "app.example.com":
+			w.WriteHeader(http.StatusMisdirectedRequest)
+		case r.Method == http.MethodPost && r.URL.Path == "/health" && r.URL.Query().Get("deep") == "1" && r.Header.Get("X-Check") == "gslb" && string(body) == "ping":
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/draining":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	server.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName = hello.ServerName
+			return nil, nil
+		},
+	}
+	server.StartTLS()
+	defer server.Close()
+	addr := server.Listener.Addr().String()
+
+	tests := []struct {
+		name    string
+		request HTTPRequest
+		wantErr bool
+	}{
+		{"default request", HTTPRequest{Scheme: "https", Host: "app.example.com"}, false},
+		{"wrong host", HTTPRequest{Scheme: "https", Host: "other.example.com", ExpectedStatus: []int{404}}, true},
+		{"unavailable", HTTPRequest{Scheme: "https", Host: "app.example.com", Path: "/draining"}, true},
+		{"unexpected status", HTTPRequest{Scheme: "https", Host: "app.example.com", ExpectedStatus: []int{200}}, true},
+		{"expected status", HTTPRequest{
+			Scheme:         "https",
+			Host:           "app.example.com",
+			Method:         http.MethodPost,
+			Path:           "/health?deep=1",
+			Headers:        map[string]string{"X-Check": "gslb"},
+			Body:           "ping",
+			ExpectedStatus: []int{200, 204},
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := NewHTTPChecker(addr, tt.request, time.Second)
+			if err != nil {
+				t.Fatalf("NewHTTPChecker() failed: %v", err)
+			}
+			err = checker.Check()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if serverName != tt.request.Host {
+				t.Errorf("expected server name: %s, got: %s", tt.request.Host, serverName)
+			}
+		})
+	}
+
+	checker, _ := NewHTTPChecker(addr, HTTPRequest{Scheme: "https", Host: "app.example.com", SNI: "sni.example.com"}, time.Second)
+	if err := checker.Check(); err != nil || serverName != "sni.example.com" {
+		t.Errorf("expected the configured server name to be sent, got: %s, %v", serverName, err)
+	}
+}
+
+func TestHTTPChecker_TLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	addr := server.Listener.Addr().String()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatalf("could not write ca bundle: %v", err)
+	}
+
+	// the certificate of the test server doubles as client certificate
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	der, err := x509.MarshalPKCS8PrivateKey(server.TLS.Certificates[0].PrivateKey)
+	if err != nil {
+		t.Fatalf("could not marshal key: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("could not write key: %v", err)
+	}
+
+	// the certificate of the test server is valid for example.com and *.example.com until 2084
+	tests := []struct {
+		name     string
+		host     string
+		options  TLSOptions
+		wantFail bool
+		wantErr  error
+	}{
+		{"verified", "example.com", TLSOptions{CAFile: caFile}, false, nil},
+		{"wrong server name", "app.example.org", TLSOptions{CAFile: caFile}, true, nil},
+		{"unknown authority", "example.com", TLSOptions{Verify: true}, true, nil},
+		{"not verified", "app.example.com", TLSOptions{}, false, nil},
+		{"client certificate verified", "example.com", TLSOptions{CAFile: caFile, CertFile: caFile, KeyFile: keyFile}, false, nil},
+		{"client certificate unknown authority", "example.com", TLSOptions{CertFile: caFile, KeyFile: keyFile}, true, nil},
+		{"expires within threshold", "example.com", TLSOptions{ExpiryFailure: 100 * 365 * 24 * time.Hour}, true, ErrCertificateExpiring},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := NewHTTPChecker(addr, HTTPRequest{Scheme: "https", Host: tt.host, TLS: tt.options}, time.Second)
+			if err != nil {
+				t.Fatalf("NewHTTPChecker() failed: %v", err)
+			}
+
+			err = checker.Check()
+			if (err != nil) != tt.wantFail || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Fatalf("unexpected result of check: %v", err)
+			}
+			if !tt.wantFail && !checker.CertificateExpiry().Equal(server.Certificate().NotAfter) {
+				t.Errorf("expected the expiry of the certificate to be recorded, got: %s", checker.CertificateExpiry())
+			}
+		})
+	}
+
+	if _, err := NewHTTPChecker(addr, HTTPRequest{Scheme: "https", TLS: TLSOptions{CertFile: caFile}}, time.Second); !errors.Is(err, ErrInvalidClientCertificate) {
+		t.Errorf("expected a client certificate without key to be rejected, got: %v", err)
+	}
+}
+
+func TestHTTPRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request HTTPRequest
+		wantErr error
+	}{
+		{"defaults", HTTPRequest{}, nil},
+		{"method", HTTPRequest{Method: "GET /"}, ErrInvalidHTTPMethod},
+		{"path", HTTPRequest{Path: "health"}, ErrInvalidHTTPPath},
+		{"status", HTTPRequest{ExpectedStatus: []int{200, 600}}, ErrInvalidHTTPStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.request.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected: %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
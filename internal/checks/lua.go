@@ -19,32 +19,20 @@ var ErrBodyTooBig = errors.New("response body too big")
 const maxBodySize = 1 * 1024 * 1024 // 1MB
 
 type LuaValidator struct {
-	script   string
-	compiled *glua.LFunction
+	script *lua.Script
 }
 
-// sets global lua values for the script
-// executes validation script, and returns the validation result
+func NewLuaValidator(script string) *LuaValidator {
+	return &LuaValidator{script: lua.NewScript(script)}
+}
+
+// executes validation script with the response as status_code, body and headers, and returns the validation result
 func (l *LuaValidator) Validate(resp *http.Response) (err error) {
 	defer func() { // makes sure we recover from any panics caused by the lua execution
 		if r := recover(); r != nil {
 			err = fmt.Errorf("recovered from lua script validation error: %v", r)
 		}
 	}()
-	vm := lua.Get()
-	defer lua.Put(vm)
-
-	sandbox := lua.GetSandBox(vm)
-
-	if l.compiled == nil {
-		// compile user script
-		// and apply sandbox environment for execution
-		userScript, err := vm.LoadString(l.script)
-		if err != nil {
-			return fmt.Errorf("could not compile user-script: %w", err)
-		}
-		l.compiled = userScript
-	}
 
 	// populate response-body
 	luaBody, err := l.readBody(resp.Body)
@@ -52,52 +40,20 @@ func (l *LuaValidator) Validate(resp *http.Response) (err error) {
 		bslog.Error("unable to load response body", slog.String("reason", err.Error()))
 	}
 
-	// create lua table for header values
-	luaHeaders := vm.NewTable()
-	for key, val := range resp.Header {
-		if len(val) > 0 {
-			luaHeaders.RawSetString(key, glua.LString(val[0]))
+	// bounded by the timeout of the lua limits
+	return l.script.Run(context.Background(), func(L *glua.LState, env *glua.LTable) {
+		// create lua table for header values
+		luaHeaders := L.NewTable()
+		for key, val := range resp.Header {
+			if len(val) > 0 {
+				luaHeaders.RawSetString(key, glua.LString(val[0]))
+			}
 		}
-	}
-
-	// set sandbox values
-	sandbox.RawSetString("status_code", glua.LNumber(resp.StatusCode))
-	sandbox.RawSetString("body", glua.LString(luaBody))
-	sandbox.RawSetString("headers", luaHeaders)
-
-	// set execution timeout
-	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*150)
-	defer cancel()
-	vm.SetContext(ctx)
-
-	vm.SetFEnv(l.compiled, sandbox)
-	vm.Push(l.compiled)
-	if err := vm.PCall(0, 1, nil); err != nil {
-		// Clean up before returning
-		sandbox.RawSetString("status_code", glua.LNil)
-		sandbox.RawSetString("body", glua.LNil)
-		sandbox.RawSetString("headers", glua.LNil)
-		return fmt.Errorf("lua script execution failed: %w", err)
-	}
-
-	// Get the return value
-	ret := vm.Get(-1)
-	vm.Pop(1)
-
-	// Clean up sandbox
-	sandbox.RawSetString("status_code", glua.LNil)
-	sandbox.RawSetString("body", glua.LNil)
-	sandbox.RawSetString("headers", glua.LNil)
-
-	if ret == glua.LNil {
-		return fmt.Errorf("script returned a nil value")
-	}
-
-	if ret == glua.LFalse {
-		return fmt.Errorf("health-check validation returned a false value")
-	}
 
-	return err
+		env.RawSetString("status_code", glua.LNumber(resp.StatusCode))
+		env.RawSetString("body", glua.LString(luaBody))
+		env.RawSetString("headers", luaHeaders)
+	})
 }
 
 // reads the response body into a string representation
@@ -120,3 +76,46 @@ func (l *LuaValidator) readBody(body io.ReadCloser) (string, error) {
 
 	return string(rawBody), nil
 }
+
+// LuaChecker runs a script that probes the service itself, with the probe api of the lua package.
+// the script must return true when the service is healthy, and may return a reason as its second value when it is not.
+type LuaChecker struct {
+	*RoundTripper
+	addr    string
+	host    string
+	script  *lua.Script
+	timeout time.Duration
+}
+
+func NewLuaChecker(addr, host, script string, timeout time.Duration) *LuaChecker {
+	return &LuaChecker{
+		RoundTripper: NewRoundtripper(),
+		addr:         addr,
+		host:         host,
+		script:       lua.NewScript(script),
+		timeout:      timeout,
+	}
+}
+
+func (c *LuaChecker) Check() (err error) {
+	defer func() { // makes sure we recover from any panics caused by the lua execution
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recovered from lua script check error: %v", r)
+		}
+	}()
+
+	probe := &lua.Probe{Addr: c.addr, Host: c.host}
+	defer probe.Close()
+
+	// every call of the probe is bounded by the check timeout
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	c.startRecording()
+	defer c.endRecording()
+	return c.script.Run(ctx, probe.Register)
+}
+
+func (c *LuaChecker) Roundtrip() time.Duration {
+	return c.AverageRoundtripTime()
+}
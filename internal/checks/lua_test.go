@@ -0,0 +1,82 @@
+package checks
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vitistack/gslb-operator/pkg/lua"
+)
+
+func TestLuaChecker(t *testing.T) {
+	if err := lua.LoadSandboxConfig("../../sandbox.lua"); err != nil {
+		t.Fatalf("could not load sandbox: %v", err)
+	}
+
+	// answers PING like redis
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				if line == "PING\r\n" {
+					conn.Write([]byte("+PONG\r\n"))
+				}
+			}()
+		}
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "app.example.com" || r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		addr    string
+		script  string
+		wantErr bool
+	}{
+		{"redis ping", listener.Addr().String(), `
+			local conn, err = tcp.connect()
+			if not conn then return false, err end
+			conn:send("PING\r\n")
+			local pong, err = conn:expect("+PONG")
+			return pong ~= nil, err`, false},
+		{"missing banner", listener.Addr().String(), `
+			local conn, err = tcp.connect()
+			if not conn then return false, err end
+			local banner, err = conn:expect("220")
+			return banner ~= nil, err`, true},
+		{"http get", server.Listener.Addr().String(), `
+			local resp, err = http.get("/health", { ["X-Token"] = "secret" })
+			return resp ~= nil and resp.status == 200, err`, false},
+		{"http without header", server.Listener.Addr().String(), `
+			local resp, err = http.get("/health")
+			return resp ~= nil and resp.status == 200, err`, true},
+		{"runs out of time", listener.Addr().String(), `while true do end`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewLuaChecker(tt.addr, "app.example.com", tt.script, 300*time.Millisecond).Check()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package checks
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vitistack/gslb-operator/pkg/bslog"
+)
+
+var (
+	ErrInvalidCABundle          = errors.New("could not load ca bundle")
+	ErrInvalidClientCertificate = errors.New("could not load client certificate")
+	ErrNegativeExpiryThreshold  = errors.New("certificate expiry threshold can not be negative")
+	ErrCertificateExpiring      = errors.New("certificate expires within threshold")
+)
+
+// TLS options of a check.
+// the certificate of the service is only verified if Verify is set, a CA bundle is configured
+// or a client certificate is set, so the client certificate is never sent to an unverified service.
+// its expiry is checked either way.
+type TLSOptions struct {
+	Verify        bool
+	CAFile        string        // PEM bundle of the CAs to verify against, the system roots if empty
+	CertFile      string        // PEM client certificate, for services that require mTLS
+	KeyFile       string        // PEM key of the client certificate
+	ExpiryWarning time.Duration // warn when the certificate expires within, never if zero
+	ExpiryFailure time.Duration // fail when the certificate expires within, never if zero
+}
+
+func (o TLSOptions) Validate() error {
+	errs := make([]error, 0)
+	if o.ExpiryWarning < 0 || o.ExpiryFailure < 0 {
+		errs = append(errs, ErrNegativeExpiryThreshold)
+	}
+	if _, err := o.Config(""); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// returns the client config of the options, verifying the certificate of the service for serverName
+func (o TLSOptions) Config(serverName string) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: !o.Verify && o.CAFile == "" && o.CertFile == "",
+	}
+
+	if o.CAFile != "" {
+		bundle, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCABundle, err)
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("%w: no certificates in %s", ErrInvalidCABundle, o.CAFile)
+		}
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidClientCertificate, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// checks the expiry of the leaf certificate of the connection against the thresholds of the options.
+// returns when the certificate expires, and whether it expires within the warning threshold.
+func (o TLSOptions) checkExpiry(state *tls.ConnectionState) (expiry time.Time, warn bool, err error) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return time.Time{}, false, nil
+	}
+
+	expiry = state.PeerCertificates[0].NotAfter
+	remaining := time.Until(expiry)
+	if o.ExpiryFailure > 0 && remaining < o.ExpiryFailure {
+		return expiry, false, fmt.Errorf("%w: expires %s", ErrCertificateExpiring, expiry.Format(time.RFC3339))
+	}
+
+	return expiry, o.ExpiryWarning > 0 && remaining < o.ExpiryWarning, nil
+}
+
+// expiry of the certificate of the last TLS connection of a check
+type certificateExpiry struct {
+	mu     sync.Mutex
+	expiry time.Time
+	warned time.Time // expiry of the last certificate that was warned about
+}
+
+func (c *certificateExpiry) CertificateExpiry() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.expiry
+}
+
+// records when the certificate of the connection expires, and warns once for every certificate that expires within the warning threshold.
+// attrs tell which check the warning is about
+func (c *certificateExpiry) record(options TLSOptions, state *tls.ConnectionState, attrs ...any) error {
+	expiry, warn, err := options.checkExpiry(state)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expiry = expiry
+	if warn && !c.warned.Equal(expiry) {
+		c.warned = expiry
+		bslog.Warn("certificate of service expires soon", append(attrs, slog.Time("expiry", expiry))...)
+	}
+
+	return err
+}
@@ -4,6 +4,8 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/vitistack/gslb-operator/internal/utils/timesutil"
 	"github.com/vitistack/gslb-operator/pkg/bslog"
@@ -48,6 +50,7 @@ type Config struct {
 	api    API
 	gslb   GSLB
 	jwt    JWT
+	k8s    Kubernetes
 }
 
 func GetInstance() *Config {
@@ -70,10 +73,20 @@ func (c *Config) JWT() *JWT {
 	return &c.jwt
 }
 
+func (c *Config) Kubernetes() *Kubernetes {
+	return &c.k8s
+}
+
 // Server configuration
 type Server struct {
-	ENV         string `env:"SRV_ENV" flag:"env"`
-	LUA_SANDBOX string `env:"SRV_LUA_SANDBOX" flag:"lua-sandbox"`
+	ENV                  string `env:"SRV_ENV" flag:"env"`
+	LUA_SANDBOX          string `env:"SRV_LUA_SANDBOX" flag:"lua-sandbox"`
+	LUA_POOL_SIZE        int    `env:"SRV_LUA_POOL_SIZE" flag:"lua-pool-size"`               // VMs running scripts at once, the number of CPUs if 0
+	LUA_POOL_WAIT        string `env:"SRV_LUA_POOL_WAIT" flag:"lua-pool-wait"`               // how long a script waits for a free VM, 100ms if empty
+	LUA_TIMEOUT          string `env:"SRV_LUA_TIMEOUT" flag:"lua-timeout"`                   // of a script validating a http response, 150ms if empty
+	LUA_MAX_INSTRUCTIONS int64  `env:"SRV_LUA_MAX_INSTRUCTIONS" flag:"lua-max-instructions"` // per script run, 1000000 if 0, unlimited if negative
+	LUA_MAX_STACK        int    `env:"SRV_LUA_MAX_STACK" flag:"lua-max-stack"`               // values on the stack of a script, 262144 if 0
+	LUA_MAX_STRING       int    `env:"SRV_LUA_MAX_STRING" flag:"lua-max-string"`             // bytes of a string built by string.rep or json, 1MB if 0
 }
 
 func (s *Server) Env() string {
@@ -84,6 +97,38 @@ func (s *Server) LuaSandbox() string {
 	return s.LUA_SANDBOX
 }
 
+func (s *Server) LuaPoolSize() int {
+	return s.LUA_POOL_SIZE
+}
+
+// zero if not set
+func (s *Server) LuaPoolWait() (timesutil.Duration, error) {
+	if s.LUA_POOL_WAIT == "" {
+		return 0, nil
+	}
+	return timesutil.FromString(s.LUA_POOL_WAIT)
+}
+
+// zero if not set
+func (s *Server) LuaTimeout() (timesutil.Duration, error) {
+	if s.LUA_TIMEOUT == "" {
+		return 0, nil
+	}
+	return timesutil.FromString(s.LUA_TIMEOUT)
+}
+
+func (s *Server) LuaMaxInstructions() int64 {
+	return s.LUA_MAX_INSTRUCTIONS
+}
+
+func (s *Server) LuaMaxStack() int {
+	return s.LUA_MAX_STACK
+}
+
+func (s *Server) LuaMaxString() int {
+	return s.LUA_MAX_STRING
+}
+
 // API configuration
 type API struct {
 	PORT string `env:"API_PORT" flag:"port"`
@@ -96,18 +141,34 @@ func (a *API) Port() string {
 // GSLB configuration
 type GSLB struct {
 	ZONE         string `env:"GSLB_ZONE" flag:"gslb-zone"`
-	NAMESERVER   string `env:"GSLB_NAMESERVER" flag:"gslb-nameserver"`
+	NAMESERVER   string `env:"GSLB_NAMESERVER" flag:"gslb-nameserver"` // comma separated, tried in order
 	POLLINTERVAL string `env:"GSLB_POLL_INTERVAL" flag:"poll-interval"`
 	UPDATERHOST  string `env:"GSLB_UPDATER_HOST" flag:"updater-host"`
 	SERVERS      string `env:"GSLB_DNSDIST_SERVERS_FILE"`
+	CONFIGDIR    string `env:"GSLB_CONFIG_DIR" flag:"config-dir"`       // directory of JSON/YAML GSLB - configs, watched for changes
+	CONFIGFILE   string `env:"GSLB_CONFIG_FILE" flag:"config-file"`     // JSON/YAML file of GSLB - configs, read on startup
+	NOTIFYADDR   string `env:"GSLB_NOTIFY_ADDR" flag:"notify-addr"`     // address to receive NOTIFY from the nameserver on, disabled if empty
+	TSIGKEYNAME  string `env:"GSLB_TSIG_KEY_NAME" flag:"tsig-key-name"` // zone-transfers are unsigned if empty
+	TSIGALGO     string `env:"GSLB_TSIG_ALGORITHM" flag:"tsig-algorithm"`
+	TSIGSECRET   string `env:"GSLB_TSIG_SECRET"`                                                // base64, like in a BIND key file
+	ZONESNAPSHOT string `env:"GSLB_ZONE_SNAPSHOT" flag:"zone-snapshot"`                         // last-known-good config zone, used if no nameserver is reachable on startup
+	DELETEGUARD  int    `env:"GSLB_DELETE_GUARD_PERCENT" flag:"delete-guard-percent"`           // max percent of services removed without confirmation, 0 disables the guard
+	RTTIMPROVE   string `env:"GSLB_ROUNDTRIP_MIN_IMPROVEMENT" flag:"roundtrip-min-improvement"` // relative improvement (0.2 = 20% faster) to promote a faster member, 0.2 if empty
+	RTTDWELL     string `env:"GSLB_ROUNDTRIP_DWELL" flag:"roundtrip-dwell"`                     // how long a faster member must stay faster before promotion, 30s if empty
 }
 
 func (g *GSLB) Zone() string {
 	return g.ZONE
 }
 
-func (g *GSLB) NameServer() string {
-	return g.NAMESERVER
+func (g *GSLB) NameServers() []string {
+	servers := make([]string, 0)
+	for server := range strings.SplitSeq(g.NAMESERVER, ",") {
+		if server = strings.TrimSpace(server); server != "" {
+			servers = append(servers, server)
+		}
+	}
+	return servers
 }
 
 func (g *GSLB) PollInterval() (timesutil.Duration, error) {
@@ -127,6 +188,54 @@ func (g *GSLB) Servers() string {
 	return g.SERVERS
 }
 
+func (g *GSLB) ConfigDir() string {
+	return g.CONFIGDIR
+}
+
+func (g *GSLB) ConfigFile() string {
+	return g.CONFIGFILE
+}
+
+func (g *GSLB) NotifyAddr() string {
+	return g.NOTIFYADDR
+}
+
+func (g *GSLB) TSIGKeyName() string {
+	return g.TSIGKEYNAME
+}
+
+func (g *GSLB) TSIGAlgorithm() string {
+	return g.TSIGALGO
+}
+
+func (g *GSLB) TSIGSecret() string {
+	return strings.TrimSpace(g.TSIGSECRET) // secret files usually end with a newline
+}
+
+func (g *GSLB) ZoneSnapshot() string {
+	return g.ZONESNAPSHOT
+}
+
+func (g *GSLB) DeleteGuardPercent() int {
+	return g.DELETEGUARD
+}
+
+// zero if not set
+func (g *GSLB) RoundtripMinImprovement() (float64, error) {
+	if g.RTTIMPROVE == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(g.RTTIMPROVE, 64)
+}
+
+// zero if not set
+func (g *GSLB) RoundtripDwell() (timesutil.Duration, error) {
+	if g.RTTDWELL == "" {
+		return 0, nil
+	}
+	return timesutil.FromString(g.RTTDWELL)
+}
+
 type JWT struct {
 	SECRET string `env:"JWT_SECRET"`
 	USER   string `env:"JWT_USER"`
@@ -140,6 +249,20 @@ func (jwt *JWT) User() string {
 	return jwt.USER
 }
 
+// Kubernetes configuration, for reading GSLB - configs from GSLBConfig resources
+type Kubernetes struct {
+	ENABLED   bool   `env:"K8S_ENABLED" flag:"k8s"`
+	NAMESPACE string `env:"K8S_NAMESPACE" flag:"k8s-namespace"` // empty watches every namespace
+}
+
+func (k *Kubernetes) Enabled() bool {
+	return k.ENABLED
+}
+
+func (k *Kubernetes) Namespace() string {
+	return k.NAMESPACE
+}
+
 func newConfig() (*Config, error) {
 	fileLoader, err := loaders.NewFileLoader(
 		".env",
@@ -165,14 +288,19 @@ func newConfig() (*Config, error) {
 	}
 	gslbCfg := GSLB{
 		POLLINTERVAL: "1m",
+		TSIGALGO:     "hmac-sha256",
+		ZONESNAPSHOT: "./data/zone-snapshot.json",
+		DELETEGUARD:  50,
 	}
 	jwtCfg := JWT{}
+	k8sCfg := Kubernetes{}
 
 	configs := []any{
 		&serverCfg,
 		&apiCfg,
 		&gslbCfg,
 		&jwtCfg,
+		&k8sCfg,
 	}
 
 	for _, cfg := range configs {
@@ -187,5 +315,6 @@ func newConfig() (*Config, error) {
 		api:    apiCfg,
 		gslb:   gslbCfg,
 		jwt:    jwtCfg,
+		k8s:    k8sCfg,
 	}, nil
 }
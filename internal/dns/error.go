@@ -0,0 +1,14 @@
+package dns
+
+import "errors"
+
+var (
+	ErrTransferTimeout          = errors.New("zone-transfer timed out")
+	ErrMissingSOA               = errors.New("missing SOA record of zone")
+	ErrUnsupportedTSIGAlgorithm = errors.New("unsupported TSIG algorithm")
+	ErrMissingTSIGSecret        = errors.New("missing TSIG secret")
+	ErrInvalidTSIGSecret        = errors.New("TSIG secret is not valid base64")
+	ErrNoNameServers            = errors.New("no nameservers configured")
+	ErrSnapshotZoneMismatch     = errors.New("snapshot is of another zone")
+	ErrNoPendingDeletion        = errors.New("no pending deletion")
+)
@@ -1,40 +1,66 @@
 package dns
 
 import (
+	"cmp"
 	"context"
-	"encoding/json"
 	"log/slog"
-	"strings"
+	"maps"
+	"slices"
 	"sync"
+	"time"
 
-	"codeberg.org/miekg/dns"
 	"github.com/vitistack/gslb-operator/internal/dns/update"
 	"github.com/vitistack/gslb-operator/internal/manager"
 	"github.com/vitistack/gslb-operator/internal/model"
 	"github.com/vitistack/gslb-operator/internal/service"
+	"github.com/vitistack/gslb-operator/internal/source"
 	"github.com/vitistack/gslb-operator/pkg/bslog"
+	"github.com/vitistack/gslb-operator/pkg/models/deletions"
+	"github.com/vitistack/gslb-operator/pkg/models/rejections"
+	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
 )
 
 // Handles/Orchestrates DNS related things
 type Handler struct {
-	fetcher       *ZoneFetcher // fetch GSLB config from dns
+	source        source.ConfigSource // where the GSLB - configs come from
 	svcManager    *manager.ServicesManager
 	updater       update.Updater
-	knownServices map[string]struct{} // service.ID: makes it easier to look up using map, but dont need a real value!
+	knownServices map[string]model.GSLBConfig     // service.ID: config it was registered with
+	deleteGuard   int                             // max percent of the known services removed in one batch without confirmation
+	pending       *deletions.PendingDeletion      // removal held back by the delete guard
+	rejected      []rejections.Rejection          // configs rejected by the source
+	unregistered  map[string]rejections.Rejection // service.ID: why the services manager refused its config
+	mu            sync.Mutex
 	stop          chan struct{}
 	cancel        func() // cancels context
 	wg            sync.WaitGroup
 }
 
-func NewHandler(fetcher *ZoneFetcher, mgr *manager.ServicesManager, updater update.Updater) *Handler {
-	return &Handler{
-		fetcher:       fetcher,
+type handlerOption func(h *Handler)
+
+func NewHandler(configSource source.ConfigSource, mgr *manager.ServicesManager, updater update.Updater, opts ...handlerOption) *Handler {
+	handler := &Handler{
+		source:        configSource,
 		svcManager:    mgr,
 		updater:       updater,
-		knownServices: make(map[string]struct{}),
+		knownServices: make(map[string]model.GSLBConfig),
+		unregistered:  make(map[string]rejections.Rejection),
 		stop:          make(chan struct{}),
 		wg:            sync.WaitGroup{},
 	}
+
+	for _, opt := range opts {
+		opt(handler)
+	}
+
+	return handler
+}
+
+// refuse to remove more than percent of the known services in one batch, until it is confirmed. 0 disables the guard
+func WithDeleteGuard(percent int) handlerOption {
+	return func(h *Handler) {
+		h.deleteGuard = percent
+	}
 }
 
 func (h *Handler) Start(ctx context.Context, cancel func()) {
@@ -44,27 +70,26 @@ func (h *Handler) Start(ctx context.Context, cancel func()) {
 	}
 
 	// function to update DNS
-	h.svcManager.DNSUpdate = func(service *service.Service, healthy bool) {
+	h.svcManager.DNSUpdate = func(service *service.Service, healthy bool) (spoofs.FleetUpdate, error) {
 		if healthy {
-			h.onServiceUp(service)
-		} else {
-			h.onServiceDown(service)
+			return h.updater.OnServiceUp(service)
 		}
+		return h.updater.OnServiceDown(service)
 	}
 
 	h.svcManager.Start()
 
-	zoneBatches, pollErrors := h.fetcher.StartAutoPoll(ctx)
+	updates, sourceErrors := h.source.Start(ctx)
 
 	h.wg.Go(func() {
-		h.handleZoneUpdates(zoneBatches, pollErrors)
+		h.handleUpdates(updates, sourceErrors)
 	})
 }
 
 func (h *Handler) Stop(ctx context.Context) {
 	done := make(chan struct{})
 	go func() {
-		h.cancel() // cancel zone-updates
+		h.cancel() // cancel config updates
 		h.wg.Wait()
 		h.svcManager.Stop()
 		close(done)
@@ -78,85 +103,267 @@ func (h *Handler) Stop(ctx context.Context) {
 	}
 }
 
-func (h *Handler) onServiceDown(svc *service.Service) {
-	err := h.updater.OnServiceDown(svc)
-	if err != nil {
-		bslog.Warn("error while updating service on service down", slog.String("error", err.Error()))
-	}
-}
-
-func (h *Handler) onServiceUp(svc *service.Service) {
-	err := h.updater.OnServiceUp(svc)
-	if err != nil {
-		bslog.Warn("error while updating service state on service up", slog.String("error", err.Error()))
-	}
-}
-
-func (h *Handler) handleZoneUpdates(zone <-chan []dns.RR, pollErrors <-chan error) {
+func (h *Handler) handleUpdates(updates <-chan source.Update, sourceErrors <-chan error) {
 	for {
 		select {
-		case records, ok := <-zone:
+		case update, ok := <-updates:
 			if !ok { // chan is closed
 				return
 			}
-			servicesInBatch := make(map[string]struct{}, 0)
-			for _, record := range records { // registers every service in the current batch
-				svc := h.handleRecord(record)
-				if svc != nil {
-					servicesInBatch[svc.GetID()] = struct{}{}
-				}
-			}
+			h.handleUpdate(update)
 
-			for key := range h.knownServices { // remove any services that dont exist in the current batch
-				if _, exists := servicesInBatch[key]; !exists {
-					bslog.Info("service no longer exists in GSLB - config zone", slog.String("action", "removing"), slog.String("serviceID", key))
-					err := h.svcManager.RemoveService(key)
-					if err != nil {
-						bslog.Error("failed to remove service", slog.Any("serviceID", key), slog.String("reason", err.Error()))
-					}
-				}
-			}
-
-			h.knownServices = servicesInBatch
-
-		case err, ok := <-pollErrors:
+		case err, ok := <-sourceErrors:
 			if !ok {
 				return
 			}
-			bslog.Error("zone transfer did not succeed", slog.String("reason", err.Error()))
+			bslog.Error("config source did not succeed", slog.String("reason", err.Error()))
 
 		case <-h.stop:
-			bslog.Debug("no longer handling zone transfers")
+			bslog.Debug("no longer handling config updates")
 			return
 		}
 	}
 }
 
-func (h *Handler) handleRecord(record dns.RR) *service.Service {
-	txt, ok := record.(*dns.TXT)
-	if !ok {
-		return nil
+// registers the services of the update, and removes the services that no longer exist.
+// services with unchanged config are left alone.
+func (h *Handler) handleUpdate(update source.Update) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	defer h.reject(update.Rejections)
+
+	switch update.Kind {
+	case source.SNAPSHOT:
+		inSnapshot := make(map[string]struct{}, len(update.Configs))
+		for _, config := range update.Configs {
+			inSnapshot[config.ServiceID] = struct{}{}
+			h.registerService(update.Source, config)
+		}
+
+		for id := range h.unregistered {
+			if _, exists := inSnapshot[id]; !exists {
+				delete(h.unregistered, id)
+			}
+		}
+
+		removed := make([]string, 0)
+		for id := range h.knownServices { // remove any services that dont exist in the current snapshot
+			if _, exists := inSnapshot[id]; !exists {
+				removed = append(removed, id)
+			}
+		}
+
+		previous := h.pending
+		h.pending = nil // the current snapshot decides what is removed
+		h.removeServices(update.Source, removed, previous)
+
+	case source.UPSERT:
+		for _, config := range update.Configs {
+			h.registerService(update.Source, config)
+			h.unpend(config.ServiceID)
+		}
+
+	case source.DELETE:
+		removed := make([]string, 0, len(update.Configs))
+		for _, config := range update.Configs {
+			delete(h.unregistered, config.ServiceID)
+			if _, exists := h.knownServices[config.ServiceID]; exists {
+				removed = append(removed, config.ServiceID)
+			}
+		}
+		h.removeServices(update.Source, removed, h.pending)
 	}
+}
 
-	rawData := txt.Txt[0]
-	data := strings.ReplaceAll(rawData, "\\", "")
-	svcConfig := model.GSLBConfig{
-		MemberOf:         txt.Hdr.Name,
-		FailureThreshold: service.DEFAULT_FAILURE_THRESHOLD,
+// removes the services, unless there are too many of them. They are then held back until the removal is confirmed.
+// previous is the removal held back before the update, if any.
+// expects the caller to hold the lock
+func (h *Handler) removeServices(from string, ids []string, previous *deletions.PendingDeletion) {
+	if len(ids) == 0 {
+		pendingDeletions.Set(float64(h.pendingCount()))
+		return
 	}
 
-	err := json.Unmarshal([]byte(data), &svcConfig)
-	if err != nil {
-		bslog.Error("failed to parse GSLB entry", slog.String("reason", err.Error()))
-		return nil
+	if h.deleteGuard > 0 && len(ids)*100 > h.deleteGuard*len(h.knownServices) {
+		pending := &deletions.PendingDeletion{
+			Source:        from,
+			KnownServices: len(h.knownServices),
+			Since:         time.Now(),
+		}
+		if h.pending != nil { // add to the removal already held back
+			pending.ServiceIDs = h.pending.ServiceIDs
+		}
+		if previous != nil {
+			pending.Since = previous.Since
+		}
+
+		pending.ServiceIDs = append(slices.Clone(pending.ServiceIDs), ids...)
+		slices.Sort(pending.ServiceIDs)
+		pending.ServiceIDs = slices.Compact(pending.ServiceIDs)
+		h.pending = pending
+		pendingDeletions.Set(float64(len(pending.ServiceIDs)))
+
+		if previous == nil || !slices.Equal(previous.ServiceIDs, pending.ServiceIDs) {
+			bslog.Warn("refusing to remove services without confirmation",
+				slog.Int("removing", len(ids)),
+				slog.Int("known", len(h.knownServices)),
+				slog.Int("guardPercent", h.deleteGuard),
+				slog.String("source", from),
+			)
+		}
+		return
+	}
+
+	for _, id := range ids {
+		bslog.Info("service no longer exists in GSLB - config source", slog.String("action", "removing"), slog.String("serviceID", id), slog.String("source", from))
+		h.removeService(id)
+	}
+	pendingDeletions.Set(float64(h.pendingCount()))
+}
+
+// the service exists again, so it is no longer pending removal.
+// expects the caller to hold the lock
+func (h *Handler) unpend(id string) {
+	if h.pending == nil {
+		return
+	}
+
+	h.pending.ServiceIDs = slices.DeleteFunc(h.pending.ServiceIDs, func(pending string) bool {
+		return pending == id
+	})
+	if len(h.pending.ServiceIDs) == 0 {
+		h.pending = nil
+	}
+	pendingDeletions.Set(float64(h.pendingCount()))
+}
+
+// expects the caller to hold the lock
+func (h *Handler) pendingCount() int {
+	if h.pending == nil {
+		return 0
+	}
+	return len(h.pending.ServiceIDs)
+}
+
+// returns the removal held back by the delete guard, if any
+func (h *Handler) PendingDeletion() (deletions.PendingDeletion, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.pending == nil {
+		return deletions.PendingDeletion{}, false
+	}
+	pending := *h.pending
+	pending.ServiceIDs = slices.Clone(pending.ServiceIDs)
+	return pending, true
+}
+
+// removes the services held back by the delete guard, and returns their ids
+func (h *Handler) ConfirmDeletion() ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.pending == nil {
+		return nil, ErrNoPendingDeletion
+	}
+
+	removed := make([]string, 0, len(h.pending.ServiceIDs))
+	for _, id := range h.pending.ServiceIDs {
+		if _, exists := h.knownServices[id]; exists {
+			bslog.Info("removing service after confirmation", slog.String("serviceID", id), slog.String("source", h.pending.Source))
+			h.removeService(id)
+			removed = append(removed, id)
+		}
+	}
+
+	h.pending = nil
+	pendingDeletions.Set(0)
+	return removed, nil
+}
+
+// expects the caller to hold the lock
+func (h *Handler) registerService(from string, config model.GSLBConfig) {
+	if known, exists := h.knownServices[config.ServiceID]; exists && known.Equal(config) {
+		return
 	}
 
-	bslog.Debug("registering new GSLB - config", slog.Any("config", svcConfig))
-	svc, err := h.svcManager.RegisterService(svcConfig)
+	bslog.Debug("registering new GSLB - config", slog.Any("config", config))
+	_, err := h.svcManager.RegisterService(config)
 	if err != nil {
 		bslog.Error("could not register service", slog.String("reason", err.Error()))
-		return nil
+		rejection := source.NewRejection(from, config.ServiceID, config, err)
+		if known, ok := h.unregistered[config.ServiceID]; ok && slices.Equal(known.Reasons, rejection.Reasons) {
+			rejection.Since = known.Since
+		}
+		h.unregistered[config.ServiceID] = rejection
+		return
+	}
+
+	delete(h.unregistered, config.ServiceID)
+	h.knownServices[config.ServiceID] = config
+}
+
+// replaces the configs rejected by the source.
+// a record rejected for the same reasons as before keeps the time it was first rejected.
+// expects the caller to hold the lock
+func (h *Handler) reject(latest []rejections.Rejection) {
+	previous := make(map[string]rejections.Rejection, len(h.rejected))
+	for _, rejection := range h.rejected {
+		previous[rejectionKey(rejection)] = rejection
+	}
+
+	h.rejected = make([]rejections.Rejection, 0, len(latest))
+	for _, rejection := range latest {
+		known, ok := previous[rejectionKey(rejection)]
+		if ok && slices.Equal(known.Reasons, rejection.Reasons) {
+			rejection.Since = known.Since
+		} else {
+			bslog.Warn("rejected GSLB - config",
+				slog.String("source", rejection.Source),
+				slog.String("record", rejection.Record),
+				slog.String("serviceID", rejection.ServiceID),
+				slog.Any("reasons", rejection.Reasons),
+			)
+		}
+		h.rejected = append(h.rejected, rejection)
+	}
+
+	rejectedConfigs.Reset()
+	for _, rejection := range h.rejected {
+		rejectedConfigs.WithLabelValues(rejection.Source).Inc()
 	}
+	for _, rejection := range h.unregistered {
+		rejectedConfigs.WithLabelValues(rejection.Source).Inc()
+	}
+}
+
+func rejectionKey(rejection rejections.Rejection) string {
+	return rejection.Source + "/" + rejection.Record + "/" + rejection.ServiceID
+}
+
+// returns the latest rejection of every GSLB - config that is not used, sorted by source and record
+func (h *Handler) Rejections() []rejections.Rejection {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-	return svc
+	rejected := slices.Concat(h.rejected, slices.Collect(maps.Values(h.unregistered)))
+	slices.SortFunc(rejected, func(a, b rejections.Rejection) int {
+		return cmp.Or(
+			cmp.Compare(a.Source, b.Source),
+			cmp.Compare(a.Record, b.Record),
+			cmp.Compare(a.ServiceID, b.ServiceID),
+		)
+	})
+	for i := range rejected {
+		rejected[i].Reasons = slices.Clone(rejected[i].Reasons)
+	}
+	return rejected
+}
+
+func (h *Handler) removeService(id string) {
+	delete(h.knownServices, id)
+	err := h.svcManager.RemoveService(id)
+	if err != nil {
+		bslog.Error("failed to remove service", slog.Any("serviceID", id), slog.String("reason", err.Error()))
+	}
 }
@@ -0,0 +1,105 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/manager"
+	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/internal/source"
+	"github.com/vitistack/gslb-operator/internal/utils/timesutil"
+)
+
+func testConfigs(n int) []model.GSLBConfig {
+	configs := make([]model.GSLBConfig, 0, n)
+	for i := range n {
+		configs = append(configs, model.GSLBConfig{
+			ServiceID:        fmt.Sprintf("svc-%d", i),
+			Fqdn:             "app.example.com",
+			MemberOf:         "app.example.com",
+			Ip:               fmt.Sprintf("10.0.0.%d", i+1),
+			Port:             "443",
+			Datacenter:       fmt.Sprintf("dc%d", i),
+			Interval:         timesutil.Duration(30 * time.Second),
+			CheckType:        "TCP-FULL",
+			FailureThreshold: 3,
+		})
+	}
+	return configs
+}
+
+func TestHandler_DeleteGuard(t *testing.T) {
+	handler := NewHandler(nil, manager.NewManager(manager.WithDryRun(true)), nil, WithDeleteGuard(50))
+	configs := testConfigs(4)
+
+	handler.handleUpdate(source.Update{Source: "test", Kind: source.SNAPSHOT, Configs: configs})
+	if len(handler.knownServices) != 4 {
+		t.Fatalf("expected all services to be registered, got: %d", len(handler.knownServices))
+	}
+
+	// removing 1 of 4 services is within the guard
+	handler.handleUpdate(source.Update{Source: "test", Kind: source.SNAPSHOT, Configs: configs[1:]})
+	if _, ok := handler.PendingDeletion(); ok || len(handler.knownServices) != 3 {
+		t.Fatalf("expected svc-0 to be removed without confirmation, got known: %d", len(handler.knownServices))
+	}
+
+	// an empty snapshot would remove every service
+	handler.handleUpdate(source.Update{Source: "test", Kind: source.SNAPSHOT})
+	pending, ok := handler.PendingDeletion()
+	if !ok || !slices.Equal(pending.ServiceIDs, []string{"svc-1", "svc-2", "svc-3"}) {
+		t.Fatalf("expected removal of every service to be held back, got: %+v", pending)
+	}
+	if len(handler.knownServices) != 3 {
+		t.Fatalf("did not expect services to be removed before confirmation, got known: %d", len(handler.knownServices))
+	}
+
+	// a service that comes back is no longer pending removal
+	handler.handleUpdate(source.Update{Source: "test", Kind: source.UPSERT, Configs: configs[3:]})
+	pending, _ = handler.PendingDeletion()
+	if !slices.Equal(pending.ServiceIDs, []string{"svc-1", "svc-2"}) {
+		t.Fatalf("expected svc-3 to no longer be pending removal, got: %+v", pending)
+	}
+
+	removed, err := handler.ConfirmDeletion()
+	if err != nil {
+		t.Fatalf("ConfirmDeletion() failed: %v", err)
+	}
+	if !slices.Equal(removed, []string{"svc-1", "svc-2"}) || len(handler.knownServices) != 1 {
+		t.Errorf("expected svc-1 and svc-2 to be removed after confirmation, got: %v", removed)
+	}
+
+	if _, err := handler.ConfirmDeletion(); !errors.Is(err, ErrNoPendingDeletion) {
+		t.Errorf("expected no pending deletion after confirmation, got: %v", err)
+	}
+}
+
+func TestHandler_Rejections(t *testing.T) {
+	handler := NewHandler(nil, manager.NewManager(manager.WithDryRun(true)), nil)
+	configs := testConfigs(1)
+	_, rejected := source.Validate("test", []source.Record{
+		{Name: "app.example.com.", Err: errors.New("could not parse GSLB - config")},
+	})
+
+	handler.handleUpdate(source.Update{Source: "merged", Kind: source.SNAPSHOT, Configs: configs, Rejections: rejected})
+	first := handler.Rejections()
+	if len(first) != 1 || first[0].Source != "test" {
+		t.Fatalf("expected the rejection of the source to be kept, got: %+v", first)
+	}
+
+	// the same rejection keeps the time it was first rejected
+	_, rejected = source.Validate("test", []source.Record{
+		{Name: "app.example.com.", Err: errors.New("could not parse GSLB - config")},
+	})
+	handler.handleUpdate(source.Update{Source: "merged", Kind: source.SNAPSHOT, Configs: configs, Rejections: rejected})
+	if latest := handler.Rejections(); len(latest) != 1 || !latest[0].Since.Equal(first[0].Since) {
+		t.Errorf("expected the time of the first rejection to be kept, got: %+v", latest)
+	}
+
+	handler.handleUpdate(source.Update{Source: "merged", Kind: source.SNAPSHOT, Configs: configs})
+	if latest := handler.Rejections(); len(latest) != 0 {
+		t.Errorf("expected the rejection to be cleared once the record is fixed, got: %+v", latest)
+	}
+}
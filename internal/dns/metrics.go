@@ -0,0 +1,34 @@
+package dns
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	zoneSerial = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "config_zone_serial",
+		Help: "SOA serial of the last transferred config zone",
+	})
+
+	zoneTransfers = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "config_zone_transfers_total",
+			Help: "Number of config zone refreshes, by how the zone was transferred",
+		},
+		[]string{"type"}, // axfr, ixfr or unchanged
+	)
+
+	pendingDeletions = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "config_pending_deletions",
+		Help: "Number of services held back from removal until it is confirmed",
+	})
+
+	rejectedConfigs = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "config_rejected_records",
+			Help: "Number of GSLB - configs that are not used because they are not valid, by config source",
+		},
+		[]string{"source"},
+	)
+)
@@ -0,0 +1,99 @@
+package dns
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"codeberg.org/miekg/dns"
+	"codeberg.org/miekg/dns/dnsutil"
+	"github.com/vitistack/gslb-operator/pkg/bslog"
+)
+
+// last-known-good zone, as saved to disk
+type zoneSnapshot struct {
+	Zone    string    `json:"zone"`
+	Serial  uint32    `json:"serial"`
+	Saved   time.Time `json:"saved"`
+	Records []string  `json:"records"`
+}
+
+// saves the known zone as the last-known-good zone.
+// an empty zone is never saved, so a truncated transfer can not replace a good snapshot.
+func (f *ZoneFetcher) saveSnapshot() {
+	if f.snapshot == "" || len(f.records) == 0 {
+		return
+	}
+
+	snapshot := zoneSnapshot{
+		Zone:    f.Zone,
+		Serial:  f.serial,
+		Saved:   time.Now(),
+		Records: make([]string, 0, len(f.records)),
+	}
+	for _, record := range f.Records() {
+		snapshot.Records = append(snapshot.Records, record.String())
+	}
+
+	if err := writeSnapshot(f.snapshot, snapshot); err != nil {
+		bslog.Warn("could not save last-known-good config zone", slog.String("file", f.snapshot), slog.String("reason", err.Error()))
+	}
+}
+
+// reads the last-known-good zone as a full change of the zone
+func (f *ZoneFetcher) loadSnapshot() (ZoneChange, error) {
+	if f.snapshot == "" {
+		return ZoneChange{}, os.ErrNotExist
+	}
+
+	data, err := os.ReadFile(f.snapshot)
+	if err != nil {
+		return ZoneChange{}, fmt.Errorf("could not read last-known-good config zone: %w", err)
+	}
+
+	snapshot := zoneSnapshot{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return ZoneChange{}, fmt.Errorf("could not parse last-known-good config zone: %w", err)
+	}
+
+	if !strings.EqualFold(dnsutil.Fqdn(snapshot.Zone), dnsutil.Fqdn(f.Zone)) {
+		return ZoneChange{}, fmt.Errorf("%w: %s", ErrSnapshotZoneMismatch, snapshot.Zone)
+	}
+
+	change := ZoneChange{
+		Serial: snapshot.Serial,
+		Full:   true,
+		Added:  make([]dns.RR, 0, len(snapshot.Records)),
+	}
+	for _, text := range snapshot.Records {
+		record, err := dns.New(text)
+		if err != nil {
+			return ZoneChange{}, fmt.Errorf("could not parse record of last-known-good config zone: %w", err)
+		}
+		change.Added = append(change.Added, record)
+	}
+
+	return change, nil
+}
+
+// writes to a temporary file first, so a crash never leaves a partial snapshot
+func writeSnapshot(path string, snapshot zoneSnapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
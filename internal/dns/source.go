@@ -0,0 +1,146 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"codeberg.org/miekg/dns"
+	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/internal/service"
+	"github.com/vitistack/gslb-operator/internal/source"
+	"github.com/vitistack/gslb-operator/pkg/bslog"
+)
+
+func (f *ZoneFetcher) Name() string {
+	return "axfr:" + f.Zone
+}
+
+// Start makes the ZoneFetcher a source.ConfigSource.
+// a full zone-transfer is emitted as a snapshot of the GSLB - configs in the zone,
+// an incremental one as the configs that were removed and added.
+func (f *ZoneFetcher) Start(ctx context.Context) (<-chan source.Update, <-chan error) {
+	updates := make(chan source.Update)
+	errs := make(chan error)
+	zone, pollErrors := f.StartAutoPoll(ctx)
+
+	go func() {
+		defer close(updates)
+		defer close(errs)
+
+		for zone != nil || pollErrors != nil {
+			select {
+			case change, ok := <-zone:
+				if !ok { // chan is closed
+					zone = nil
+					continue
+				}
+
+				for _, update := range f.updatesOf(change) {
+					select {
+					case updates <- update:
+					case <-ctx.Done():
+					}
+				}
+
+			case err, ok := <-pollErrors:
+				if !ok {
+					pollErrors = nil
+					continue
+				}
+				select {
+				case errs <- err:
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+
+	return updates, errs
+}
+
+// converts a change of the zone to updates of the GSLB - configs in it.
+// every record of the zone is validated, so a change can also reject configs that did not change themselves.
+func (f *ZoneFetcher) updatesOf(change ZoneChange) []source.Update {
+	valid, rejected := source.Validate(f.Name(), ParseRecords(change.Zone))
+	if change.Full {
+		return []source.Update{{
+			Source:     f.Name(),
+			Kind:       source.SNAPSHOT,
+			Configs:    valid,
+			Rejections: rejected,
+		}}
+	}
+
+	changed := make(map[string]struct{}, len(change.Added)+len(change.Deleted))
+	for _, config := range ParseConfigRecords(slices.Concat(change.Added, change.Deleted)) {
+		changed[config.ServiceID] = struct{}{}
+	}
+
+	added := make([]model.GSLBConfig, 0, len(change.Added))
+	for _, config := range valid {
+		if _, ok := changed[config.ServiceID]; ok {
+			added = append(added, config)
+			delete(changed, config.ServiceID) // a changed record is deleted and added again
+		}
+	}
+
+	deleted := make([]model.GSLBConfig, 0, len(changed))
+	for _, id := range slices.Sorted(maps.Keys(changed)) {
+		deleted = append(deleted, model.GSLBConfig{ServiceID: id})
+	}
+
+	updates := make([]source.Update, 0, 2)
+	if len(deleted) > 0 {
+		updates = append(updates, source.Update{Source: f.Name(), Kind: source.DELETE, Configs: deleted, Rejections: rejected})
+	}
+	if len(added) > 0 || len(updates) == 0 {
+		updates = append(updates, source.Update{Source: f.Name(), Kind: source.UPSERT, Configs: added, Rejections: rejected})
+	}
+	return updates
+}
+
+// returns the GSLB - configs in the TXT records of the config zone.
+// records that are not valid GSLB - configs are skipped.
+func ParseConfigRecords(records []dns.RR) []model.GSLBConfig {
+	configs := make([]model.GSLBConfig, 0, len(records))
+	for _, record := range ParseRecords(records) {
+		if record.Err == nil {
+			configs = append(configs, record.Config)
+		}
+	}
+	return configs
+}
+
+// parses the TXT records of the config zone, records that are not valid JSON are returned with the reason.
+// the records are named after their owner, which is also the group the config is a member of.
+func ParseRecords(records []dns.RR) []source.Record {
+	parsed := make([]source.Record, 0, len(records))
+	for _, record := range records {
+		txt, ok := record.(*dns.TXT)
+		if !ok || len(txt.Txt) == 0 {
+			continue
+		}
+
+		rawData := txt.Txt[0]
+		data := strings.ReplaceAll(rawData, "\\", "")
+		svcConfig := model.GSLBConfig{
+			MemberOf:         txt.Hdr.Name,
+			FailureThreshold: service.DEFAULT_FAILURE_THRESHOLD,
+		}
+
+		err := json.Unmarshal([]byte(data), &svcConfig)
+		if err != nil {
+			bslog.Error("failed to parse GSLB entry", slog.String("record", txt.Hdr.Name), slog.String("reason", err.Error()))
+			err = fmt.Errorf("could not parse GSLB - config: %w", err)
+		}
+
+		parsed = append(parsed, source.Record{Name: txt.Hdr.Name, Config: svcConfig, Err: err})
+	}
+
+	return parsed
+}
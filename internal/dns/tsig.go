@@ -0,0 +1,56 @@
+package dns
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"codeberg.org/miekg/dns"
+	"codeberg.org/miekg/dns/dnsutil"
+)
+
+// key that zone-transfer requests are signed with, and responses must be signed with
+type TSIGKey struct {
+	Name      string
+	Algorithm string
+	Secret    []byte
+}
+
+// creates a TSIG key from its name, algorithm and base64 encoded secret.
+// returns nil if name is empty, as TSIG is not configured.
+func NewTSIGKey(name, algorithm, secret string) (*TSIGKey, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	algorithm = dnsutil.Fqdn(strings.ToLower(algorithm))
+	switch algorithm {
+	case dns.HmacSHA1, dns.HmacSHA224, dns.HmacSHA256, dns.HmacSHA384, dns.HmacSHA512:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedTSIGAlgorithm, algorithm)
+	}
+
+	if secret == "" {
+		return nil, fmt.Errorf("%w: %s", ErrMissingTSIGSecret, name)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidTSIGSecret, name, err)
+	}
+
+	return &TSIGKey{
+		Name:      dnsutil.Fqdn(strings.ToLower(name)),
+		Algorithm: algorithm,
+		Secret:    decoded,
+	}, nil
+}
+
+// adds a TSIG record to msg, that is signed when it is sent
+func (k *TSIGKey) sign(msg *dns.Msg) {
+	msg.Pseudo = append(msg.Pseudo, dns.NewTSIG(k.Name, k.Algorithm, 0))
+}
+
+// the signer that signs requests and verifies every message of the response
+func (k *TSIGKey) transfer() *dns.Transfer {
+	return &dns.Transfer{TSIGSigner: dns.HmacTSIG{Secret: k.Secret}}
+}
@@ -9,9 +9,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net"
 	"os"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -22,16 +24,39 @@ import (
 	"github.com/vitistack/gslb-operator/internal/service"
 	"github.com/vitistack/gslb-operator/pkg/bslog"
 	"github.com/vitistack/gslb-operator/pkg/dnsdist"
+	"github.com/vitistack/gslb-operator/pkg/models/servers"
 	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
 	"github.com/vitistack/gslb-operator/pkg/persistence"
 )
 
 const DEFAULT_SYNCHRONIZE_JOB = time.Minute
 
+const (
+	DEFAULT_UPDATE_RETRIES       = 5               // attempts on servers that failed an update, before the synchronization is left to pick them up
+	DEFAULT_UPDATE_RETRY_BACKOFF = time.Second * 2 // wait before the first retry, doubled on every attempt
+)
+
+// description of a spoof action in the output of showRules()
+const spoofAction = "spoof in answer to "
+
 // contacts dnsdist servers to make update directly
 type DNSDISTUpdater struct {
 	servers   map[string]*dnsdist.Client
 	spoofRepo repo.SpoofRepo
+
+	syncMu   sync.Mutex // one synchronization at a time
+	statusMu sync.RWMutex
+	status   map[string]servers.ServerStatus // by server name, see status.go
+
+	// by server name, held while the rules of the server are read and changed,
+	// so updates, rollbacks and synchronizations of the same server never interleave
+	serverLocksMu sync.Mutex
+	serverLocks   map[string]*sync.Mutex
+
+	// by memberOf, held from reading the spoofs of a group in the store until the update of the group is applied or rolled back,
+	// so a rollback never restores spoofs over a newer update of the same group
+	groupLocksMu sync.Mutex
+	groupLocks   map[string]*sync.Mutex
 }
 
 func NewDNSDISTUpdater(store persistence.Store[model.GSLBServiceGroup]) (*DNSDISTUpdater, error) {
@@ -74,28 +99,174 @@ func NewDNSDISTUpdater(store persistence.Store[model.GSLBServiceGroup]) (*DNSDIS
 	return updater, nil
 }
 
-func (d *DNSDISTUpdater) OnServiceUp(svc *service.Service) error {
+func (d *DNSDISTUpdater) OnServiceUp(svc *service.Service) (spoofs.FleetUpdate, error) {
+	result, err := d.applyGroup(svc.MemberOf, true)
+	if err != nil {
+		return result, fmt.Errorf("could not create dnsdist-spoof: %w", err)
+	}
+	return result, nil
+}
+
+// a healthy service taken out of rotation can be put back, an unhealthy service is never answered with again
+func (d *DNSDISTUpdater) OnServiceDown(svc *service.Service) (spoofs.FleetUpdate, error) {
+	result, err := d.applyGroup(svc.MemberOf, svc.IsHealthy())
+	if err != nil {
+		return result, fmt.Errorf("could not remove dnsdist-spoof: %w", err)
+	}
+	return result, nil
+}
+
+// the override stays in the store, so it is not rolled back on the servers either
+func (d *DNSDISTUpdater) OnOverride(memberOf string) (spoofs.FleetUpdate, error) {
+	return d.applyGroup(memberOf, false)
+}
 
-	for _, client := range d.servers {
-		err := client.AddDomainSpoof(svc.MemberOf+":"+svc.Datacenter, svc.MemberOf, svc.GetIP())
+// locks the rules of a dnsdist server, and returns the function that unlocks them
+func (d *DNSDISTUpdater) lockServer(server string) func() {
+	d.serverLocksMu.Lock()
+	if d.serverLocks == nil {
+		d.serverLocks = make(map[string]*sync.Mutex)
+	}
+	lock, ok := d.serverLocks[server]
+	if !ok {
+		lock = &sync.Mutex{}
+		d.serverLocks[server] = lock
+	}
+	d.serverLocksMu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// locks the updates of a service group, and returns the function that unlocks them
+func (d *DNSDISTUpdater) lockGroup(memberOf string) func() {
+	d.groupLocksMu.Lock()
+	if d.groupLocks == nil {
+		d.groupLocks = make(map[string]*sync.Mutex)
+	}
+	lock, ok := d.groupLocks[memberOf]
+	if !ok {
+		lock = &sync.Mutex{}
+		d.groupLocks[memberOf] = lock
+	}
+	d.groupLocksMu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// replaces the spoofs of a service group configured on a dnsdist server with the desired spoofs,
+// and returns the spoofs that were configured before
+func (d *DNSDISTUpdater) updateServer(server, memberOf string, desired []spoofs.Spoof) ([]spoofs.Spoof, error) {
+	defer d.lockServer(server)()
+
+	client := d.servers[server]
+	rawRuleSet, err := client.ShowRules()
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch ruleset: %w", err)
+	}
+
+	configured, err := d.ParseRuleSet(rawRuleSet)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse ruleset: %w", err)
+	}
+
+	configured = slices.DeleteFunc(configured, func(s spoofs.Spoof) bool {
+		return s.FQDN != memberOf
+	})
+
+	return configured, d.replaceSpoofs(client, configured, desired)
+}
+
+// updates the service group on the servers that failed, with a growing backoff.
+// the spoofs are read again on every attempt, so a retry never reverts a newer change
+func (d *DNSDISTUpdater) retryGroup(memberOf string, stragglers []string) {
+	backoff := DEFAULT_UPDATE_RETRY_BACKOFF
+	for attempt := 1; attempt <= DEFAULT_UPDATE_RETRIES && len(stragglers) > 0; attempt++ {
+		time.Sleep(backoff)
+		backoff *= 2
+
+		unlock := d.lockGroup(memberOf)
+		desired, err := d.spoofRepo.ReadGroup(memberOf)
 		if err != nil {
-			return fmt.Errorf("could not create dnsdist-spoof: %w", err)
+			unlock()
+			bslog.Error("could not fetch spoofs for retry", slog.String("memberOf", memberOf), slog.String("reason", err.Error()))
+			continue
 		}
+
+		stragglers = slices.DeleteFunc(stragglers, func(server string) bool {
+			_, err := d.updateServer(server, memberOf, desired)
+			if err != nil {
+				bslog.Warn("retry of dnsdist update failed",
+					slog.String("server_name", server),
+					slog.String("memberOf", memberOf),
+					slog.Int("attempt", attempt),
+					slog.String("reason", err.Error()))
+				return false
+			}
+			bslog.Info("retry of dnsdist update succeeded", slog.String("server_name", server), slog.String("memberOf", memberOf))
+			return true
+		})
+		unlock()
 	}
 
-	return nil
+	if len(stragglers) > 0 {
+		bslog.Error("giving up on dnsdist update, leaving it to the synchronization",
+			slog.String("memberOf", memberOf),
+			slog.Any("servers", stragglers))
+	}
 }
 
-func (d *DNSDISTUpdater) OnServiceDown(svc *service.Service) error {
-	for _, client := range d.servers {
-		err := client.RmRuleWithName(svc.MemberOf + ":" + svc.Datacenter)
+// replaces the configured spoofs of a service group with the desired spoofs, unless they are already equal.
+// the desired rules are added before the configured ones are removed, so the fqdn is spoofed at all times.
+// added rules end up after the configured ones, and rmRule removes the first rule with a name,
+// so a configured rule is removed even when a desired rule has the same name
+func (d *DNSDISTUpdater) replaceSpoofs(client *dnsdist.Client, configured, desired []spoofs.Spoof) error {
+	if slices.EqualFunc(configured, desired, spoofs.Spoof.Equal) {
+		return nil
+	}
+
+	probabilities := chainedProbabilities(desired)
+	for i, spoof := range desired {
+		err := client.AddWeightedDomainSpoof(spoof.Key(), spoof.FQDN, probabilities[i], spoof.Addresses()...)
+		if err != nil {
+			return fmt.Errorf("could not add spoof: %s: %w", spoof.Key(), err)
+		}
+	}
+
+	for _, spoof := range configured {
+		err := client.RmRuleWithName(spoof.Key())
 		if err != nil {
-			return fmt.Errorf("could not remove dnsdist-spoof: %w", err)
+			return fmt.Errorf("could not remove spoof: %s: %w", spoof.Key(), err)
 		}
 	}
+
 	return nil
 }
 
+// returns the probability each spoof rule should match with, so the queries are split in proportion to the weights.
+// dnsdist evaluates the rules in order, so a rule only sees the queries the rules before it did not match:
+// weights 90, 10 gives probabilities 0.9, 1.
+// spoofs without weight always match.
+func chainedProbabilities(toWeigh []spoofs.Spoof) []float64 {
+	remaining := 0
+	for _, spoof := range toWeigh {
+		remaining += spoof.Weight
+	}
+
+	probabilities := make([]float64, len(toWeigh))
+	for i, spoof := range toWeigh {
+		if spoof.Weight <= 0 || spoof.Weight >= remaining {
+			probabilities[i] = 1
+			continue
+		}
+		probabilities[i] = float64(spoof.Weight) / float64(remaining)
+		remaining -= spoof.Weight
+	}
+
+	return probabilities
+}
+
 func (d *DNSDISTUpdater) Synchronize(ctx context.Context) {
 	go func() {
 		for {
@@ -120,57 +291,120 @@ func (d *DNSDISTUpdater) Synchronize(ctx context.Context) {
 }
 
 func (d *DNSDISTUpdater) synchronizeServers() error {
-	desiredHash, err := d.spoofRepo.Hash()
+	desired, desiredHash, err := d.desiredSpoofs()
 	if err != nil {
-		return fmt.Errorf("unable to get hash representation of spoofs: %w", err)
+		return err
 	}
 
+	d.syncMu.Lock()
+	defer d.syncMu.Unlock()
+
 	wg := sync.WaitGroup{}
 
 	for server, client := range d.servers {
 		wg.Go(func() {
-			rawRuleSet, err := client.ShowRules()
-			if err != nil {
-				bslog.Error("unable to fetch ruleset from dnsdist server", slog.String("reason", err.Error()))
-				return
-			}
+			d.synchronizeServer(server, client, desired, desiredHash)
+		})
+	}
 
-			data, err := d.ParseRuleSet(rawRuleSet)
-			if err != nil {
-				bslog.Error("could not synchronize dnsdist server", slog.String("reason", err.Error()))
-			}
+	wg.Wait()
 
-			slices.SortFunc(data, func(a, b spoofs.Spoof) int {
-				return cmp.Compare(fmt.Sprintf("%s:%s", a.FQDN, a.DC), fmt.Sprintf("%s:%s", b.FQDN, b.DC))
-			})
+	return nil
+}
 
-			marshalledSpoofs, err := json.Marshal(data)
-			if err != nil {
-				bslog.Error("unable to marshall spoofs", slog.String("reason", err.Error()))
-				return
-			}
+// compares the rules of a dnsdist server with the desired spoofs, and reconciles the server when they differ
+func (d *DNSDISTUpdater) synchronizeServer(server string, client *dnsdist.Client, desired []spoofs.Spoof, desiredHash string) servers.ServerStatus {
+	defer d.lockServer(server)()
 
-			rawHash := sha256.Sum256(marshalledSpoofs) // creating bytes representation of spoofs
-			hash := hex.EncodeToString(rawHash[:])
-			if hash != desiredHash {
-				err := d.reconcileServer(client, data)
-				if err != nil {
-					bslog.Warn("failed to reconcile server", slog.String("server_name", server))
-				}
-			}
-		})
+	status := servers.ServerStatus{
+		Name:        server,
+		LastSync:    time.Now(),
+		DesiredHash: desiredHash,
 	}
 
-	wg.Wait()
+	rawRuleSet, err := client.ShowRules()
+	if err != nil {
+		bslog.Error("unable to fetch ruleset from dnsdist server", slog.String("server_name", server), slog.String("reason", err.Error()))
+		status.LastError = err.Error()
+		return d.setStatus(status, false)
+	}
+	status.Connected = true
 
-	return nil
+	data, err := d.ParseRuleSet(rawRuleSet)
+	if err != nil {
+		bslog.Error("could not synchronize dnsdist server", slog.String("server_name", server), slog.String("reason", err.Error()))
+		status.LastError = err.Error()
+		return d.setStatus(status, false)
+	}
+
+	status.ObservedHash, err = hashSpoofs(data)
+	if err != nil {
+		bslog.Error("unable to marshall spoofs", slog.String("reason", err.Error()))
+		status.LastError = err.Error()
+		return d.setStatus(status, false)
+	}
+
+	drifted := status.ObservedHash != desiredHash
+	if drifted {
+		status.Missing, status.Extra = drift(data, desired)
+		bslog.Info("dnsdist server drifted from the desired spoofs",
+			slog.String("server_name", server),
+			slog.Any("missing", status.Missing),
+			slog.Any("extra", status.Extra))
+
+		err := d.reconcileServer(client, data)
+		if err != nil {
+			bslog.Warn("failed to reconcile server", slog.String("server_name", server), slog.String("reason", err.Error()))
+			status.LastError = err.Error()
+			return d.setStatus(status, drifted)
+		}
+	}
+	status.InSync = true
+
+	return d.setStatus(status, drifted)
+}
+
+// returns every desired spoof, and the hash the dnsdist servers are compared against
+func (d *DNSDISTUpdater) desiredSpoofs() ([]spoofs.Spoof, string, error) {
+	desiredHash, err := d.spoofRepo.Hash()
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to get hash representation of spoofs: %w", err)
+	}
+
+	desired, err := d.spoofRepo.ReadAll()
+	if err != nil {
+		return nil, "", fmt.Errorf("could not fetch spoofs: %w", err)
+	}
+
+	return desired, desiredHash, nil
+}
+
+// hashes spoofs the same way as the spoof repository, so a server in sync has the desired hash
+func hashSpoofs(data []spoofs.Spoof) (string, error) {
+	slices.SortFunc(data, func(a, b spoofs.Spoof) int {
+		return cmp.Compare(fmt.Sprintf("%s:%s", a.FQDN, a.DC), fmt.Sprintf("%s:%s", b.FQDN, b.DC))
+	})
+
+	marshalledSpoofs, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	rawHash := sha256.Sum256(marshalledSpoofs) // creating bytes representation of spoofs
+	return hex.EncodeToString(rawHash[:]), nil
 }
 
+// parses the output of showRules(), and returns every spoof rule created by the operator.
+// rule names are on the form fqdn:datacenter, and a rule may spoof several IPv4 and IPv6 addresses.
+// weighted rules have the weight appended to the datacenter:
+//
+//	0   app.example.com:DC1+DC2   0 qname==app.example.com.   spoof in answer to 10.0.0.1 2001:db8::1
+//	1   web.example.com:DC1@90    0 ...                       spoof in answer to 10.0.1.1
 func (d *DNSDISTUpdater) ParseRuleSet(ruleSet string) ([]spoofs.Spoof, error) {
 	reader := strings.NewReader(ruleSet)
 	lines := bufio.NewScanner(reader)
 
-	pattern, err := regexp.Compile(`[a-zA-Z0-9._-]+:[A-Z0-9]+|spoof|\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}`)
+	namePattern, err := regexp.Compile(`^\s*\d+\s+([a-zA-Z0-9._-]+):([a-zA-Z0-9._+-]+)(?:@(\d+))?\s`)
 	if err != nil {
 		return nil, fmt.Errorf("unable to compile regex: %w", err)
 	}
@@ -178,57 +412,77 @@ func (d *DNSDISTUpdater) ParseRuleSet(ruleSet string) ([]spoofs.Spoof, error) {
 	spoofRules := make([]spoofs.Spoof, 0)
 	for lines.Scan() {
 		line := lines.Text()
-		matches := pattern.FindAllString(line, -1)
-		if len(matches) < 3 {
+		actionIdx := strings.Index(line, spoofAction)
+		if actionIdx == -1 {
 			continue
 		}
-		rule := dnsdist.Rule{
-			Name:   matches[0],
-			Action: matches[1],
+
+		name := namePattern.FindStringSubmatch(line[:actionIdx])
+		if name == nil {
+			continue
 		}
 
-		if rule.Action != "spoof" {
+		ips := spoofedAddresses(line[actionIdx+len(spoofAction):])
+		if len(ips) == 0 {
 			continue
 		}
 
-		spoofRules = append(spoofRules,
-			spoofs.Spoof{
-				FQDN: strings.Split(rule.Name, ":")[0],
-				DC:   strings.Split(rule.Name, ":")[1],
-				IP:   matches[2],
-			})
+		spoof := spoofs.NewSpoof(name[1], name[2], ips...)
+		if name[3] != "" {
+			spoof.Weight, _ = strconv.Atoi(name[3]) // only digits are matched
+		}
+		spoofRules = append(spoofRules, spoof)
 	}
 
 	return spoofRules, nil
 }
 
+// returns the IPv4 and IPv6 addresses of a spoof action, in the form they are configured with
+func spoofedAddresses(action string) []string {
+	ips := make([]string, 0, 1)
+	for _, field := range strings.Fields(action) {
+		ip := net.ParseIP(strings.Trim(field, "[],"))
+		if ip != nil {
+			ips = append(ips, ip.String())
+		}
+	}
+	return ips
+}
+
+// configures every spoof in the store on the server, replacing the configured spoofs that differ.
+// expects the caller to hold the lock of the server
 func (d *DNSDISTUpdater) reconcileServer(client *dnsdist.Client, configuredSpoofs []spoofs.Spoof) error {
 	gslbspoofs, err := d.spoofRepo.ReadAll()
 	if err != nil {
 		return fmt.Errorf("could not fetch spoofs: %w", err)
 	}
 
-	for _, spoof := range configuredSpoofs { // remove all spoofs that should not exist any more
-		if !slices.ContainsFunc(gslbspoofs, func(s spoofs.Spoof) bool {
-			return s.FQDN+":"+s.DC == spoof.FQDN+":"+spoof.DC
-		}) {
-			err := client.RmRuleWithName(spoof.FQDN + ":" + spoof.DC)
+	configured := groupByFQDN(configuredSpoofs)
+	desired := groupByFQDN(gslbspoofs)
+
+	for fqdn, spoofs := range configured { // remove all spoofs that should not exist any more
+		if _, ok := desired[fqdn]; !ok {
+			err := d.replaceSpoofs(client, spoofs, nil)
 			if err != nil {
-				return fmt.Errorf("could not remove spoof: %w", err)
+				return fmt.Errorf("could not remove spoofs for: %s: %w", fqdn, err)
 			}
 		}
 	}
 
-	for _, spoof := range gslbspoofs { // add all spoofs that does not exist but should
-		if !slices.ContainsFunc(configuredSpoofs, func(s spoofs.Spoof) bool {
-			return s.FQDN+":"+s.DC == spoof.FQDN+":"+spoof.DC
-		}) {
-			err := client.AddDomainSpoof(spoof.FQDN+":"+spoof.DC, spoof.FQDN, spoof.IP)
-			if err != nil {
-				return fmt.Errorf("could not remove spoof: %w", err)
-			}
+	for fqdn, spoofs := range desired { // add or replace all spoofs that differ from what they should be
+		err := d.replaceSpoofs(client, configured[fqdn], spoofs)
+		if err != nil {
+			return fmt.Errorf("could not reconcile spoofs for: %s: %w", fqdn, err)
 		}
 	}
 
 	return nil
 }
+
+func groupByFQDN(toGroup []spoofs.Spoof) map[string][]spoofs.Spoof {
+	grouped := make(map[string][]spoofs.Spoof)
+	for _, spoof := range toGroup {
+		grouped[spoof.FQDN] = append(grouped[spoof.FQDN], spoof)
+	}
+	return grouped
+}
@@ -0,0 +1,341 @@
+package update
+
+import (
+	"encoding/base64"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/model"
+	repo "github.com/vitistack/gslb-operator/internal/repositories/spoof"
+	"github.com/vitistack/gslb-operator/internal/service"
+	"github.com/vitistack/gslb-operator/pkg/dnsdist"
+	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
+	"github.com/vitistack/gslb-operator/pkg/persistence/store/memory"
+)
+
+const testRuleSet = `#   Name                        Matches Rule                          Action
+0   app.example.com:DC1               0 qname==app.example.com.       spoof in answer to 10.0.0.1 
+1   multi.example.com:DC1+DC2         4 qname==multi.example.com.     spoof in answer to 10.0.0.2 10.0.0.1 
+2   unrelated                         0 qname==unrelated.example.com. spoof in answer to 10.0.0.9 
+3   drop.example.com:DC1              0 qname==drop.example.com.      drop
+4   web.example.com:DC1@90            0 (qname==web.example.com.) && (match with prob. 0.900000) spoof in answer to 10.0.1.1 
+5   web.example.com:DC2@10            0 qname==web.example.com.       spoof in answer to 10.0.1.2 
+6   dual.example.com:DC1              0 qname==dual.example.com.      spoof in answer to 10.0.2.1 2001:db8::1 
+`
+
+func TestDNSDISTUpdater_ParseRuleSet(t *testing.T) {
+	d := &DNSDISTUpdater{}
+	got, err := d.ParseRuleSet(testRuleSet)
+	if err != nil {
+		t.Fatalf("ParseRuleSet() failed: %v", err)
+	}
+
+	want := []spoofs.Spoof{
+		spoofs.NewSpoof("app.example.com", "DC1", "10.0.0.1"),
+		spoofs.NewSpoof("multi.example.com", "DC1+DC2", "10.0.0.1", "10.0.0.2"),
+		{FQDN: "web.example.com", DC: "DC1", IP: "10.0.1.1", Weight: 90},
+		{FQDN: "web.example.com", DC: "DC2", IP: "10.0.1.2", Weight: 10},
+		spoofs.NewSpoof("dual.example.com", "DC1", "10.0.2.1", "2001:db8::1"),
+	}
+
+	if !slices.EqualFunc(got, want, spoofs.Spoof.Equal) {
+		t.Fatalf("ParseRuleSet() = %v, want %v", got, want)
+	}
+}
+
+func TestDNSDISTUpdater_OnServiceUp(t *testing.T) {
+	var key [dnsdist.KEY_LEN]byte
+	copy(key[:], "0123456789abcdef0123456789abcdef")
+	server := dnsdist.NewMockServer(t, key)
+	server.Start()
+	defer server.Stop()
+
+	host, port, _ := net.SplitHostPort(server.Addr())
+	client, err := dnsdist.NewClient(
+		base64.StdEncoding.EncodeToString(key[:]),
+		dnsdist.WithHost(host),
+		dnsdist.WithPort(port),
+	)
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer client.Disconnect()
+
+	store := memory.NewStore[model.GSLBServiceGroup]()
+	store.Save("app.example.com", model.GSLBServiceGroup{
+		{ID: "1", MemberOf: "app.example.com", Datacenter: "DC1", IP: "10.0.0.1", IsActive: true},
+		{ID: "2", MemberOf: "app.example.com", Datacenter: "DC2", IP: "10.0.0.2", IsActive: true},
+		{ID: "3", MemberOf: "app.example.com", Datacenter: "DC3", IP: "10.0.0.3", IsActive: false},
+	})
+
+	executed := make([]string, 0)
+	record := func(cmd string) string {
+		executed = append(executed, cmd)
+		return ""
+	}
+	server.SetHandler("showRules()", func(string) string { return testRuleSet })
+	server.SetHandler("rmRule('app.example.com:DC1')", record)
+	server.SetHandler("addAction(QNameRule('app.example.com'), SpoofAction({'10.0.0.1', '10.0.0.2'}, {ttl=3600}), {name='app.example.com:DC1+DC2'})", record)
+
+	updater := &DNSDISTUpdater{
+		servers:   map[string]*dnsdist.Client{"test": client},
+		spoofRepo: *repo.NewSpoofRepo(store),
+	}
+
+	svc, err := service.NewServiceFromGSLBConfig(model.GSLBConfig{
+		ServiceID:  "2",
+		MemberOf:   "app.example.com",
+		Ip:         "10.0.0.2",
+		Port:       "80",
+		Datacenter: "DC2",
+	})
+	if err != nil {
+		t.Fatalf("could not create service during testing: %v", err)
+	}
+
+	if _, err := updater.OnServiceUp(svc); err != nil {
+		t.Fatalf("OnServiceUp() failed: %v", err)
+	}
+
+	if len(executed) != 2 {
+		t.Fatalf("expected the old rule to be replaced by a multi-record rule, executed: %v", executed)
+	}
+	if !strings.HasPrefix(executed[0], "addAction") {
+		t.Errorf("expected the new rule to be added before the old rule is removed, executed: %v", executed)
+	}
+}
+
+func TestDNSDISTUpdater_Resync(t *testing.T) {
+	var key [dnsdist.KEY_LEN]byte
+	copy(key[:], "0123456789abcdef0123456789abcdef")
+	server := dnsdist.NewMockServer(t, key)
+	server.Start()
+	defer server.Stop()
+
+	host, port, _ := net.SplitHostPort(server.Addr())
+	client, err := dnsdist.NewClient(
+		base64.StdEncoding.EncodeToString(key[:]),
+		dnsdist.WithHost(host),
+		dnsdist.WithPort(port),
+	)
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer client.Disconnect()
+	server.SetHandler("showRules()", func(string) string { return testRuleSet })
+
+	store := memory.NewStore[model.GSLBServiceGroup]()
+	store.Save("app.example.com", model.GSLBServiceGroup{
+		{ID: "1", MemberOf: "app.example.com", Datacenter: "DC1", IP: "10.0.0.1", IsActive: true},
+	})
+	store.Save("new.example.com", model.GSLBServiceGroup{
+		{ID: "2", MemberOf: "new.example.com", Datacenter: "DC1", IP: "10.0.5.1", IsActive: true},
+	})
+
+	updater := &DNSDISTUpdater{
+		servers:   map[string]*dnsdist.Client{"test": client},
+		spoofRepo: *repo.NewSpoofRepo(store),
+	}
+
+	if statuses := updater.Servers(); len(statuses) != 1 || !statuses[0].LastSync.IsZero() {
+		t.Errorf("expected a server that is not synchronized yet, got: %+v", statuses)
+	}
+
+	status, err := updater.Resync("test")
+	if err != nil {
+		t.Fatalf("Resync() failed: %v", err)
+	}
+	if !status.Connected || !status.InSync || status.DriftCount != 1 || status.ObservedHash == status.DesiredHash {
+		t.Errorf("expected a drifted server to be reconciled, got: %+v", status)
+	}
+	if !slices.Equal(status.Missing, []string{"new.example.com:DC1"}) || len(status.Extra) != 4 {
+		t.Errorf("expected the missing and extra rules, got missing: %v, extra: %v", status.Missing, status.Extra)
+	}
+
+	statuses, err := updater.ResyncAll()
+	if err != nil || len(statuses) != 1 || statuses[0].DriftCount != 2 {
+		t.Errorf("expected the drift count to add up, got: %+v: %v", statuses, err)
+	}
+
+	if _, err := updater.Resync("unknown"); !errors.Is(err, ErrServerNotFound) {
+		t.Errorf("expected an unknown server to not be found, got: %v", err)
+	}
+}
+
+func TestDNSDISTUpdater_RollbackWithoutQuorum(t *testing.T) {
+	var key [dnsdist.KEY_LEN]byte
+	copy(key[:], "0123456789abcdef0123456789abcdef")
+	server := dnsdist.NewMockServer(t, key)
+	server.Start()
+	defer server.Stop()
+
+	newClient := func(addr string) *dnsdist.Client {
+		t.Helper()
+		host, port, _ := net.SplitHostPort(addr)
+		client, err := dnsdist.NewClient(
+			base64.StdEncoding.EncodeToString(key[:]),
+			dnsdist.WithHost(host),
+			dnsdist.WithPort(port),
+			dnsdist.WithTimeout(time.Second),
+		)
+		if err != nil {
+			t.Fatalf("could not create client: %v", err)
+		}
+		return client
+	}
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not reserve a port: %v", err)
+	}
+	unreachable := closed.Addr().String()
+	closed.Close()
+
+	const (
+		dc1 = "0   app.example.com:DC1   0 qname==app.example.com.   spoof in answer to 10.0.0.1 \n"
+		dc2 = "0   app.example.com:DC2   0 qname==app.example.com.   spoof in answer to 10.0.0.2 \n"
+	)
+	mu := sync.Mutex{}
+	rules := ""
+	addRule := func(rule string) func(string) string {
+		return func(string) string {
+			mu.Lock()
+			defer mu.Unlock()
+			rules += rule
+			return ""
+		}
+	}
+	rmRule := func(rule string) func(string) string {
+		return func(string) string {
+			mu.Lock()
+			defer mu.Unlock()
+			rules = strings.Replace(rules, rule, "", 1)
+			return ""
+		}
+	}
+	server.SetHandler("showRules()", func(string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		return rules
+	})
+	server.SetHandler("rmRule('app.example.com:DC1')", rmRule(dc1))
+	server.SetHandler("rmRule('app.example.com:DC2')", rmRule(dc2))
+	server.SetHandler("addAction(QNameRule('app.example.com'), SpoofAction({'10.0.0.1'}, {ttl=3600}), {name='app.example.com:DC1'})", addRule(dc1))
+	server.SetHandler("addAction(QNameRule('app.example.com'), SpoofAction({'10.0.0.2'}, {ttl=3600}), {name='app.example.com:DC2'})", addRule(dc2))
+
+	newService := func(id, ip, dc string) *service.Service {
+		t.Helper()
+		svc, err := service.NewServiceFromGSLBConfig(model.GSLBConfig{
+			ServiceID:  id,
+			MemberOf:   "app.example.com",
+			Ip:         ip,
+			Port:       "80",
+			Datacenter: dc,
+		})
+		if err != nil {
+			t.Fatalf("could not create service during testing: %v", err)
+		}
+		return svc
+	}
+	dc1Service := newService("1", "10.0.0.1", "DC1")
+	dc2Service := newService("2", "10.0.0.2", "DC2")
+
+	tests := []struct {
+		name       string
+		update     func(*DNSDISTUpdater) (spoofs.FleetUpdate, error)
+		rolledBack bool
+		want       string
+	}{
+		{
+			name: "promotion is rolled back",
+			update: func(d *DNSDISTUpdater) (spoofs.FleetUpdate, error) {
+				return d.OnServiceUp(dc2Service)
+			},
+			rolledBack: true,
+			want:       dc1,
+		},
+		{
+			name: "withdrawal of an unhealthy service stands",
+			update: func(d *DNSDISTUpdater) (spoofs.FleetUpdate, error) {
+				return d.OnServiceDown(dc1Service) // never checked, so not healthy
+			},
+			want: dc2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			rules = dc1
+			mu.Unlock()
+
+			store := memory.NewStore[model.GSLBServiceGroup]()
+			store.Save("app.example.com", model.GSLBServiceGroup{
+				{ID: "1", MemberOf: "app.example.com", Datacenter: "DC1", IP: "10.0.0.1", IsActive: false},
+				{ID: "2", MemberOf: "app.example.com", Datacenter: "DC2", IP: "10.0.0.2", IsActive: true},
+			})
+
+			updater := &DNSDISTUpdater{
+				servers: map[string]*dnsdist.Client{
+					"a": newClient(server.Addr()),
+					"b": newClient(unreachable),
+					"c": newClient(unreachable),
+				},
+				spoofRepo: *repo.NewSpoofRepo(store),
+			}
+			for _, client := range updater.servers {
+				defer client.Disconnect()
+			}
+
+			update, err := tt.update(updater)
+			if !errors.Is(err, ErrNoQuorum) {
+				t.Fatalf("expected the update to fail without a quorum, got: %v", err)
+			}
+			if update.Applied || update.Reverted != tt.rolledBack || update.Quorum != 2 || !slices.Equal(update.Stragglers(), []string{"b", "c"}) {
+				t.Errorf("expected rolled back to be %t, got: %+v", tt.rolledBack, update)
+			}
+			mu.Lock()
+			if rules != tt.want {
+				t.Errorf("expected rules: %q, got: %q", tt.want, rules)
+			}
+			mu.Unlock()
+			for _, status := range updater.Servers() {
+				if status.Name != "a" && (status.InSync || status.LastError == "") {
+					t.Errorf("expected the unreachable servers to be marked out of sync, got: %+v", status)
+				}
+			}
+		})
+	}
+}
+
+func TestChainedProbabilities(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []int
+		want    []float64
+	}{
+		{name: "unweighted", weights: []int{0}, want: []float64{1}},
+		{name: "90/10", weights: []int{90, 10}, want: []float64{0.9, 1}},
+		{name: "50/50", weights: []int{50, 50}, want: []float64{0.5, 1}},
+		{name: "even thirds", weights: []int{1, 1, 1}, want: []float64{1.0 / 3, 0.5, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toWeigh := make([]spoofs.Spoof, 0, len(tt.weights))
+			for _, weight := range tt.weights {
+				toWeigh = append(toWeigh, spoofs.Spoof{Weight: weight})
+			}
+
+			got := chainedProbabilities(toWeigh)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("chainedProbabilities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package update
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vitistack/gslb-operator/pkg/bslog"
+	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
+)
+
+const (
+	DEFAULT_SERVER_ATTEMPTS = 3                      // attempts on a single server, before it counts as failed
+	DEFAULT_SERVER_BACKOFF  = time.Millisecond * 200 // wait between the attempts on a single server
+)
+
+var (
+	ErrPartialUpdate = errors.New("dnsdist servers failed to update")
+	ErrNoQuorum      = errors.New("too few dnsdist servers took the update")
+)
+
+// outcome of updating a single server
+type serverResult struct {
+	server   string
+	previous []spoofs.Spoof // the spoofs of the group on the server before the update
+	known    bool           // whether previous was read, i.e. the server can be rolled back
+	err      error
+}
+
+// configures the spoofs of a service group on every dnsdist server in parallel, as they are in the store.
+// The store knows which services are active in the group,
+// so both service up and service down end up here, and the group converges to a single (possibly multi-record) rule.
+//
+// the update stands when a majority of the servers take it: the stragglers are marked out of sync and retried in the background.
+// otherwise a revertible update is rolled back on every server, so the fleet keeps answering the same, and the caller must undo it in the store.
+// an update that is not revertible, like withdrawing an unhealthy service, stands on the servers that took it even without a quorum.
+// updates of the same group run one at a time, so the spoofs captured for a rollback are never older than the last update
+func (d *DNSDISTUpdater) applyGroup(memberOf string, revertible bool) (spoofs.FleetUpdate, error) {
+	defer d.lockGroup(memberOf)()
+
+	fleet := spoofs.FleetUpdate{
+		MemberOf: memberOf,
+		Quorum:   len(d.servers)/2 + 1,
+	}
+
+	desired, err := d.spoofRepo.ReadGroup(memberOf)
+	if err != nil {
+		return fleet, fmt.Errorf("could not fetch spoofs for group: %s: %w", memberOf, err)
+	}
+
+	results := make(chan serverResult, len(d.servers))
+	wg := sync.WaitGroup{}
+	for server := range d.servers {
+		wg.Go(func() {
+			results <- d.updateServerWithRetries(server, memberOf, desired)
+		})
+	}
+	wg.Wait()
+	close(results)
+
+	applied := 0
+	updated := make([]serverResult, 0, len(d.servers))
+	for result := range results {
+		update := spoofs.ServerUpdate{Server: result.server, Success: result.err == nil}
+		if result.err != nil {
+			update.Error = result.err.Error()
+		} else {
+			applied++
+		}
+		fleet.Servers = append(fleet.Servers, update)
+		updated = append(updated, result)
+	}
+	slices.SortFunc(fleet.Servers, func(a, b spoofs.ServerUpdate) int {
+		return cmp.Compare(a.Server, b.Server)
+	})
+
+	stragglers := fleet.Stragglers()
+	if len(stragglers) == 0 {
+		fleet.Applied = true
+		return fleet, nil
+	}
+
+	if applied < fleet.Quorum && revertible {
+		fleet.Reverted = true
+		d.rollback(memberOf, updated)
+		return fleet, fmt.Errorf("%w: %s: %d of %d, quorum is %d", ErrNoQuorum, memberOf, applied, len(d.servers), fleet.Quorum)
+	}
+
+	for _, result := range updated {
+		if result.err != nil {
+			d.markOutOfSync(result.server, result.err)
+		}
+	}
+	go d.retryGroup(memberOf, stragglers)
+
+	if applied < fleet.Quorum {
+		return fleet, fmt.Errorf("%w: %s: %d of %d, quorum is %d, kept on the servers that took it", ErrNoQuorum, memberOf, applied, len(d.servers), fleet.Quorum)
+	}
+	fleet.Applied = true
+	return fleet, fmt.Errorf("%w: %s: %s", ErrPartialUpdate, memberOf, strings.Join(stragglers, ", "))
+}
+
+// updates a single server, retrying with a short backoff
+func (d *DNSDISTUpdater) updateServerWithRetries(server, memberOf string, desired []spoofs.Spoof) serverResult {
+	result := serverResult{server: server}
+	for attempt := 1; attempt <= DEFAULT_SERVER_ATTEMPTS; attempt++ {
+		previous, err := d.updateServer(server, memberOf, desired)
+		if previous != nil && !result.known { // the first read is what the server had before the update
+			result.previous, result.known = previous, true
+		}
+		if result.err = err; err == nil {
+			return result
+		}
+
+		bslog.Debug("dnsdist update failed",
+			slog.String("server_name", server),
+			slog.String("memberOf", memberOf),
+			slog.Int("attempt", attempt),
+			slog.String("reason", err.Error()))
+		if attempt < DEFAULT_SERVER_ATTEMPTS {
+			time.Sleep(DEFAULT_SERVER_BACKOFF)
+		}
+	}
+	return result
+}
+
+// restores the spoofs the servers had before the update, a failed server may have taken part of it.
+// expects the caller to hold the lock of the group
+func (d *DNSDISTUpdater) rollback(memberOf string, results []serverResult) {
+	for _, result := range results {
+		if !result.known {
+			d.markOutOfSync(result.server, result.err)
+			continue
+		}
+
+		if _, err := d.updateServer(result.server, memberOf, result.previous); err != nil {
+			bslog.Error("could not roll back dnsdist update",
+				slog.String("server_name", result.server),
+				slog.String("memberOf", memberOf),
+				slog.String("reason", err.Error()))
+			d.markOutOfSync(result.server, err)
+			continue
+		}
+		bslog.Warn("rolled back dnsdist update", slog.String("server_name", result.server), slog.String("memberOf", memberOf))
+	}
+}
@@ -0,0 +1,56 @@
+package update
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	serverConnected = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dnsdist_server_connected",
+			Help: "Whether the last synchronization could read the rules of the dnsdist server",
+		},
+		[]string{"server"},
+	)
+
+	serverInSync = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dnsdist_server_in_sync",
+			Help: "Whether the dnsdist server has the desired spoofs after the last synchronization",
+		},
+		[]string{"server"},
+	)
+
+	serverLastSync = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dnsdist_server_last_sync_timestamp_seconds",
+			Help: "Unix time of the last synchronization of the dnsdist server",
+		},
+		[]string{"server"},
+	)
+
+	serverDrift = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dnsdist_server_drift_total",
+			Help: "Number of synchronizations that found the dnsdist server out of sync",
+		},
+		[]string{"server"},
+	)
+
+	serverMissingRules = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dnsdist_server_missing_rules",
+			Help: "Number of desired spoof rules the last synchronization found missing on the dnsdist server",
+		},
+		[]string{"server"},
+	)
+
+	serverExtraRules = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dnsdist_server_extra_rules",
+			Help: "Number of spoof rules the last synchronization found on the dnsdist server that are not desired",
+		},
+		[]string{"server"},
+	)
+)
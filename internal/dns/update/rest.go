@@ -96,11 +96,7 @@ func (u *RESTUpdater) ServiceUp(svc *service.Service) error {
 
 	req, err := u.builder.POST().SetHeader("Authorization", token).
 		URL("/spoofs").
-		Body(spoofs.Spoof{
-			FQDN: svc.MemberOf,
-			IP:   svc.GetIP(),
-			DC:   svc.Datacenter,
-		}).
+		Body(spoofs.NewSpoof(svc.MemberOf, svc.Datacenter, svc.GetIPs()...)).
 		Build()
 	if err != nil {
 		return fmt.Errorf("could not create post request for update: %s", err.Error())
@@ -0,0 +1,126 @@
+package update
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/vitistack/gslb-operator/pkg/models/servers"
+	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
+)
+
+var ErrServerNotFound = errors.New("dnsdist server not found")
+
+// returns the reconciliation state of every dnsdist server, ordered by name
+func (d *DNSDISTUpdater) Servers() []servers.ServerStatus {
+	d.statusMu.RLock()
+	defer d.statusMu.RUnlock()
+
+	statuses := make([]servers.ServerStatus, 0, len(d.servers))
+	for name := range d.servers {
+		status, ok := d.status[name]
+		if !ok { // not synchronized yet
+			status = servers.ServerStatus{Name: name}
+		}
+		statuses = append(statuses, status)
+	}
+	slices.SortFunc(statuses, func(a, b servers.ServerStatus) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return statuses
+}
+
+// synchronizes a single dnsdist server right away
+func (d *DNSDISTUpdater) Resync(name string) (servers.ServerStatus, error) {
+	client, ok := d.servers[name]
+	if !ok {
+		return servers.ServerStatus{}, fmt.Errorf("%w: %s", ErrServerNotFound, name)
+	}
+
+	desired, desiredHash, err := d.desiredSpoofs()
+	if err != nil {
+		return servers.ServerStatus{}, err
+	}
+
+	d.syncMu.Lock()
+	defer d.syncMu.Unlock()
+
+	return d.synchronizeServer(name, client, desired, desiredHash), nil
+}
+
+// synchronizes every dnsdist server right away
+func (d *DNSDISTUpdater) ResyncAll() ([]servers.ServerStatus, error) {
+	if err := d.synchronizeServers(); err != nil {
+		return nil, err
+	}
+	return d.Servers(), nil
+}
+
+// stores the outcome of a synchronization, drifted counts towards the drift count of the server
+func (d *DNSDISTUpdater) setStatus(status servers.ServerStatus, drifted bool) servers.ServerStatus {
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+
+	if d.status == nil {
+		d.status = make(map[string]servers.ServerStatus)
+	}
+
+	status.DriftCount = d.status[status.Name].DriftCount
+	if drifted {
+		status.DriftCount++
+		serverDrift.WithLabelValues(status.Name).Inc()
+	}
+	d.status[status.Name] = status
+
+	serverConnected.WithLabelValues(status.Name).Set(boolToFloat(status.Connected))
+	serverInSync.WithLabelValues(status.Name).Set(boolToFloat(status.InSync))
+	serverLastSync.WithLabelValues(status.Name).Set(float64(status.LastSync.Unix()))
+	serverMissingRules.WithLabelValues(status.Name).Set(float64(len(status.Missing)))
+	serverExtraRules.WithLabelValues(status.Name).Set(float64(len(status.Extra)))
+
+	return status
+}
+
+// returns the names of the desired spoofs that are not configured, and of the configured spoofs that are not desired
+func drift(configured, desired []spoofs.Spoof) (missing, extra []string) {
+	for _, spoof := range desired {
+		if !slices.ContainsFunc(configured, spoof.Equal) {
+			missing = append(missing, spoof.Key())
+		}
+	}
+	for _, spoof := range configured {
+		if !slices.ContainsFunc(desired, spoof.Equal) {
+			extra = append(extra, spoof.Key())
+		}
+	}
+	return missing, extra
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// marks a server that did not take an update as out of sync, until the next synchronization or retry
+func (d *DNSDISTUpdater) markOutOfSync(server string, reason error) {
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+
+	if d.status == nil {
+		d.status = make(map[string]servers.ServerStatus)
+	}
+
+	status := d.status[server]
+	status.Name = server
+	status.InSync = false
+	if reason != nil {
+		status.LastError = reason.Error()
+	}
+	d.status[server] = status
+
+	serverInSync.WithLabelValues(server).Set(0)
+}
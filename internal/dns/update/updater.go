@@ -1,8 +1,17 @@
 package update
 
-import "github.com/vitistack/gslb-operator/internal/service"
+import (
+	"github.com/vitistack/gslb-operator/internal/service"
+	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
+)
 
+// configures the spoofs of a service group on the dnsdist servers, and reports how every server took it
+// an update that too few servers took is rolled back on all of them, and reported as Reverted,
+// the caller must then undo the change in the store, or the synchronization configures it again
 type Updater interface {
-	OnServiceUp(*service.Service) error
-	OnServiceDown(*service.Service) error
+	OnServiceUp(*service.Service) (spoofs.FleetUpdate, error)
+	OnServiceDown(*service.Service) (spoofs.FleetUpdate, error)
+
+	// configures the spoofs of the service group as they are in the store, e.g. after an override changed
+	OnOverride(memberOf string) (spoofs.FleetUpdate, error)
 }
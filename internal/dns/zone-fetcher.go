@@ -2,25 +2,51 @@ package dns
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"codeberg.org/miekg/dns"
+	"codeberg.org/miekg/dns/dnsutil"
+	"codeberg.org/miekg/dns/rdata"
 	"github.com/vitistack/gslb-operator/internal/config"
 	"github.com/vitistack/gslb-operator/internal/utils/timesutil"
 	"github.com/vitistack/gslb-operator/pkg/bslog"
 )
 
-// Fetches a DNS zone from a dedicated server via AXFR
+// Fetches a DNS zone from dedicated servers via AXFR, or IXFR once the zone is known.
+// The zone is only transferred when its SOA serial changed,
+// and a NOTIFY from the server triggers a transfer right away.
+// The servers are tried in order, and the last-known-good zone is used on startup if none of them are reachable.
 type ZoneFetcher struct {
-	Zone     string
-	Server   string
-	wg       sync.WaitGroup
-	interval timesutil.Duration
-	timeout  time.Duration
-	client   *dns.Client
+	Zone       string
+	Servers    []string
+	wg         sync.WaitGroup
+	interval   timesutil.Duration
+	timeout    time.Duration
+	client     *dns.Client
+	notifyAddr string
+	tsig       *TSIGKey          // signs zone-transfers if set
+	snapshot   string            // file the last-known-good zone is saved to, disabled if empty
+	notify     chan struct{}     // pending transfer triggered by a NOTIFY
+	serial     uint32            // serial of the zone in records
+	records    map[string]dns.RR // the zone, without the SOA. nil until the first transfer
+}
+
+// change of the zone since the last transfer
+type ZoneChange struct {
+	Serial  uint32
+	Full    bool     // Added is the whole zone, and Deleted is empty
+	Added   []dns.RR // records added to the zone
+	Deleted []dns.RR // records removed from the zone
+	Zone    []dns.RR // the whole zone after the change, set once it is applied
 }
 
 type fetcherOption func(fetcher *ZoneFetcher)
@@ -34,18 +60,25 @@ func NewZoneFetcherWithAutoPoll(opts ...fetcherOption) *ZoneFetcher {
 	}
 
 	fetcher := &ZoneFetcher{ // default values
-		Zone:     gslb.Zone(),
-		Server:   gslb.NameServer(),
-		wg:       sync.WaitGroup{},
-		interval: fetcInterval,
-		timeout:  time.Second * 5,
-		client:   dns.NewClient(),
+		Zone:       gslb.Zone(),
+		Servers:    gslb.NameServers(),
+		wg:         sync.WaitGroup{},
+		interval:   fetcInterval,
+		timeout:    time.Second * 5,
+		client:     dns.NewClient(),
+		notifyAddr: gslb.NotifyAddr(),
+		snapshot:   gslb.ZoneSnapshot(),
+		notify:     make(chan struct{}, 1),
 	}
 
 	for _, opt := range opts { // set custom options
 		opt(fetcher)
 	}
 
+	if fetcher.tsig != nil {
+		fetcher.client.Transfer = fetcher.tsig.transfer()
+	}
+
 	return fetcher
 }
 
@@ -55,9 +88,10 @@ func WithZone(zone string) fetcherOption {
 	}
 }
 
-func WithServer(server string) fetcherOption {
+// servers to transfer the zone from, tried in order
+func WithServers(servers ...string) fetcherOption {
 	return func(fetcher *ZoneFetcher) {
-		fetcher.Server = server
+		fetcher.Servers = servers
 	}
 }
 
@@ -73,9 +107,30 @@ func WithTimeout(timeout time.Duration) fetcherOption {
 	}
 }
 
-// starts the auto-fetch, and listen for errors and records on the returned channels
-func (f *ZoneFetcher) StartAutoPoll(ctx context.Context) (zone chan []dns.RR, pollErrors chan error) {
-	zone = make(chan []dns.RR, 1)
+// address to listen for NOTIFY from the server on, over UDP. Empty disables the listener
+func WithNotifyAddr(addr string) fetcherOption {
+	return func(fetcher *ZoneFetcher) {
+		fetcher.notifyAddr = addr
+	}
+}
+
+// file to save the last-known-good zone to. Empty disables it
+func WithSnapshotFile(path string) fetcherOption {
+	return func(fetcher *ZoneFetcher) {
+		fetcher.snapshot = path
+	}
+}
+
+// signs zone-transfers with the key, and rejects responses that are not signed with it. nil disables TSIG
+func WithTSIG(key *TSIGKey) fetcherOption {
+	return func(fetcher *ZoneFetcher) {
+		fetcher.tsig = key
+	}
+}
+
+// starts the auto-fetch, and listen for errors and zone changes on the returned channels
+func (f *ZoneFetcher) StartAutoPoll(ctx context.Context) (zone chan ZoneChange, pollErrors chan error) {
+	zone = make(chan ZoneChange, 1)
 	pollErrors = make(chan error)
 
 	bslog.Debug("polling config zone", slog.String("interval", f.interval.String()))
@@ -85,7 +140,12 @@ func (f *ZoneFetcher) StartAutoPoll(ctx context.Context) (zone chan []dns.RR, po
 		defer close(pollErrors)
 		defer bslog.Debug("closing zone-fetcher")
 
-		f.AXFRTransfer(ctx, zone, pollErrors)
+		if f.notifyAddr != "" {
+			stopListener := f.listenNotify(ctx, pollErrors)
+			defer stopListener()
+		}
+
+		f.Refresh(ctx, zone, pollErrors)
 
 		for {
 			select {
@@ -94,7 +154,11 @@ func (f *ZoneFetcher) StartAutoPoll(ctx context.Context) (zone chan []dns.RR, po
 				return
 
 			case <-ticker.C:
-				f.AXFRTransfer(ctx, zone, pollErrors)
+				f.Refresh(ctx, zone, pollErrors)
+
+			case <-f.notify:
+				bslog.Debug("received NOTIFY for config zone")
+				f.Refresh(ctx, zone, pollErrors)
 			}
 		}
 	})
@@ -106,51 +170,339 @@ func (f *ZoneFetcher) StopPoll() {
 
 }
 
-func (f *ZoneFetcher) AXFRTransfer(ctx context.Context, zone chan []dns.RR, transferErrors chan error) {
+// transfers the zone if its serial changed, and publishes the change on zone.
+// once the zone is known, only the changed records are transferred if the server supports IXFR.
+// the servers are tried in order, until one of them succeeds.
+func (f *ZoneFetcher) Refresh(ctx context.Context, zone chan ZoneChange, transferErrors chan error) {
 	if ctx.Err() != nil {
 		return // context is cancelled
 	}
 
-	bslog.Debug("starting zone-transfer")
-	f.client.Transfer = &dns.Transfer{}
-	msg := dns.NewMsg(f.Zone, dns.TypeAXFR)
+	errs := make([]error, 0, len(f.Servers))
+	for _, server := range f.Servers {
+		change, err := f.refreshFrom(ctx, server)
+		if err == nil {
+			if change != nil {
+				// the known zone only moves on once the consumer has the change,
+				// otherwise the next refresh transfers it again
+				records := f.apply(change)
+				if f.publish(ctx, zone, *change) {
+					f.commit(records, change.Serial)
+					f.saveSnapshot()
+				}
+			}
+			return
+		}
+
+		if ctx.Err() != nil {
+			bslog.Debug("zone-transfer cancelled")
+			return
+		}
+		bslog.Warn("could not refresh config zone", slog.String("server", server), slog.String("reason", err.Error()))
+		errs = append(errs, fmt.Errorf("could not transfer zone: %v from server: %v:%w", f.Zone, server, err))
+	}
+
+	if len(f.Servers) == 0 {
+		errs = append(errs, ErrNoNameServers)
+	}
+
+	if f.records == nil { // nothing is known about the zone yet, fall back to the last-known-good zone
+		change, err := f.loadSnapshot()
+		if err == nil {
+			bslog.Warn("no nameserver is reachable, using last-known-good config zone", slog.Uint64("serial", uint64(change.Serial)))
+			records := f.apply(&change)
+			if f.publish(ctx, zone, change) {
+				f.commit(records, change.Serial)
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
 
-	envelopes, err := f.client.TransferIn(ctx, msg, "tcp", f.Server)
+	select {
+	case transferErrors <- errors.Join(errs...):
+	case <-ctx.Done():
+	}
+}
+
+// transfers the zone from server if its serial changed, returns nil if the known zone is up to date
+func (f *ZoneFetcher) refreshFrom(ctx context.Context, server string) (*ZoneChange, error) {
+	known := f.records != nil
+	serial, err := f.QuerySerial(ctx, server)
+	if err != nil {
+		bslog.Warn("could not query serial of config zone, transferring it anyway", slog.String("server", server), slog.String("reason", err.Error()))
+	} else if known && dns.EqualSerial(serial, f.serial) {
+		bslog.Debug("config zone is unchanged", slog.Uint64("serial", uint64(serial)))
+		zoneTransfers.WithLabelValues("unchanged").Inc()
+		return nil, nil
+	}
+
+	var change ZoneChange
+	if known {
+		change, err = f.IXFRTransfer(ctx, server)
+		if err != nil && ctx.Err() == nil {
+			bslog.Warn("incremental zone-transfer failed, falling back to AXFR", slog.String("server", server), slog.String("reason", err.Error()))
+		}
+	}
+
+	if !known || err != nil {
+		change, err = f.AXFRTransfer(ctx, server)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !change.Full && len(change.Added) == 0 && len(change.Deleted) == 0 {
+		bslog.Debug("config zone is up to date", slog.Uint64("serial", uint64(f.serial)))
+		return nil, nil
+	}
+	return &change, nil
+}
+
+// safe publish to consumer, returns whether the consumer received the change
+func (f *ZoneFetcher) publish(ctx context.Context, zone chan ZoneChange, change ZoneChange) bool {
+	select {
+	case <-ctx.Done():
+		bslog.Debug("zone-transfer cancelled before sending records")
+		return false
+	case zone <- change:
+		bslog.Debug("zone-transfer completed", slog.Uint64("serial", uint64(change.Serial)), slog.Bool("full", change.Full))
+		return true
+	case <-time.After(f.timeout): // dont block forever
+		bslog.Warn("zone-transfer timed out", slog.String("after", f.timeout.String()), slog.String("reason", "consumer may be blocked"))
+		return false
+	}
+}
+
+// returns the current SOA serial of the zone on the server
+func (f *ZoneFetcher) QuerySerial(ctx context.Context, server string) (uint32, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	msg := dns.NewMsg(f.Zone, dns.TypeSOA)
+	resp, _, err := f.client.Exchange(ctx, msg, "udp", server)
+	if err != nil {
+		return 0, fmt.Errorf("could not query SOA: %w", err)
+	}
+
+	for _, record := range resp.Answer {
+		if soa, ok := record.(*dns.SOA); ok {
+			return soa.Serial, nil
+		}
+	}
+	return 0, ErrMissingSOA
+}
+
+// transfers the whole zone
+func (f *ZoneFetcher) AXFRTransfer(ctx context.Context, server string) (ZoneChange, error) {
+	bslog.Debug("starting zone-transfer", slog.String("type", "AXFR"), slog.String("server", server))
+	records, err := f.transfer(ctx, dns.NewMsg(f.Zone, dns.TypeAXFR), server)
 	if err != nil {
-		transferErrors <- fmt.Errorf("could not transfer zone: %v from server: %v:%w", f.Zone, f.Server, err)
+		return ZoneChange{}, err
+	}
+
+	soa, ok := leadingSOA(records)
+	if !ok {
+		return ZoneChange{}, ErrMissingSOA
 	}
+
+	zoneTransfers.WithLabelValues("axfr").Inc()
+	return ZoneChange{
+		Serial: soa.Serial,
+		Full:   true,
+		Added:  withoutSOA(records),
+	}, nil
+}
+
+// transfers the changes since the serial of the known zone.
+// the server may answer with the whole zone instead, the change is then a full one.
+func (f *ZoneFetcher) IXFRTransfer(ctx context.Context, server string) (ZoneChange, error) {
+	bslog.Debug("starting zone-transfer", slog.String("type", "IXFR"), slog.String("server", server), slog.Uint64("serial", uint64(f.serial)))
+	msg := dns.NewMsg(f.Zone, dns.TypeIXFR)
+	msg.Ns = []dns.RR{&dns.SOA{
+		Hdr: dns.Header{Name: dnsutil.Fqdn(f.Zone), Class: dns.ClassINET},
+		SOA: rdata.SOA{Ns: ".", Mbox: ".", Serial: f.serial},
+	}}
+
+	records, err := f.transfer(ctx, msg, server)
+	if err != nil {
+		return ZoneChange{}, err
+	}
+
+	soa, ok := leadingSOA(records)
+	if !ok {
+		return ZoneChange{}, ErrMissingSOA
+	}
+	zoneTransfers.WithLabelValues("ixfr").Inc()
+
+	if len(records) == 1 { // we are up to date
+		return ZoneChange{Serial: f.serial}, nil
+	}
+
+	if _, ok := records[1].(*dns.SOA); !ok { // server sent the whole zone
+		return ZoneChange{Serial: soa.Serial, Full: true, Added: withoutSOA(records)}, nil
+	}
+
+	// SOA(new), then for every version: SOA(old), deleted records, SOA(new), added records. Ends with SOA(new)
+	change := ZoneChange{Serial: soa.Serial}
+	deleting := false
+	for _, record := range records[1 : len(records)-1] {
+		if _, ok := record.(*dns.SOA); ok {
+			deleting = !deleting
+			continue
+		}
+
+		if deleting {
+			change.Deleted = append(change.Deleted, record)
+		} else {
+			change.Added = append(change.Added, record)
+		}
+	}
+
+	return change, nil
+}
+
+// returns the records of the known zone
+func (f *ZoneFetcher) Records() []dns.RR {
+	records := make([]dns.RR, 0, len(f.records))
+	for _, record := range f.records {
+		records = append(records, record)
+	}
+	return records
+}
+
+// applies the change to a copy of the known zone, and returns the copy.
+// the whole zone after the change is set on the change
+func (f *ZoneFetcher) apply(change *ZoneChange) map[string]dns.RR {
+	records := make(map[string]dns.RR, len(f.records)+len(change.Added))
+	if !change.Full {
+		maps.Copy(records, f.records)
+	}
+
+	for _, record := range change.Deleted {
+		delete(records, recordKey(record))
+	}
+	for _, record := range change.Added {
+		records[recordKey(record)] = record
+	}
+
+	change.Zone = slices.Collect(maps.Values(records))
+	return records
+}
+
+// makes records the known zone at serial
+func (f *ZoneFetcher) commit(records map[string]dns.RR, serial uint32) {
+	f.records = records
+	f.serial = serial
+	zoneSerial.Set(float64(serial))
+}
+
+// reads every record of a zone-transfer
+func (f *ZoneFetcher) transfer(ctx context.Context, msg *dns.Msg, server string) ([]dns.RR, error) {
+	if f.tsig != nil {
+		f.tsig.sign(msg)
+	}
+
+	envelopes, err := f.client.TransferIn(ctx, msg, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+
 	records := make([]dns.RR, 0)
 	for {
 		select {
 		case <-ctx.Done():
-			bslog.Debug("zone-transfer cancelled before sending records")
-			return
+			return nil, ctx.Err()
 
 		case envelope, ok := <-envelopes:
 			if !ok { // transfer completed
-				// safe publish to consumer
-				select {
-				case <-ctx.Done():
-					bslog.Debug("zone-transfer cancelled before sending records")
-					return
-				case zone <- records:
-					bslog.Debug("zone-transfer completed")
-					return
-				case <-time.After(f.timeout): // dont block forever
-					bslog.Warn("zone-transfer timed out", slog.String("after", f.timeout.String()), slog.String("reason", "consumer may be blocked"))
-					return
-				}
+				return records, nil
 			}
 
 			if envelope.Error != nil {
-				transferErrors <- envelope.Error
-				return
+				return nil, envelope.Error
 			}
 			records = append(records, envelope.Answer...)
 
 		case <-time.After(f.timeout):
 			bslog.Warn("zone-transfer timed out", slog.String("after", f.timeout.String()), slog.String("reason", "stopped receiving records, but connection did not terminate"))
-			return
+			return nil, ErrTransferTimeout
+		}
+	}
+}
+
+// starts a DNS server that accepts NOTIFY for the zone, and returns a func that stops it
+func (f *ZoneFetcher) listenNotify(ctx context.Context, listenErrors chan error) func() {
+	server := &dns.Server{
+		Addr:    f.notifyAddr,
+		Net:     "udp",
+		Handler: dns.HandlerFunc(f.HandleNotify),
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		bslog.Info("listening for NOTIFY", slog.String("addr", f.notifyAddr))
+		if err := server.ListenAndServe(); err != nil {
+			select {
+			case listenErrors <- fmt.Errorf("could not listen for NOTIFY on: %v:%w", f.notifyAddr, err):
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return func() {
+		server.Shutdown(context.Background())
+		<-stopped
+	}
+}
+
+// answers a NOTIFY for the zone, and triggers a transfer.
+// anyone may send a NOTIFY, but it only makes the fetcher check the serial on the server.
+func (f *ZoneFetcher) HandleNotify(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) {
+	resp := new(dns.Msg)
+	dnsutil.SetReply(resp, r)
+
+	name, _ := dnsutil.Question(r)
+	switch {
+	case r.Opcode != dns.OpcodeNotify:
+		resp.Rcode = dns.RcodeRefused
+	case !strings.EqualFold(dnsutil.Fqdn(name), dnsutil.Fqdn(f.Zone)):
+		resp.Rcode = dns.RcodeNotAuth
+	default:
+		resp.Authoritative = true
+		select {
+		case f.notify <- struct{}{}:
+		default: // a transfer is already pending
+		}
+	}
+
+	if _, err := io.Copy(w, resp); err != nil && !errors.Is(err, context.Canceled) {
+		bslog.Warn("could not answer NOTIFY", slog.String("reason", err.Error()))
+	}
+}
+
+// identifies a record, regardless of its TTL
+func recordKey(record dns.RR) string {
+	hdr := record.Header()
+	return fmt.Sprintf("%s %d %s", strings.ToLower(hdr.Name), dns.RRToType(record), record.Data().String())
+}
+
+func leadingSOA(records []dns.RR) (*dns.SOA, bool) {
+	if len(records) == 0 {
+		return nil, false
+	}
+	soa, ok := records[0].(*dns.SOA)
+	return soa, ok
+}
+
+// a zone-transfer starts and ends with the SOA of the zone
+func withoutSOA(records []dns.RR) []dns.RR {
+	zone := make([]dns.RR, 0, len(records))
+	for _, record := range records {
+		if _, ok := record.(*dns.SOA); !ok {
+			zone = append(zone, record)
 		}
 	}
+	return zone
 }
@@ -0,0 +1,336 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"codeberg.org/miekg/dns"
+	"codeberg.org/miekg/dns/dnstest"
+	"codeberg.org/miekg/dns/dnsutil"
+	"github.com/vitistack/gslb-operator/internal/source"
+)
+
+const testZone = "gslb.example.com."
+
+func testSOA(serial uint32) dns.RR {
+	return dnstest.New(fmt.Sprintf("%s 3600 IN SOA ns.example.com. admin.example.com. %d 3600 600 86400 60", testZone, serial))
+}
+
+func testConfigRecord(serviceID, ip string) dns.RR {
+	return dnstest.New(fmt.Sprintf(`app.%s 60 IN TXT "{\"service_id\":\"%s\",\"fqdn\":\"app.example.com\",\"ip\":\"%s\",\"port\":\"443\",\"datacenter\":\"dc1\",\"interval\":\"5s\"}"`, testZone, serviceID, ip))
+}
+
+// primary nameserver of the test zone, serving the SOA, AXFR and IXFR from serial 1
+type fakePrimary struct {
+	mu          sync.Mutex
+	serial      uint32
+	zone        []dns.RR       // without the SOA
+	incremental []dns.RR       // IXFR answer from serial 1
+	tsig        dns.TSIGSigner // signs the transfer if set
+}
+
+func (p *fakePrimary) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) {
+	r.Unpack()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, qtype := dnsutil.Question(r)
+	var answer []dns.RR
+	switch qtype {
+	case dns.TypeSOA:
+		resp := new(dns.Msg)
+		dnsutil.SetReply(resp, r)
+		resp.Answer = []dns.RR{testSOA(p.serial)}
+		io.Copy(w, resp)
+		return
+	case dns.TypeAXFR:
+		answer = append(append([]dns.RR{testSOA(p.serial)}, p.zone...), testSOA(p.serial))
+	case dns.TypeIXFR:
+		answer = p.incremental
+	}
+
+	client := new(dns.Client)
+	if p.tsig != nil {
+		client.Transfer = &dns.Transfer{TSIGSigner: p.tsig}
+	}
+
+	w.Hijack() // we close the connection when the transfer is done
+	env := make(chan *dns.Envelope)
+	go func() {
+		client.TransferOut(w, r, env)
+		w.Close()
+	}()
+	env <- &dns.Envelope{Answer: answer}
+	close(env)
+}
+
+// starts the primary on both tcp and udp, and returns its address
+func startPrimary(t *testing.T, primary *fakePrimary) string {
+	t.Helper()
+	handler := func(s *dns.Server) { s.Handler = primary }
+
+	cancelTCP, addr, err := dnstest.TCPServer("127.0.0.1:0", handler)
+	if err != nil {
+		t.Fatalf("could not start tcp server: %v", err)
+	}
+	t.Cleanup(cancelTCP)
+
+	cancelUDP, _, err := dnstest.UDPServer(addr, handler)
+	if err != nil {
+		t.Fatalf("could not start udp server: %v", err)
+	}
+	t.Cleanup(cancelUDP)
+
+	return addr
+}
+
+func TestZoneFetcher_Refresh(t *testing.T) {
+	primary := &fakePrimary{
+		serial: 1,
+		zone: []dns.RR{
+			testConfigRecord("svc-1", "10.0.0.1"),
+			testConfigRecord("svc-2", "10.0.0.2"),
+		},
+	}
+	addr := startPrimary(t, primary)
+
+	fetcher := NewZoneFetcherWithAutoPoll(WithZone(testZone), WithServers(addr), WithNotifyAddr(""), WithSnapshotFile(""))
+	zone := make(chan ZoneChange, 1)
+	errs := make(chan error, 1)
+	ctx := context.Background()
+
+	fetcher.Refresh(ctx, zone, errs)
+	change := receive(t, zone, errs)
+	updates := fetcher.updatesOf(change)
+	if len(updates) != 1 || updates[0].Kind != source.SNAPSHOT || len(updates[0].Configs) != 2 {
+		t.Fatalf("expected snapshot of both configs after the first transfer, got: %+v", updates)
+	}
+
+	fetcher.Refresh(ctx, zone, errs)
+	if len(zone) != 0 {
+		t.Fatalf("did not expect a change when the serial is unchanged, got: %+v", <-zone)
+	}
+
+	primary.mu.Lock()
+	primary.serial = 2
+	primary.incremental = []dns.RR{
+		testSOA(2),
+		testSOA(1),
+		testConfigRecord("svc-1", "10.0.0.1"),
+		testConfigRecord("svc-2", "10.0.0.2"),
+		testSOA(2),
+		testConfigRecord("svc-1", "10.0.0.11"),
+		testConfigRecord("svc-3", "10.0.0.3"),
+		testSOA(2),
+	}
+	primary.mu.Unlock()
+
+	fetcher.Refresh(ctx, zone, errs)
+	change = receive(t, zone, errs)
+	if change.Full || change.Serial != 2 {
+		t.Fatalf("expected incremental change to serial 2, got: %+v", change)
+	}
+
+	updates = fetcher.updatesOf(change)
+	if len(updates) != 2 {
+		t.Fatalf("expected a delete and an upsert, got: %+v", updates)
+	}
+	if updates[0].Kind != source.DELETE || len(updates[0].Configs) != 1 || updates[0].Configs[0].ServiceID != "svc-2" {
+		t.Errorf("expected only svc-2 to be deleted, got: %+v", updates[0])
+	}
+	if updates[1].Kind != source.UPSERT || len(updates[1].Configs) != 2 {
+		t.Errorf("expected svc-1 and svc-3 to be upserted, got: %+v", updates[1])
+	}
+
+	configs := ParseConfigRecords(fetcher.Records())
+	if len(configs) != 2 {
+		t.Fatalf("expected the known zone to have two configs, got: %+v", configs)
+	}
+	for _, config := range configs {
+		if config.ServiceID == "svc-1" && config.Ip != "10.0.0.11" {
+			t.Errorf("expected svc-1 to be updated in the known zone, got: %+v", config)
+		}
+	}
+}
+
+func TestZoneFetcher_Failover(t *testing.T) {
+	primary := &fakePrimary{
+		serial: 7,
+		zone:   []dns.RR{testConfigRecord("svc-1", "10.0.0.1")},
+	}
+	addr := startPrimary(t, primary)
+	snapshot := filepath.Join(t.TempDir(), "zone-snapshot.json")
+	unreachable := "127.0.0.1:1"
+
+	fetcher := NewZoneFetcherWithAutoPoll(WithZone(testZone), WithServers(unreachable, addr), WithTimeout(time.Millisecond*200), WithNotifyAddr(""), WithSnapshotFile(snapshot))
+	zone := make(chan ZoneChange, 1)
+	errs := make(chan error, 1)
+	fetcher.Refresh(context.Background(), zone, errs)
+	if change := receive(t, zone, errs); change.Serial != 7 || len(change.Added) != 1 {
+		t.Fatalf("expected the zone from the second server, got: %+v", change)
+	}
+
+	// restarting while no server is reachable uses the last-known-good zone
+	restarted := NewZoneFetcherWithAutoPoll(WithZone(testZone), WithServers(unreachable), WithTimeout(time.Millisecond*200), WithNotifyAddr(""), WithSnapshotFile(snapshot))
+	zone = make(chan ZoneChange, 1)
+	errs = make(chan error, 1)
+	restarted.Refresh(context.Background(), zone, errs)
+
+	change := <-zone
+	if !change.Full || change.Serial != 7 {
+		t.Fatalf("expected the last-known-good zone, got: %+v", change)
+	}
+	configs := ParseConfigRecords(change.Added)
+	if len(configs) != 1 || configs[0].ServiceID != "svc-1" {
+		t.Errorf("expected the config of the last-known-good zone, got: %+v", configs)
+	}
+	if err := <-errs; err == nil {
+		t.Error("expected the unreachable server to be reported")
+	}
+}
+
+func TestZoneFetcher_StalledConsumer(t *testing.T) {
+	primary := &fakePrimary{
+		serial: 3,
+		zone:   []dns.RR{testConfigRecord("svc-1", "10.0.0.1")},
+	}
+	addr := startPrimary(t, primary)
+	snapshot := filepath.Join(t.TempDir(), "zone-snapshot.json")
+
+	fetcher := NewZoneFetcherWithAutoPoll(WithZone(testZone), WithServers(addr), WithTimeout(time.Millisecond*200), WithNotifyAddr(""), WithSnapshotFile(snapshot))
+	zone := make(chan ZoneChange) // nobody receives, so the change times out
+	errs := make(chan error, 1)
+	fetcher.Refresh(context.Background(), zone, errs)
+
+	if len(fetcher.Records()) != 0 || fetcher.serial != 0 {
+		t.Fatalf("expected the dropped change to leave the known zone untouched, got serial: %d, records: %+v", fetcher.serial, fetcher.Records())
+	}
+	if _, err := os.Stat(snapshot); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected no last-known-good zone to be saved for the dropped change, got: %v", err)
+	}
+
+	// the consumer is back, so the same serial is transferred again instead of being skipped as unchanged
+	zone = make(chan ZoneChange, 1)
+	fetcher.Refresh(context.Background(), zone, errs)
+	if change := receive(t, zone, errs); !change.Full || change.Serial != 3 || len(change.Zone) != 1 {
+		t.Fatalf("expected the whole zone at serial 3, got: %+v", change)
+	}
+	if fetcher.serial != 3 || len(fetcher.Records()) != 1 {
+		t.Errorf("expected the known zone to be at serial 3 after the change was received, got serial: %d", fetcher.serial)
+	}
+	if _, err := os.Stat(snapshot); err != nil {
+		t.Errorf("expected the last-known-good zone to be saved, got: %v", err)
+	}
+}
+
+func TestZoneFetcher_TSIG(t *testing.T) {
+	const secret = "c2VjcmV0LWtleS1vZi10aGUtZ3NsYi16b25l"
+
+	if _, err := NewTSIGKey("transfer.", "hmac-sha256", "not base64"); !errors.Is(err, ErrInvalidTSIGSecret) {
+		t.Errorf("expected invalid secret to be rejected, got: %v", err)
+	}
+	if _, err := NewTSIGKey("transfer.", "hmac-md5", secret); !errors.Is(err, ErrUnsupportedTSIGAlgorithm) {
+		t.Errorf("expected unsupported algorithm to be rejected, got: %v", err)
+	}
+
+	key, err := NewTSIGKey("Transfer", "HMAC-SHA256", secret)
+	if err != nil {
+		t.Fatalf("NewTSIGKey() failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		signer  dns.TSIGSigner
+		wantErr bool
+	}{
+		{"signed", dns.HmacTSIG{Secret: key.Secret}, false},
+		{"bad signature", dns.HmacTSIG{Secret: []byte("another-secret")}, true},
+		{"unsigned", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &fakePrimary{
+				serial: 1,
+				zone:   []dns.RR{testConfigRecord("svc-1", "10.0.0.1")},
+				tsig:   tt.signer,
+			}
+			addr := startPrimary(t, primary)
+
+			fetcher := NewZoneFetcherWithAutoPoll(WithZone(testZone), WithServers(addr), WithNotifyAddr(""), WithSnapshotFile(""), WithTSIG(key))
+			zone := make(chan ZoneChange, 1)
+			errs := make(chan error, 1)
+			fetcher.Refresh(context.Background(), zone, errs)
+
+			select {
+			case err := <-errs:
+				if !tt.wantErr {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			case change := <-zone:
+				if tt.wantErr {
+					t.Fatalf("expected transfer to be rejected, got: %+v", change)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("expected the transfer to complete or fail")
+			}
+		})
+	}
+}
+
+func TestZoneFetcher_HandleNotify(t *testing.T) {
+	fetcher := NewZoneFetcherWithAutoPoll(WithZone(testZone), WithSnapshotFile(""))
+	cancel, addr, err := dnstest.UDPServer("127.0.0.1:0", func(s *dns.Server) {
+		s.Handler = dns.HandlerFunc(fetcher.HandleNotify)
+	})
+	if err != nil {
+		t.Fatalf("could not start udp server: %v", err)
+	}
+	defer cancel()
+
+	tests := []struct {
+		zone      string
+		wantRcode uint16
+		wantFetch bool
+	}{
+		{"other.example.com.", dns.RcodeNotAuth, false},
+		{testZone, dns.RcodeSuccess, true},
+	}
+
+	for _, tt := range tests {
+		notify := dns.NewMsg(tt.zone, dns.TypeSOA)
+		notify.Opcode = dns.OpcodeNotify
+		resp, err := dns.Exchange(context.Background(), notify, "udp", addr)
+		if err != nil {
+			t.Fatalf("could not send NOTIFY: %v", err)
+		}
+
+		if resp.Rcode != tt.wantRcode {
+			t.Errorf("NOTIFY for %s: expected rcode: %s, but got: %s", tt.zone, dns.RcodeToString[tt.wantRcode], dns.RcodeToString[resp.Rcode])
+		}
+		if fetch := len(fetcher.notify) == 1; fetch != tt.wantFetch {
+			t.Errorf("NOTIFY for %s: expected transfer to be triggered: %v", tt.zone, tt.wantFetch)
+		}
+	}
+}
+
+func receive(t *testing.T, zone chan ZoneChange, errs chan error) ZoneChange {
+	t.Helper()
+	select {
+	case change := <-zone:
+		return change
+	case err := <-errs:
+		t.Fatalf("refresh failed: %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("expected a change of the zone")
+	}
+	return ZoneChange{}
+}
@@ -0,0 +1,293 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	SERVICE_ACCOUNT_DIR = "/var/run/secrets/kubernetes.io/serviceaccount"
+	DEFAULT_TIMEOUT     = time.Second * 10
+)
+
+// minimal client for the GSLBConfig resources of the kubernetes api server
+type Client struct {
+	server    string
+	token     string
+	tokenFile string // read on every request if set, instead of token
+	namespace string // empty for every namespace
+	http      *http.Client
+}
+
+type clientOption func(c *Client)
+
+// creates a client for the api server at server, e.g. https://10.96.0.1:443
+func NewClient(server string, opts ...clientOption) *Client {
+	client := &Client{
+		server: strings.TrimSuffix(server, "/"),
+		http: &http.Client{
+			Timeout: DEFAULT_TIMEOUT,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	return client
+}
+
+// creates a client from the service account mounted in the pod
+func NewInClusterClient(opts ...clientOption) (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, ErrNotInCluster
+	}
+
+	tokenFile := SERVICE_ACCOUNT_DIR + "/token"
+	if _, err := os.Stat(tokenFile); err != nil {
+		return nil, fmt.Errorf("could not read service account token: %w", err)
+	}
+
+	ca, err := os.ReadFile(SERVICE_ACCOUNT_DIR + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("could not read service account ca: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in service account ca")
+	}
+
+	httpClient := &http.Client{
+		Timeout: DEFAULT_TIMEOUT,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}
+
+	opts = append([]clientOption{WithTokenFile(tokenFile), WithHTTPClient(httpClient)}, opts...)
+	return NewClient("https://"+net.JoinHostPort(host, port), opts...), nil
+}
+
+// bearer token used to authenticate to the api server
+func WithToken(token string) clientOption {
+	return func(c *Client) {
+		c.token = strings.TrimSpace(token)
+	}
+}
+
+// file the bearer token is read from on every request, so a rotated service account token is picked up
+func WithTokenFile(path string) clientOption {
+	return func(c *Client) {
+		c.tokenFile = path
+	}
+}
+
+// only list and watch resources in namespace
+func WithNamespace(namespace string) clientOption {
+	return func(c *Client) {
+		c.namespace = namespace
+	}
+}
+
+func WithHTTPClient(client *http.Client) clientOption {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// returns the path of the resources, in the namespace if set
+func (c *Client) resourcePath(namespace string) string {
+	path := "/apis/" + GROUP + "/" + VERSION
+	if namespace != "" {
+		path += "/namespaces/" + url.PathEscape(namespace)
+	}
+	return path + "/" + RESOURCE
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	endpoint := c.server + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// sets the bearer token on req, read from the token file if set
+func (c *Client) authorize(req *http.Request) error {
+	token := c.token
+	if c.tokenFile != "" {
+		raw, err := os.ReadFile(c.tokenFile)
+		if err != nil {
+			return fmt.Errorf("could not read service account token: %w", err)
+		}
+		token = strings.TrimSpace(string(raw))
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// sends req with client. when the api server rejects the token read from the token file,
+// it may have been rotated since, so the token is read again and req is sent once more
+func (c *Client) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || c.tokenFile == "" {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, fmt.Errorf("could not resend request: %w", err)
+		}
+	}
+	if err := c.authorize(retry); err != nil {
+		return nil, err
+	}
+	return client.Do(retry)
+}
+
+// lists every GSLBConfig resource
+func (c *Client) List(ctx context.Context) (*GSLBConfigList, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, c.resourcePath(c.namespace), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(c.http, req)
+	if err != nil {
+		return nil, fmt.Errorf("could not list %s: %w", RESOURCE, err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	list := &GSLBConfigList{}
+	if err := json.NewDecoder(resp.Body).Decode(list); err != nil {
+		return nil, fmt.Errorf("could not decode %s: %w", RESOURCE, err)
+	}
+
+	return list, nil
+}
+
+// watches GSLBConfig resources for changes after resourceVersion.
+// the returned channel is closed when the api server ends the watch, or ctx is cancelled.
+func (c *Client) Watch(ctx context.Context, resourceVersion string) (<-chan WatchEvent, error) {
+	query := url.Values{}
+	query.Set("watch", "true")
+	query.Set("allowWatchBookmarks", "true")
+	if resourceVersion != "" {
+		query.Set("resourceVersion", resourceVersion)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, c.resourcePath(c.namespace), query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// a watch is long lived, so the client timeout can not be used
+	watchClient := *c.http
+	watchClient.Timeout = 0
+
+	resp, err := c.do(&watchClient, req)
+	if err != nil {
+		return nil, fmt.Errorf("could not watch %s: %w", RESOURCE, err)
+	}
+
+	if err := checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	events := make(chan WatchEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			event := WatchEvent{}
+			if err := decoder.Decode(&event); err != nil {
+				return // the watch ended
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// replaces the status of the GSLBConfig resource, through the status subresource
+func (c *Client) UpdateStatus(ctx context.Context, namespace, name string, status GSLBConfigStatus) error {
+	patch, err := json.Marshal(map[string]GSLBConfigStatus{"status": status})
+	if err != nil {
+		return fmt.Errorf("could not marshal status: %w", err)
+	}
+
+	path := c.resourcePath(namespace) + "/" + url.PathEscape(name) + "/status"
+	req, err := c.newRequest(ctx, http.MethodPatch, path, nil, bytes.NewReader(patch))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	resp, err := c.do(c.http, req)
+	if err != nil {
+		return fmt.Errorf("could not update status of %s/%s: %w", namespace, name, err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+// returns an error if resp is not successful, with the message of the api server if there is one
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	status := Status{}
+	body, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, &status); err != nil || status.Message == "" {
+		status.Message = string(body)
+	}
+
+	if resp.StatusCode == http.StatusGone {
+		return fmt.Errorf("%w: %s", ErrResourceVersionGone, status.Message)
+	}
+	return fmt.Errorf("%w: %s: %s", ErrUnexpectedStatus, resp.Status, status.Message)
+}
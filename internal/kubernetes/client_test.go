@@ -0,0 +1,69 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClient_RotatedToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("first\n"), 0o600); err != nil {
+		t.Fatalf("could not write token: %v", err)
+	}
+
+	var authorizations []string
+	var patches []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != "Bearer second" {
+			// the token is rotated while the request with the old one is on its way
+			os.WriteFile(tokenFile, []byte("second\n"), 0o600)
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(Status{Message: "Unauthorized"})
+			return
+		}
+
+		if r.Method == http.MethodPatch {
+			body, _ := io.ReadAll(r.Body)
+			patches = append(patches, string(body))
+		}
+		json.NewEncoder(w).Encode(GSLBConfigList{})
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(server.URL, WithTokenFile(tokenFile))
+	if _, err := client.List(context.Background()); err != nil {
+		t.Fatalf("expected the list to be retried with the rotated token, got: %v", err)
+	}
+	if len(authorizations) != 2 || authorizations[0] != "Bearer first" || authorizations[1] != "Bearer second" {
+		t.Fatalf("expected one retry with the token read again, got: %v", authorizations)
+	}
+
+	os.WriteFile(tokenFile, []byte("third\n"), 0o600)
+	authorizations = nil
+	if err := client.UpdateStatus(context.Background(), "gslb", "app", GSLBConfigStatus{}); err != nil {
+		t.Fatalf("expected the status update to be retried with the rotated token, got: %v", err)
+	}
+	if len(patches) != 1 || patches[0] == "" {
+		t.Errorf("expected the body of the status update to be sent again, got: %v", patches)
+	}
+
+	// the token keeps being rejected, so it is only retried once
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	authorizations = nil
+	if _, err := client.List(context.Background()); err == nil {
+		t.Fatal("expected a rejected token to fail the list")
+	}
+	if len(authorizations) != 2 {
+		t.Errorf("expected a single retry, got: %v", authorizations)
+	}
+}
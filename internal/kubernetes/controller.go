@@ -0,0 +1,414 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/internal/service"
+	"github.com/vitistack/gslb-operator/internal/source"
+	"github.com/vitistack/gslb-operator/pkg/bslog"
+	"github.com/vitistack/gslb-operator/pkg/models/rejections"
+)
+
+const (
+	DEFAULT_STATUS_INTERVAL = time.Second * 30
+	DEFAULT_RETRY_INTERVAL  = time.Second * 5
+)
+
+// the part of the services manager the controller needs to report status
+type ServiceLookup interface {
+	GetService(id string) *service.Service
+}
+
+// Controller is a source.ConfigSource for GSLBConfig resources,
+// that also writes the health of the services back to the status of the resources.
+type Controller struct {
+	client         *Client
+	lookup         ServiceLookup
+	statusInterval time.Duration
+	retryInterval  time.Duration
+	resources      map[string]*resource // namespace/name: resource
+	mu             sync.Mutex
+	wg             sync.WaitGroup
+}
+
+// a GSLBConfig resource known by the controller
+type resource struct {
+	namespace string
+	name      string
+	serviceID string
+	rejection *rejections.Rejection // set if the config is not valid
+	status    GSLBConfigStatus      // last status written to the api server
+}
+
+type controllerOption func(c *Controller)
+
+func NewController(client *Client, lookup ServiceLookup, opts ...controllerOption) *Controller {
+	controller := &Controller{
+		client:         client,
+		lookup:         lookup,
+		statusInterval: DEFAULT_STATUS_INTERVAL,
+		retryInterval:  DEFAULT_RETRY_INTERVAL,
+		resources:      make(map[string]*resource),
+	}
+
+	for _, opt := range opts {
+		opt(controller)
+	}
+
+	return controller
+}
+
+// how often the health of the services is written to the status of the resources
+func WithStatusInterval(interval time.Duration) controllerOption {
+	return func(c *Controller) {
+		c.statusInterval = interval
+	}
+}
+
+// how long to wait before listing the resources again, after the api server failed
+func WithRetryInterval(interval time.Duration) controllerOption {
+	return func(c *Controller) {
+		c.retryInterval = interval
+	}
+}
+
+func (c *Controller) Name() string {
+	if c.client.namespace != "" {
+		return "kubernetes:" + c.client.namespace
+	}
+	return "kubernetes"
+}
+
+// starts watching resources and writing status until ctx is cancelled
+func (c *Controller) Start(ctx context.Context) (<-chan source.Update, <-chan error) {
+	updates := make(chan source.Update)
+	errs := make(chan error)
+
+	c.wg.Go(func() {
+		defer close(updates)
+		defer close(errs)
+		c.run(ctx, updates, errs)
+	})
+
+	c.wg.Go(func() {
+		ticker := time.NewTicker(c.statusInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.WriteStatus(ctx)
+			}
+		}
+	})
+
+	return updates, errs
+}
+
+// waits for the controller to stop, after the context passed to Start is cancelled
+func (c *Controller) Stop() {
+	c.wg.Wait()
+}
+
+// lists the resources, and watches for changes. A watch that ends is resumed from the last seen resource version,
+// the resources are only listed again when that version is gone, or the api server failed.
+func (c *Controller) run(ctx context.Context, updates chan<- source.Update, errs chan<- error) {
+	emit := func(update source.Update) {
+		select {
+		case updates <- update:
+		case <-ctx.Done():
+		}
+	}
+
+	resourceVersion := ""
+	for ctx.Err() == nil {
+		var err error
+		if resourceVersion == "" {
+			var snapshot source.Update
+			snapshot, resourceVersion, err = c.Resync(ctx)
+			if err == nil {
+				emit(snapshot)
+			}
+		}
+		if err == nil {
+			resourceVersion, err = c.watch(ctx, resourceVersion, emit)
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err != nil {
+			resourceVersion = ""
+			select {
+			case errs <- fmt.Errorf("failed to reconcile GSLBConfig resources: %w", err):
+			case <-ctx.Done():
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(c.retryInterval):
+			}
+		}
+	}
+	bslog.Debug("stopped watching GSLBConfig resources")
+}
+
+// lists every resource, and returns the snapshot of their configs and the resource version to watch from.
+func (c *Controller) Resync(ctx context.Context) (source.Update, string, error) {
+	list, err := c.client.List(ctx)
+	if err != nil {
+		return source.Update{}, "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	listed := make(map[string]struct{}, len(list.Items))
+	configs := make([]model.GSLBConfig, 0, len(list.Items))
+	for _, config := range list.Items {
+		listed[config.Key()] = struct{}{}
+		if gslbConfig, ok := c.apply(config); ok {
+			configs = append(configs, gslbConfig)
+		}
+	}
+
+	for key := range c.resources { // forget resources that no longer exist
+		if _, ok := listed[key]; !ok {
+			delete(c.resources, key)
+		}
+	}
+
+	snapshot := source.Update{
+		Source:     c.Name(),
+		Kind:       source.SNAPSHOT,
+		Configs:    configs,
+		Rejections: c.rejections(),
+	}
+	return snapshot, list.Metadata.ResourceVersion, nil
+}
+
+// watches for changes after resourceVersion until the watch ends, and returns the last seen resource version to resume from.
+// the returned version is empty when it is gone, and the resources must be listed again
+func (c *Controller) watch(ctx context.Context, resourceVersion string, emit func(source.Update)) (string, error) {
+	events, err := c.client.Watch(ctx, resourceVersion)
+	if err != nil {
+		return "", err
+	}
+
+	for event := range events {
+		switch event.Type {
+		case EVENT_ADDED, EVENT_MODIFIED, EVENT_DELETED, EVENT_BOOKMARK:
+			config := GSLBConfig{}
+			if err := json.Unmarshal(event.Object, &config); err != nil {
+				return "", fmt.Errorf("could not decode %s event: %w", event.Type, err)
+			}
+			if config.Metadata.ResourceVersion != "" {
+				resourceVersion = config.Metadata.ResourceVersion
+			}
+			if event.Type == EVENT_BOOKMARK { // only moves the resource version
+				continue
+			}
+
+			c.mu.Lock()
+			var changes []source.Update
+			if event.Type == EVENT_DELETED {
+				changes = c.remove(config)
+			} else {
+				changes = c.upsert(config)
+			}
+			c.mu.Unlock()
+
+			for _, change := range changes { // a slow consumer must not hold up the status writes
+				emit(change)
+			}
+
+		case EVENT_ERROR:
+			status := Status{}
+			_ = json.Unmarshal(event.Object, &status)
+			if status.Code == http.StatusGone {
+				bslog.Debug("watch expired, listing GSLBConfig resources again", slog.String("reason", status.Message))
+				return "", nil
+			}
+			return "", fmt.Errorf("%w: watch failed: %s", ErrUnexpectedStatus, status.Message)
+		}
+	}
+
+	return resourceVersion, nil
+}
+
+// returns the updates for an added or modified resource.
+// expects the caller to hold the lock
+func (c *Controller) upsert(config GSLBConfig) []source.Update {
+	changes := make([]source.Update, 0, 2)
+	var oldServiceID string
+	if known, ok := c.resources[config.Key()]; ok {
+		oldServiceID = known.serviceID
+	}
+
+	gslbConfig, ok := c.apply(config)
+	if oldServiceID != "" && (!ok || oldServiceID != gslbConfig.ServiceID) { // the old service is gone
+		changes = append(changes, source.Update{
+			Source:     c.Name(),
+			Kind:       source.DELETE,
+			Configs:    []model.GSLBConfig{{ServiceID: oldServiceID}},
+			Rejections: c.rejections(),
+		})
+	}
+
+	if ok || oldServiceID == "" { // an invalid resource is still emitted, to report its rejection
+		update := source.Update{
+			Source:     c.Name(),
+			Kind:       source.UPSERT,
+			Rejections: c.rejections(),
+		}
+		if ok {
+			update.Configs = []model.GSLBConfig{gslbConfig}
+		}
+		changes = append(changes, update)
+	}
+	return changes
+}
+
+// returns the update for a deleted resource.
+// expects the caller to hold the lock
+func (c *Controller) remove(config GSLBConfig) []source.Update {
+	known, ok := c.resources[config.Key()]
+	delete(c.resources, config.Key())
+	if !ok {
+		return nil
+	}
+
+	bslog.Info("GSLBConfig resource no longer exists", slog.String("resource", config.Key()))
+	update := source.Update{
+		Source:     c.Name(),
+		Kind:       source.DELETE,
+		Rejections: c.rejections(),
+	}
+	if known.serviceID != "" {
+		update.Configs = []model.GSLBConfig{{ServiceID: known.serviceID}}
+	}
+	return []source.Update{update}
+}
+
+// remembers the resource, and returns its GSLB - config if it is valid.
+// expects the caller to hold the lock
+func (c *Controller) apply(config GSLBConfig) (model.GSLBConfig, bool) {
+	key := config.Key()
+	known, ok := c.resources[key]
+	if !ok {
+		known = &resource{
+			namespace: config.Metadata.Namespace,
+			name:      config.Metadata.Name,
+		}
+		c.resources[key] = known
+	}
+
+	gslbConfig := config.GSLBConfig()
+	err := service.ValidateGSLBConfig(gslbConfig)
+	if err == nil {
+		_, err = service.NewServiceFromGSLBConfig(gslbConfig, service.WithDryRunChecks(true))
+	}
+	if err != nil {
+		bslog.Error("invalid GSLBConfig resource", slog.String("resource", key), slog.String("reason", err.Error()))
+		rejection := source.NewRejection(c.Name(), key, gslbConfig, err)
+		if known.rejection != nil && slices.Equal(known.rejection.Reasons, rejection.Reasons) {
+			rejection.Since = known.rejection.Since
+		}
+		known.serviceID = ""
+		known.rejection = &rejection
+		return model.GSLBConfig{}, false
+	}
+
+	known.serviceID = gslbConfig.ServiceID
+	known.rejection = nil
+	return gslbConfig, true
+}
+
+// returns the rejections of every invalid resource, sorted by resource.
+// expects the caller to hold the lock
+func (c *Controller) rejections() []rejections.Rejection {
+	rejected := make([]rejections.Rejection, 0)
+	for _, key := range slices.Sorted(maps.Keys(c.resources)) {
+		if rejection := c.resources[key].rejection; rejection != nil {
+			rejected = append(rejected, *rejection)
+		}
+	}
+	return rejected
+}
+
+// writes the health of every service to the status of its resource, unless it is unchanged
+func (c *Controller) WriteStatus(ctx context.Context) {
+	c.mu.Lock()
+	pending := make(map[string]GSLBConfigStatus)
+	targets := make(map[string]resource)
+	for key, known := range c.resources {
+		status := c.statusOf(known)
+		if status != known.status {
+			pending[key] = status
+			targets[key] = *known
+		}
+	}
+	c.mu.Unlock()
+
+	for key, status := range pending {
+		target := targets[key]
+		err := c.client.UpdateStatus(ctx, target.namespace, target.name, status)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				bslog.Warn("could not update status of GSLBConfig resource", slog.String("resource", key), slog.String("reason", err.Error()))
+			}
+			continue
+		}
+
+		c.mu.Lock()
+		if known, ok := c.resources[key]; ok {
+			known.status = status
+		}
+		c.mu.Unlock()
+	}
+}
+
+// expects the caller to hold the lock
+func (c *Controller) statusOf(known *resource) GSLBConfigStatus {
+	if known.rejection != nil {
+		return GSLBConfigStatus{Message: strings.Join(known.rejection.Reasons, "; ")}
+	}
+
+	svc := c.lookup.GetService(known.serviceID)
+	if svc == nil {
+		return GSLBConfigStatus{Message: "service is not registered"}
+	}
+
+	status := GSLBConfigStatus{
+		Healthy:      svc.IsHealthy(),
+		FailureCount: svc.GetFailureCount(),
+		Message:      svc.GetLastError(),
+	}
+
+	lastCheck := svc.GetLastCheck()
+	switch {
+	case lastCheck.IsZero():
+		status.Message = "waiting for first health check"
+		return status
+	case status.Message == "" && status.Healthy:
+		status.Message = "healthy"
+	case status.Message == "":
+		status.Message = "unhealthy"
+	}
+
+	status.LastCheck = lastCheck.UTC().Format(time.RFC3339)
+	return status
+}
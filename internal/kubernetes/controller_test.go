@@ -0,0 +1,188 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/service"
+	"github.com/vitistack/gslb-operator/internal/source"
+	"github.com/vitistack/gslb-operator/internal/utils/timesutil"
+)
+
+// services registered from the updates of the controller, as the dns handler would
+type fakeRegistry struct {
+	services map[string]*service.Service
+}
+
+func (r *fakeRegistry) apply(t *testing.T, update source.Update) {
+	t.Helper()
+	if update.Kind == source.SNAPSHOT {
+		clear(r.services)
+	}
+
+	for _, config := range update.Configs {
+		if update.Kind == source.DELETE {
+			delete(r.services, config.ServiceID)
+			continue
+		}
+
+		svc, err := service.NewServiceFromGSLBConfig(config, service.WithDryRunChecks(true))
+		if err != nil {
+			t.Fatalf("controller emitted invalid config: %v", err)
+		}
+		r.services[svc.GetID()] = svc
+	}
+}
+
+func (r *fakeRegistry) GetService(id string) *service.Service {
+	return r.services[id]
+}
+
+func newTestConfig(name, serviceID, ip string) GSLBConfig {
+	return GSLBConfig{
+		Metadata: ObjectMeta{Name: name, Namespace: "gslb", UID: "uid-" + name},
+		Spec: GSLBConfigSpec{
+			ServiceID:  serviceID,
+			Fqdn:       "app.example.com",
+			MemberOf:   "app.example.com",
+			Ip:         ip,
+			Port:       "443",
+			Datacenter: "dc1",
+			Interval:   timesutil.Duration(5 * time.Second), // default of the crd
+			CheckType:  "tcp-full",
+		},
+	}
+}
+
+// fake api server, listing and watching GSLBConfig resources in the gslb namespace
+type fakeAPIServer struct {
+	list     GSLBConfigList
+	events   []WatchEvent
+	mu       sync.Mutex
+	statuses map[string]GSLBConfigStatus
+}
+
+func (f *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const path = "/apis/gslb.vitistack.io/v1alpha1/namespaces/gslb/gslbconfigs"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == path && r.URL.Query().Get("watch") == "true":
+		encoder := json.NewEncoder(w)
+		for _, event := range f.events {
+			_ = encoder.Encode(event)
+		}
+
+	case r.Method == http.MethodGet && r.URL.Path == path:
+		_ = json.NewEncoder(w).Encode(f.list)
+
+	case r.Method == http.MethodPatch && r.URL.Path == path+"/"+r.PathValue("name")+"/status":
+		patch := map[string]GSLBConfigStatus{}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &patch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.statuses[r.PathValue("name")] = patch["status"]
+		f.mu.Unlock()
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(Status{Status: "Failure", Code: http.StatusNotFound, Message: "not found"})
+	}
+}
+
+func newWatchEvent(t *testing.T, eventType string, object any) WatchEvent {
+	t.Helper()
+	raw, err := json.Marshal(object)
+	if err != nil {
+		t.Fatalf("could not marshal watch event: %v", err)
+	}
+	return WatchEvent{Type: eventType, Object: raw}
+}
+
+func TestController(t *testing.T) {
+	api := &fakeAPIServer{
+		list: GSLBConfigList{
+			Metadata: ListMeta{ResourceVersion: "10"},
+			Items: []GSLBConfig{
+				newTestConfig("dc1", "svc-1", "10.0.0.1"),
+				newTestConfig("dc2", "", "10.0.0.2"),
+				newTestConfig("invalid", "svc-3", "not-an-ip"),
+			},
+		},
+		events: []WatchEvent{
+			newWatchEvent(t, EVENT_MODIFIED, newTestConfig("dc1", "svc-1", "10.0.0.11")),
+			newWatchEvent(t, EVENT_DELETED, newTestConfig("dc2", "", "10.0.0.2")),
+			newWatchEvent(t, EVENT_ERROR, Status{Code: http.StatusGone, Message: "too old resource version"}),
+		},
+		statuses: make(map[string]GSLBConfigStatus),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/apis/gslb.vitistack.io/v1alpha1/namespaces/gslb/gslbconfigs", api)
+	mux.Handle("/apis/gslb.vitistack.io/v1alpha1/namespaces/gslb/gslbconfigs/{name}/status", api)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	registry := &fakeRegistry{services: make(map[string]*service.Service)}
+	controller := NewController(NewClient(server.URL, WithNamespace("gslb")), registry)
+	ctx := context.Background()
+
+	snapshot, resourceVersion, err := controller.Resync(ctx)
+	if err != nil {
+		t.Fatalf("Resync() failed: %v", err)
+	}
+	if resourceVersion != "10" {
+		t.Errorf("expected resource version: 10, but got: %s", resourceVersion)
+	}
+	if snapshot.Kind != source.SNAPSHOT || len(snapshot.Configs) != 2 {
+		t.Fatalf("expected snapshot of the two valid resources, got: %+v", snapshot)
+	}
+	registry.apply(t, snapshot)
+	if registry.GetService("svc-1") == nil || registry.GetService("uid-dc2") == nil {
+		t.Fatalf("expected listed resources to be registered, got: %v", registry.services)
+	}
+
+	resourceVersion, err = controller.watch(ctx, resourceVersion, func(update source.Update) {
+		registry.apply(t, update)
+	})
+	if err != nil {
+		t.Fatalf("watch() failed: %v", err)
+	}
+	if resourceVersion != "" {
+		t.Errorf("expected an expired watch to require listing again, got resource version: %s", resourceVersion)
+	}
+	if svc := registry.GetService("svc-1"); svc == nil || svc.GetIP() != "10.0.0.11" {
+		t.Errorf("expected modified resource to update the service, got: %v", svc)
+	}
+	if registry.GetService("uid-dc2") != nil {
+		t.Error("expected deleted resource to remove the service")
+	}
+
+	bookmark := newTestConfig("", "", "")
+	bookmark.Metadata.ResourceVersion = "15"
+	api.events = []WatchEvent{newWatchEvent(t, EVENT_BOOKMARK, bookmark)}
+	resourceVersion, err = controller.watch(ctx, "10", func(source.Update) {})
+	if err != nil || resourceVersion != "15" {
+		t.Errorf("expected a watch that ends to be resumed from the last seen resource version, got: %s, %v", resourceVersion, err)
+	}
+
+	controller.WriteStatus(ctx)
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if status := api.statuses["dc1"]; status.Healthy || status.Message != "waiting for first health check" {
+		t.Errorf("unexpected status for dc1: %+v", status)
+	}
+	if status := api.statuses["invalid"]; status.Message == "" {
+		t.Error("expected status of invalid resource to explain why it is not valid")
+	}
+	if _, ok := api.statuses["dc2"]; ok {
+		t.Error("did not expect status to be written for deleted resource")
+	}
+}
@@ -0,0 +1,9 @@
+package kubernetes
+
+import "errors"
+
+var (
+	ErrNotInCluster        = errors.New("not running in a kubernetes cluster")
+	ErrUnexpectedStatus    = errors.New("unexpected response from kubernetes api server")
+	ErrResourceVersionGone = errors.New("resource version is too old")
+)
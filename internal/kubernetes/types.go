@@ -0,0 +1,263 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/internal/service"
+	"github.com/vitistack/gslb-operator/internal/utils/timesutil"
+)
+
+const (
+	GROUP    = "gslb.vitistack.io"
+	VERSION  = "v1alpha1"
+	RESOURCE = "gslbconfigs"
+)
+
+// types of events received when watching resources
+const (
+	EVENT_ADDED    = "ADDED"
+	EVENT_MODIFIED = "MODIFIED"
+	EVENT_DELETED  = "DELETED"
+	EVENT_BOOKMARK = "BOOKMARK"
+	EVENT_ERROR    = "ERROR"
+)
+
+type ObjectMeta struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	UID             string `json:"uid,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type ListMeta struct {
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// GSLBConfig custom resource, as defined in crd.yaml
+type GSLBConfig struct {
+	Metadata ObjectMeta       `json:"metadata"`
+	Spec     GSLBConfigSpec   `json:"spec"`
+	Status   GSLBConfigStatus `json:"status,omitzero"`
+}
+
+type GSLBConfigSpec struct {
+	ServiceID        string             `json:"serviceID,omitempty"`
+	Fqdn             string             `json:"fqdn"`
+	MemberOf         string             `json:"memberOf"`
+	Ip               string             `json:"ip"`
+	Ipv6             string             `json:"ipv6,omitempty"`
+	Port             string             `json:"port,omitempty"`
+	Datacenter       string             `json:"datacenter"`
+	Interval         timesutil.Duration `json:"interval,omitzero"`
+	Priority         int                `json:"priority,omitempty"`
+	FailureThreshold int                `json:"failureThreshold,omitempty"`
+	CheckType        string             `json:"checkType"`
+	Script           string             `json:"lua,omitempty"`
+	GroupMode        string             `json:"groupMode,omitempty"`
+	Weight           int                `json:"weight,omitempty"`
+	DNS              *DNSCheckSpec      `json:"dns,omitempty"`
+	GRPC             *GRPCCheckSpec     `json:"grpc,omitempty"`
+	HTTP             *HTTPCheckSpec     `json:"http,omitempty"`
+	Checks           []CheckSpec        `json:"checks,omitempty"`
+	CheckMode        string             `json:"checkMode,omitempty"`
+	CheckQuorum      int                `json:"checkQuorum,omitempty"`
+	DependsOn        []string           `json:"dependsOn,omitempty"`
+	FlapThreshold    int                `json:"flapThreshold,omitempty"`
+	FlapWindow       timesutil.Duration `json:"flapWindow,omitzero"`
+}
+
+// one check of a composite health check
+type CheckSpec struct {
+	Name      string         `json:"name,omitempty"`
+	CheckType string         `json:"checkType"`
+	Port      string         `json:"port,omitempty"`
+	Script    string         `json:"lua,omitempty"`
+	DNS       *DNSCheckSpec  `json:"dns,omitempty"`
+	GRPC      *GRPCCheckSpec `json:"grpc,omitempty"`
+	HTTP      *HTTPCheckSpec `json:"http,omitempty"`
+}
+
+// request of a http or https health check
+type HTTPCheckSpec struct {
+	Host           string            `json:"host,omitempty"`
+	SNI            string            `json:"sni,omitempty"`
+	Method         string            `json:"method,omitempty"`
+	Path           string            `json:"path,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Body           string            `json:"body,omitempty"`
+	ExpectedStatus []int             `json:"expectedStatus,omitempty"`
+	TLS            *TLSCheckSpec     `json:"tls,omitempty"`
+}
+
+// TLS options of a health check, the files are mounted into the operator
+type TLSCheckSpec struct {
+	Verify        bool               `json:"verify,omitempty"`
+	CAFile        string             `json:"caFile,omitempty"`
+	CertFile      string             `json:"certFile,omitempty"`
+	KeyFile       string             `json:"keyFile,omitempty"`
+	ExpiryWarning timesutil.Duration `json:"expiryWarning,omitzero"`
+	ExpiryFailure timesutil.Duration `json:"expiryFailure,omitzero"`
+}
+
+// query of a dns health check
+type DNSCheckSpec struct {
+	Name    string   `json:"name,omitempty"`
+	Type    string   `json:"type,omitempty"`
+	Net     string   `json:"net,omitempty"`
+	Rcode   string   `json:"rcode,omitempty"`
+	Answers []string `json:"answers,omitempty"`
+}
+
+// request of a grpc health check
+type GRPCCheckSpec struct {
+	Service    string        `json:"service,omitempty"`
+	TLS        bool          `json:"tls,omitempty"`
+	TLSOptions *TLSCheckSpec `json:"tlsOptions,omitempty"`
+}
+
+type GSLBConfigStatus struct {
+	Healthy      bool   `json:"healthy"`
+	LastCheck    string `json:"lastCheck,omitempty"` // RFC 3339
+	FailureCount int    `json:"failureCount"`
+	Message      string `json:"message,omitempty"`
+}
+
+type GSLBConfigList struct {
+	Metadata ListMeta     `json:"metadata"`
+	Items    []GSLBConfig `json:"items"`
+}
+
+// event received when watching resources.
+// Object is a GSLBConfig, or a Status when Type is EVENT_ERROR
+type WatchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// error returned by the API server
+type Status struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+	Code    int    `json:"code"`
+}
+
+// returns namespace/name, which is unique for the resource in the cluster
+func (c GSLBConfig) Key() string {
+	return c.Metadata.Namespace + "/" + c.Metadata.Name
+}
+
+// returns the id of the service the resource configures, the uid of the resource if no service id is set
+func (c GSLBConfig) ServiceID() string {
+	if c.Spec.ServiceID != "" {
+		return c.Spec.ServiceID
+	}
+	return c.Metadata.UID
+}
+
+// returns the GSLB - config of the resource, as it would be in the GSLB - config zone
+func (c GSLBConfig) GSLBConfig() model.GSLBConfig {
+	failureThreshold := c.Spec.FailureThreshold
+	if failureThreshold == 0 {
+		failureThreshold = service.DEFAULT_FAILURE_THRESHOLD
+	}
+
+	checks := make([]model.CheckConfig, 0, len(c.Spec.Checks))
+	for _, check := range c.Spec.Checks {
+		checks = append(checks, model.CheckConfig{
+			Name:      check.Name,
+			CheckType: strings.ToUpper(check.CheckType),
+			Port:      check.Port,
+			Script:    check.Script,
+			DNS:       dnsCheckOf(check.DNS),
+			GRPC:      grpcCheckOf(check.GRPC),
+			HTTP:      httpCheckOf(check.HTTP),
+		})
+	}
+
+	return model.GSLBConfig{
+		ServiceID:        c.ServiceID(),
+		Fqdn:             c.Spec.Fqdn,
+		MemberOf:         c.Spec.MemberOf,
+		Ip:               c.Spec.Ip,
+		Ipv6:             c.Spec.Ipv6,
+		Port:             c.Spec.Port,
+		Datacenter:       c.Spec.Datacenter,
+		Interval:         c.Spec.Interval,
+		Priority:         c.Spec.Priority,
+		FailureThreshold: failureThreshold,
+		CheckType:        strings.ToUpper(c.Spec.CheckType), // the crd uses lower case check types
+		Script:           c.Spec.Script,
+		GroupMode:        c.Spec.GroupMode,
+		Weight:           c.Spec.Weight,
+		DNS:              dnsCheckOf(c.Spec.DNS),
+		GRPC:             grpcCheckOf(c.Spec.GRPC),
+		HTTP:             httpCheckOf(c.Spec.HTTP),
+		Checks:           checks,
+		CheckMode:        strings.ToUpper(c.Spec.CheckMode),
+		CheckQuorum:      c.Spec.CheckQuorum,
+		DependsOn:        c.Spec.DependsOn,
+		FlapThreshold:    c.Spec.FlapThreshold,
+		FlapWindow:       c.Spec.FlapWindow,
+	}
+}
+
+func dnsCheckOf(spec *DNSCheckSpec) model.DNSCheckConfig {
+	if spec == nil {
+		return model.DNSCheckConfig{}
+	}
+
+	return model.DNSCheckConfig{
+		Name:    spec.Name,
+		Type:    spec.Type,
+		Net:     spec.Net,
+		Rcode:   spec.Rcode,
+		Answers: strings.Join(spec.Answers, ","),
+	}
+}
+
+func grpcCheckOf(spec *GRPCCheckSpec) model.GRPCCheckConfig {
+	if spec == nil {
+		return model.GRPCCheckConfig{}
+	}
+
+	return model.GRPCCheckConfig{
+		Service:    spec.Service,
+		TLS:        spec.TLS,
+		TLSOptions: tlsCheckOf(spec.TLSOptions),
+	}
+}
+
+func httpCheckOf(spec *HTTPCheckSpec) model.HTTPCheckConfig {
+	if spec == nil {
+		return model.HTTPCheckConfig{}
+	}
+
+	return model.HTTPCheckConfig{
+		Host:           spec.Host,
+		SNI:            spec.SNI,
+		Method:         spec.Method,
+		Path:           spec.Path,
+		Headers:        spec.Headers,
+		Body:           spec.Body,
+		ExpectedStatus: spec.ExpectedStatus,
+		TLS:            tlsCheckOf(spec.TLS),
+	}
+}
+
+func tlsCheckOf(spec *TLSCheckSpec) model.TLSCheckConfig {
+	if spec == nil {
+		return model.TLSCheckConfig{}
+	}
+
+	return model.TLSCheckConfig{
+		Verify:        spec.Verify,
+		CAFile:        spec.CAFile,
+		CertFile:      spec.CertFile,
+		KeyFile:       spec.KeyFile,
+		ExpiryWarning: spec.ExpiryWarning,
+		ExpiryFailure: spec.ExpiryFailure,
+	}
+}
@@ -0,0 +1,113 @@
+package manager
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/internal/service"
+)
+
+// reports whether the group has members in the datacenter, but none of them are healthy.
+// a group without members in the datacenter is not down there, so a dependency on it is not enforced
+func (sg *ServiceGroup) DownIn(datacenter string) bool {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	members := false
+	for _, svc := range sg.Members {
+		if svc.Datacenter != datacenter {
+			continue
+		}
+		if svc.IsHealthy() {
+			return false
+		}
+		members = true
+	}
+	return members
+}
+
+// updates which of the service groups svc depends on are down in its datacenter
+func (sm *ServicesManager) evaluateDependencies(svc *service.Service) {
+	sm.mutex.RLock()
+	unmet := make([]string, 0)
+	for _, memberOf := range svc.GetDependsOn() {
+		if group, ok := sm.serviceGroups[memberOf]; ok && group.DownIn(svc.Datacenter) {
+			unmet = append(unmet, memberOf)
+		}
+	}
+	sm.mutex.RUnlock()
+
+	if len(unmet) == 0 {
+		unmet = nil
+	}
+	svc.SetUnmetDependencies(unmet) // may change the health of svc, which is propagated to its own dependents
+}
+
+// re-evaluates every service in datacenter that depends on the service group memberOf,
+// after the health of one of its members has changed
+func (sm *ServicesManager) propagateHealth(memberOf, datacenter string) {
+	sm.mutex.RLock()
+	dependents := make([]*service.Service, 0)
+	for _, group := range sm.serviceGroups {
+		group.mu.RLock()
+		for _, svc := range group.Members {
+			if svc.Datacenter == datacenter && slices.Contains(svc.GetDependsOn(), memberOf) {
+				dependents = append(dependents, svc)
+			}
+		}
+		group.mu.RUnlock()
+	}
+	sm.mutex.RUnlock()
+
+	for _, svc := range dependents {
+		sm.evaluateDependencies(svc)
+	}
+}
+
+// returns an error if registering config would make a service group depend on itself
+func (sm *ServicesManager) checkDependencyCycle(config model.GSLBConfig) error {
+	sm.mutex.RLock()
+	dependencies := make(map[string][]string)
+	for memberOf, group := range sm.serviceGroups {
+		group.mu.RLock()
+		for _, svc := range group.Members {
+			if svc.GetID() != config.ServiceID { // the config replaces the registered service
+				dependencies[memberOf] = append(dependencies[memberOf], svc.GetDependsOn()...)
+			}
+		}
+		group.mu.RUnlock()
+	}
+	sm.mutex.RUnlock()
+	dependencies[config.MemberOf] = append(dependencies[config.MemberOf], config.DependsOn...)
+
+	// only the dependencies of config are new, so any cycle must go through its group
+	visited := make(map[string]bool)
+	var path []string
+	var reaches func(memberOf string) bool
+	reaches = func(memberOf string) bool {
+		path = append(path, memberOf)
+		if memberOf == config.MemberOf && len(path) > 1 {
+			return true
+		}
+		if visited[memberOf] {
+			path = path[:len(path)-1]
+			return false
+		}
+		visited[memberOf] = true
+
+		for _, dependency := range dependencies[memberOf] {
+			if reaches(dependency) {
+				return true
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+
+	if reaches(config.MemberOf) {
+		return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(path, " -> "))
+	}
+	return nil
+}
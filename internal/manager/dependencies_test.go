@@ -0,0 +1,72 @@
+package manager
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/internal/service"
+	"github.com/vitistack/gslb-operator/internal/utils/timesutil"
+	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
+)
+
+func dependencyConfig(id, memberOf, datacenter string, dependsOn ...string) model.GSLBConfig {
+	return model.GSLBConfig{
+		ServiceID:        id,
+		MemberOf:         memberOf,
+		Fqdn:             id + ".example.com",
+		Ip:               "192.168.1.1",
+		Port:             "80",
+		Datacenter:       datacenter,
+		Interval:         timesutil.Duration(30 * time.Second),
+		Priority:         1,
+		FailureThreshold: 1,
+		CheckType:        "TCP-FULL",
+		DependsOn:        dependsOn,
+	}
+}
+
+func TestServicesManager_Dependencies(t *testing.T) {
+	sm := NewManager(WithDryRun(true))
+	sm.DNSUpdate = func(*service.Service, bool) (spoofs.FleetUpdate, error) { return spoofs.FleetUpdate{}, nil }
+
+	register := func(config model.GSLBConfig) *service.Service {
+		t.Helper()
+		svc, err := sm.RegisterService(config)
+		if err != nil {
+			t.Fatalf("could not register service: %v", err)
+		}
+		svc.OnSuccess()
+		return svc
+	}
+
+	db := register(dependencyConfig("db-dc1", "db.example.com", "dc1"))
+	app := register(dependencyConfig("app-dc1", "app.example.com", "dc1", "db.example.com"))
+	otherDC := register(dependencyConfig("app-dc2", "app.example.com", "dc2", "db.example.com"))
+	if !app.IsHealthy() || !otherDC.IsHealthy() {
+		t.Fatal("expected the services to be healthy while their dependency is up")
+	}
+
+	db.OnFailure(errors.New("connection refused"))
+	if app.IsHealthy() || !slices.Equal(app.GetUnmetDependencies(), []string{"db.example.com"}) {
+		t.Errorf("expected the service to be down while its dependency is down, got unmet: %v", app.GetUnmetDependencies())
+	}
+	if !otherDC.IsHealthy() {
+		t.Error("did not expect a dependency without members in the datacenter to be enforced")
+	}
+	if active := sm.GetActiveForMemberOf("app.example.com"); active != otherDC {
+		t.Errorf("expected the member in the other datacenter to take over, got: %v", active)
+	}
+
+	db.OnSuccess()
+	if !app.IsHealthy() {
+		t.Error("expected the service to be up again once its dependency is up")
+	}
+
+	_, err := sm.RegisterService(dependencyConfig("db-dc2", "db.example.com", "dc2", "app.example.com"))
+	if !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("expected a dependency cycle to be rejected, got: %v", err)
+	}
+}
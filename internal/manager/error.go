@@ -6,4 +6,10 @@ var (
 	ErrCannotPromoteUnHealthyService = errors.New("cannot promote UnHealthy service")
 	ErrServiceNotFound               = errors.New("service not found")
 	ErrServiceNotFoundInGroup        = errors.New("service not found in service group")
+	ErrServiceGroupNotFound          = errors.New("service group not found")
+	ErrInvalidFailover               = errors.New("failover requires either a datacenter or next healthy")
+	ErrNoActiveFailover              = errors.New("no active failover for service group")
+	ErrDependencyCycle               = errors.New("service groups depend on each other")
+	ErrInvalidMaintenance            = errors.New("invalid maintenance window")
+	ErrMaintenanceWindowNotFound     = errors.New("maintenance window not found")
 )
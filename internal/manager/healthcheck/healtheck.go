@@ -31,6 +31,31 @@ func (hj *HealthCheckJob) Execute() error {
 		hj.Service.Fqdn,
 		hj.Service.Datacenter).
 		Observe(checkTimeMs)
+
+	for _, result := range hj.Service.GetCheckResults() {
+		status := "success"
+		if result.Err != nil {
+			status = "failure"
+			bslog.Debug("check of composite check failed", slog.Any("service", hj.Service), slog.String("check", result.Name), slog.String("error", result.Err.Error()))
+		} else {
+			bslog.Debug("check of composite check succeeded", slog.Any("service", hj.Service), slog.String("check", result.Name))
+		}
+		healthChecksTotal.WithLabelValues(
+			hj.Service.MemberOf,
+			hj.Service.Fqdn,
+			hj.Service.Datacenter,
+			result.Name,
+			status).
+			Inc()
+	}
+
+	if expiry := hj.Service.GetCertificateExpiry(); !expiry.IsZero() {
+		certificateExpiry.WithLabelValues(
+			hj.Service.MemberOf,
+			hj.Service.Fqdn,
+			hj.Service.Datacenter).
+			Set(float64(expiry.Unix()))
+	}
 	return err
 }
 
@@ -38,16 +63,32 @@ func (hj *HealthCheckJob) OnSuccess() {
 	healthChecksTotal.WithLabelValues(hj.Service.MemberOf,
 		hj.Service.Fqdn,
 		hj.Service.Datacenter,
+		hj.Service.GetCheckType(),
 		"success").
 		Inc()
 	hj.Service.OnSuccess()
+	hj.setDampened()
 }
 
 func (hj *HealthCheckJob) OnFailure(err error) {
 	healthChecksTotal.WithLabelValues(hj.Service.MemberOf,
 		hj.Service.Fqdn,
 		hj.Service.Datacenter,
+		hj.Service.GetCheckType(),
 		"failure").
 		Inc()
 	hj.Service.OnFailure(err)
+	hj.setDampened()
+}
+
+func (hj *HealthCheckJob) setDampened() {
+	value := 0.0
+	if hj.Service.IsDampened() {
+		value = 1
+	}
+	dampened.WithLabelValues(
+		hj.Service.MemberOf,
+		hj.Service.Fqdn,
+		hj.Service.Datacenter).
+		Set(value)
 }
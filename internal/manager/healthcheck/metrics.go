@@ -9,9 +9,9 @@ var (
 	healthChecksTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "healthcheck_total",
-			Help: "Total health checks performed",
+			Help: "Total health checks performed, and every check of a composite check",
 		},
-		[]string{"memberOf",  "endpoint", "datacenter", "status"},
+		[]string{"memberOf",  "endpoint", "datacenter", "check", "status"},
 	)
 
 	healthCheckDuration = promauto.NewHistogramVec(
@@ -22,5 +22,20 @@ var (
 		},
 		[]string{"memberOf", "endpoint", "datacenter"},
 	)
-)
 
+	certificateExpiry = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "healthcheck_certificate_expiry_timestamp_seconds",
+			Help: "Unix time the certificate of the member expires, as seen by its last health check",
+		},
+		[]string{"memberOf", "endpoint", "datacenter"},
+	)
+
+	dampened = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "healthcheck_dampened",
+			Help: "1 while the member is held down because its health flaps",
+		},
+		[]string{"memberOf", "endpoint", "datacenter"},
+	)
+)
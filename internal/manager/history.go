@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/vitistack/gslb-operator/internal/service"
+
+	"github.com/vitistack/gslb-operator/pkg/models/history"
+)
+
+// the last checks and the flap state of every member of the service group memberOf
+func (sm *ServicesManager) History(memberOf string) ([]history.MemberHistory, error) {
+	sm.mutex.RLock()
+	group, ok := sm.serviceGroups[memberOf]
+	sm.mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrServiceGroupNotFound, memberOf)
+	}
+
+	group.mu.RLock()
+	defer group.mu.RUnlock()
+
+	members := make([]history.MemberHistory, 0, len(group.Members))
+	for _, svc := range group.Members {
+		dampenedSince, transitions := svc.GetFlapState()
+		member := history.MemberHistory{
+			ServiceID:   svc.GetID(),
+			MemberOf:    svc.MemberOf,
+			Fqdn:        svc.Fqdn,
+			Datacenter:  svc.Datacenter,
+			Healthy:     svc.IsHealthy(),
+			Dampened:    !dampenedSince.IsZero(),
+			Transitions: transitions,
+			Checks:      make([]history.CheckResult, 0, service.HISTORY_SIZE),
+		}
+		if member.Dampened {
+			member.DampenedSince = &dampenedSince
+		}
+
+		for _, result := range svc.GetHistory() {
+			member.Checks = append(member.Checks, history.CheckResult{
+				Time:      result.Time,
+				LatencyMs: float64(result.Latency.Microseconds()) / 1000,
+				Error:     result.Err,
+			})
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
@@ -0,0 +1,198 @@
+package manager
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vitistack/gslb-operator/internal/service"
+	"github.com/vitistack/gslb-operator/pkg/bslog"
+	"github.com/vitistack/gslb-operator/pkg/cron"
+	"github.com/vitistack/gslb-operator/pkg/models/maintenance"
+)
+
+// how often maintenance windows are opened and closed
+const DEFAULT_MAINTENANCE_EVALUATION_INTERVAL = time.Second * 10
+
+// the maintenance windows, the first to start first
+func (sm *ServicesManager) MaintenanceWindows() ([]maintenance.Window, error) {
+	windows, err := sm.maintenance.LoadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not load maintenance windows: %w", err)
+	}
+	slices.SortFunc(windows, func(a, b maintenance.Window) int {
+		return cmp.Or(a.Start.Compare(b.Start), cmp.Compare(a.ID, b.ID))
+	})
+	return windows, nil
+}
+
+// stores a new maintenance window, and takes the members it covers out of rotation if it is open
+func (sm *ServicesManager) CreateMaintenance(window maintenance.Window) (maintenance.Window, error) {
+	if err := validateMaintenance(window); err != nil {
+		return window, err
+	}
+
+	window.ID = uuid.NewString()
+	if err := sm.maintenance.Save(window.ID, window); err != nil {
+		return window, fmt.Errorf("could not store maintenance window: %w", err)
+	}
+	bslog.Info("created maintenance window", slog.Any("window", window))
+
+	sm.applyMaintenance()
+	return window, nil
+}
+
+// removes a maintenance window, the members it covers rejoin right away
+func (sm *ServicesManager) DeleteMaintenance(id string) error {
+	window, err := sm.maintenance.Load(id)
+	if err != nil {
+		return fmt.Errorf("could not load maintenance window: %w", err)
+	}
+	if window.ID == "" {
+		return fmt.Errorf("%w: %s", ErrMaintenanceWindowNotFound, id)
+	}
+
+	if err := sm.maintenance.Delete(id); err != nil {
+		return fmt.Errorf("could not delete maintenance window: %w", err)
+	}
+	bslog.Info("deleted maintenance window", slog.Any("window", window))
+
+	sm.applyMaintenance()
+	return nil
+}
+
+func validateMaintenance(window maintenance.Window) error {
+	errs := make([]error, 0)
+	if window.ServiceID == "" && window.MemberOf == "" && window.Datacenter == "" {
+		errs = append(errs, errors.New("a serviceId, memberOf or datacenter is required"))
+	}
+	if window.ServiceID != "" && (window.MemberOf != "" || window.Datacenter != "") {
+		errs = append(errs, errors.New("a serviceId can not be combined with memberOf or datacenter"))
+	}
+	if window.Start.IsZero() || !window.End.After(window.Start) {
+		errs = append(errs, errors.New("the end must be after the start"))
+	}
+	if window.Recurrence != "" {
+		if _, err := cron.Parse(window.Recurrence); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidMaintenance, errors.Join(errs...))
+	}
+	return nil
+}
+
+// periodically opens and closes maintenance windows
+func (sm *ServicesManager) evaluateMaintenance() {
+	ticker := time.NewTicker(DEFAULT_MAINTENANCE_EVALUATION_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sm.quit:
+			return
+		case <-ticker.C:
+			sm.applyMaintenance()
+		}
+	}
+}
+
+// puts every service in the open maintenance window that covers it, or out of maintenance,
+// and removes the windows that have ended for good
+func (sm *ServicesManager) applyMaintenance() {
+	windows, err := sm.openMaintenance(time.Now())
+	if err != nil {
+		bslog.Error("could not evaluate maintenance windows", slog.String("reason", err.Error()))
+		return
+	}
+
+	sm.mutex.RLock()
+	services := make([]*service.Service, 0)
+	for _, group := range sm.serviceGroups {
+		group.mu.RLock()
+		services = append(services, group.Members...)
+		group.mu.RUnlock()
+	}
+	sm.mutex.RUnlock()
+
+	for _, svc := range services { // may change the health of the services, which is handled without holding any lock
+		svc.SetMaintenance(maintenanceOf(windows, svc))
+	}
+}
+
+// puts svc in the open maintenance window that covers it, or out of maintenance
+func (sm *ServicesManager) applyMaintenanceTo(svc *service.Service) {
+	windows, err := sm.openMaintenance(time.Now())
+	if err != nil {
+		bslog.Error("could not evaluate maintenance windows", slog.String("reason", err.Error()), slog.Any("service", svc))
+		return
+	}
+	svc.SetMaintenance(maintenanceOf(windows, svc))
+}
+
+// the windows that are open at now, windows that have ended for good are removed
+func (sm *ServicesManager) openMaintenance(now time.Time) ([]maintenance.Window, error) {
+	windows, err := sm.MaintenanceWindows()
+	if err != nil {
+		return nil, err
+	}
+
+	open := make([]maintenance.Window, 0)
+	for _, window := range windows {
+		isOpen, ended := maintenanceState(window, now)
+		if ended {
+			if err := sm.maintenance.Delete(window.ID); err != nil {
+				return nil, err
+			}
+			bslog.Info("maintenance window ended", slog.Any("window", window))
+			continue
+		}
+		if isOpen {
+			open = append(open, window)
+		}
+	}
+	return open, nil
+}
+
+// reports whether window is open at now, and whether it has ended and will not open again
+func maintenanceState(window maintenance.Window, now time.Time) (open, ended bool) {
+	if now.Before(window.Start) {
+		return false, false
+	}
+	if now.Before(window.End) {
+		return true, false
+	}
+	if window.Recurrence == "" {
+		return false, true
+	}
+
+	schedule, err := cron.Parse(window.Recurrence)
+	if err != nil { // validated when the window is created
+		return false, false
+	}
+	// the first opening after now - duration is open at now, if it is not after now
+	opens := schedule.Next(now.Add(-window.End.Sub(window.Start)).UTC())
+	return !opens.IsZero() && !opens.After(now), false
+}
+
+// the id of the first window that covers svc, empty if none
+func maintenanceOf(windows []maintenance.Window, svc *service.Service) string {
+	for _, window := range windows {
+		switch {
+		case window.ServiceID != "":
+			if window.ServiceID == svc.GetID() {
+				return window.ID
+			}
+		case (window.MemberOf == "" || window.MemberOf == svc.MemberOf) &&
+			(window.Datacenter == "" || window.Datacenter == svc.Datacenter):
+			return window.ID
+		}
+	}
+	return ""
+}
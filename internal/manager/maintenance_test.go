@@ -0,0 +1,94 @@
+package manager
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/service"
+	"github.com/vitistack/gslb-operator/pkg/models/maintenance"
+	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
+)
+
+func TestServicesManager_Maintenance(t *testing.T) {
+	sm := NewManager(WithDryRun(true))
+	sm.DNSUpdate = func(*service.Service, bool) (spoofs.FleetUpdate, error) { return spoofs.FleetUpdate{}, nil }
+
+	register := func(id, datacenter string) *service.Service {
+		t.Helper()
+		svc, err := sm.RegisterService(dependencyConfig(id, "app.example.com", datacenter))
+		if err != nil {
+			t.Fatalf("could not register service: %v", err)
+		}
+		svc.OnSuccess()
+		return svc
+	}
+
+	dc1 := register("app-dc1", "dc1")
+	dc2 := register("app-dc2", "dc2")
+
+	window, err := sm.CreateMaintenance(maintenance.Window{
+		Datacenter: "dc1",
+		Start:      time.Now().Add(-time.Minute),
+		End:        time.Now().Add(time.Hour),
+		Reason:     "patching",
+	})
+	if err != nil {
+		t.Fatalf("could not create maintenance window: %v", err)
+	}
+
+	dc1.OnSuccess() // health checks keep running during maintenance
+	if dc1.IsHealthy() || dc1.GetMaintenance() != window.ID {
+		t.Error("expected the member to be out of rotation while the window is open")
+	}
+	if active := sm.GetActiveForMemberOf("app.example.com"); active != dc2 {
+		t.Errorf("expected the member in the other datacenter to take over, got: %v", active)
+	}
+
+	if err := sm.DeleteMaintenance(window.ID); err != nil {
+		t.Fatalf("could not delete maintenance window: %v", err)
+	}
+	if !dc1.IsHealthy() || dc1.GetMaintenance() != "" {
+		t.Error("expected the member to rejoin once the window is removed")
+	}
+
+	if err := sm.DeleteMaintenance(window.ID); !errors.Is(err, ErrMaintenanceWindowNotFound) {
+		t.Errorf("expected a removed window to not be found, got: %v", err)
+	}
+	if _, err := sm.CreateMaintenance(maintenance.Window{ServiceID: "app-dc1"}); !errors.Is(err, ErrInvalidMaintenance) {
+		t.Errorf("expected a window without an end to be rejected, got: %v", err)
+	}
+}
+
+func TestMaintenanceState(t *testing.T) {
+	start := time.Date(2026, time.March, 7, 2, 0, 0, 0, time.UTC) // a saturday
+	window := maintenance.Window{
+		Start:      start,
+		End:        start.Add(2 * time.Hour),
+		Recurrence: "0 2 * * 6",
+	}
+	oneOff := window
+	oneOff.Recurrence = ""
+
+	tests := []struct {
+		name       string
+		window     maintenance.Window
+		now        time.Time
+		open, ends bool
+	}{
+		{"before the start", window, start.Add(-time.Minute), false, false},
+		{"first opening", window, start.Add(time.Hour), true, false},
+		{"between openings", window, start.Add(3 * time.Hour), false, false},
+		{"next opening", window, start.AddDate(0, 0, 7).Add(time.Hour), true, false},
+		{"one-off ended", oneOff, start.Add(3 * time.Hour), false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			open, ended := maintenanceState(tt.window, tt.now)
+			if open != tt.open || ended != tt.ends {
+				t.Errorf("expected open: %v and ended: %v, got open: %v and ended: %v", tt.open, tt.ends, open, ended)
+			}
+		})
+	}
+}
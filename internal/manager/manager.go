@@ -15,6 +15,9 @@ import (
 	"github.com/vitistack/gslb-operator/internal/utils/timesutil"
 	"github.com/vitistack/gslb-operator/pkg/bslog"
 	"github.com/vitistack/gslb-operator/pkg/models/failover"
+	"github.com/vitistack/gslb-operator/pkg/models/maintenance"
+	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
+	"github.com/vitistack/gslb-operator/pkg/persistence"
 	"github.com/vitistack/gslb-operator/pkg/persistence/store/memory"
 	"github.com/vitistack/gslb-operator/pkg/pool"
 )
@@ -22,24 +25,31 @@ import (
 // Responsible for managing services, on scheduling services for health checks
 type ServicesManager struct {
 	// servicesHealthCheck maps check intervals to services that should be checked at that interval.
-	scheduledServices ScheduledServices                           // services that are scheduled on an interval
-	schedulers        map[timesutil.Duration]*scheduler.Scheduler // schedulers for health-checks
-	serviceGroups     map[string]*ServiceGroup
-	svcRepo           *svcRepo.ServiceRepo
-	mutex             sync.RWMutex
-	stop              sync.Once
-	pool              *pool.WorkerPool
-	wg                *sync.WaitGroup // schedulers use this when scheduling services asynchronously
-	DNSUpdate         func(*service.Service, bool)
-	dryrun            bool
+	scheduledServices       ScheduledServices                           // services that are scheduled on an interval
+	schedulers              map[timesutil.Duration]*scheduler.Scheduler // schedulers for health-checks
+	serviceGroups           map[string]*ServiceGroup
+	svcRepo                 *svcRepo.ServiceRepo
+	mutex                   sync.RWMutex
+	stop                    sync.Once
+	pool                    *pool.WorkerPool
+	wg                      *sync.WaitGroup // schedulers use this when scheduling services asynchronously
+	DNSUpdate               func(*service.Service, bool) (spoofs.FleetUpdate, error)
+	dryrun                  bool
+	roundtripMinImprovement float64
+	roundtripDwellTime      time.Duration
+	quit                    chan struct{} // stops background evaluation of service groups
+	maintenance             persistence.Store[maintenance.Window]
 }
 
 func NewManager(opts ...serviceManagerOption) *ServicesManager {
 	cfg := managerConfig{
-		MinRunningWorkers:     100,
-		NonBlockingBufferSize: 100,
-		DryRun:                false,
-		repo:                  svcRepo.NewServiceRepo(memory.NewStore[model.GSLBServiceGroup]()),
+		MinRunningWorkers:       100,
+		NonBlockingBufferSize:   100,
+		DryRun:                  false,
+		RoundtripMinImprovement: DEFAULT_ROUNDTRIP_MIN_IMPROVEMENT,
+		RoundtripDwellTime:      DEFAULT_ROUNDTRIP_DWELL_TIME,
+		repo:                    svcRepo.NewServiceRepo(memory.NewStore[model.GSLBServiceGroup]()),
+		maintenance:             memory.NewStore[maintenance.Window](),
 	}
 
 	for _, opt := range opts {
@@ -61,15 +71,19 @@ func NewManager(opts ...serviceManagerOption) *ServicesManager {
 	}
 
 	return &ServicesManager{
-		scheduledServices: make(ScheduledServices),
-		schedulers:        make(map[timesutil.Duration]*scheduler.Scheduler),
-		serviceGroups:     make(map[string]*ServiceGroup),
-		svcRepo:           cfg.repo,
-		mutex:             sync.RWMutex{},
-		pool:              pool,
-		stop:              sync.Once{},
-		wg:                &sync.WaitGroup{},
-		dryrun:            cfg.DryRun,
+		scheduledServices:       make(ScheduledServices),
+		schedulers:              make(map[timesutil.Duration]*scheduler.Scheduler),
+		serviceGroups:           make(map[string]*ServiceGroup),
+		svcRepo:                 cfg.repo,
+		mutex:                   sync.RWMutex{},
+		pool:                    pool,
+		stop:                    sync.Once{},
+		wg:                      &sync.WaitGroup{},
+		dryrun:                  cfg.DryRun,
+		roundtripMinImprovement: cfg.RoundtripMinImprovement,
+		roundtripDwellTime:      cfg.RoundtripDwellTime,
+		quit:                    make(chan struct{}),
+		maintenance:             cfg.maintenance,
 	}
 }
 
@@ -77,10 +91,13 @@ func NewManager(opts ...serviceManagerOption) *ServicesManager {
 // It ensures that the scheduling logic is only executed once, even if called multiple times.
 func (sm *ServicesManager) Start() {
 	sm.pool.Start()
+	go sm.evaluateRoundtrips()
+	go sm.evaluateMaintenance()
 }
 
 func (sm *ServicesManager) Stop() {
 	sm.stop.Do(func() {
+		close(sm.quit)
 		for _, scheduler := range sm.schedulers {
 			scheduler.Stop()
 		}
@@ -103,12 +120,10 @@ func (sm *ServicesManager) OnShutdown() error {
 	bslog.Debug("executing manager.OnShutdown()")
 
 	for memberOf, group := range sm.serviceGroups {
-		active := group.GetActive()
-
 		for _, svc := range group.Members {
 			gslbService := svc.GSLBService()
 
-			gslbService.IsActive = (active != nil && active.GetID() == svc.GetID())
+			gslbService.IsActive = group.IsActive(svc)
 			override, err := sm.svcRepo.HasOverride(memberOf)
 			if err != nil {
 				return fmt.Errorf("unable to check whether service group has active override: member-of: %s: %w", memberOf, err)
@@ -135,6 +150,11 @@ func (sm *ServicesManager) RegisterService(serviceCfg model.GSLBConfig) (*servic
 		return nil, fmt.Errorf("unable to register service: %s", err.Error())
 	}
 
+	if err := sm.checkDependencyCycle(serviceCfg); err != nil {
+		return nil, fmt.Errorf("unable to register service: %w", err)
+	}
+	sm.applyMaintenanceTo(newService) // before the service can be promoted
+
 	sm.mutex.RLock()
 	_, _, oldSvc := sm.scheduledServices.Search(newService.GetID())
 	if oldSvc != nil { // update service if already exists
@@ -145,10 +165,9 @@ func (sm *ServicesManager) RegisterService(serviceCfg model.GSLBConfig) (*servic
 	sm.mutex.RUnlock()
 
 	sm.mutex.Lock()
-	defer sm.mutex.Unlock()
-
 	err = sm.svcRepo.Create(newService.GSLBService())
 	if err != nil {
+		sm.mutex.Unlock()
 		return nil, fmt.Errorf("failed to create new service: %w", err)
 	}
 
@@ -163,7 +182,11 @@ func (sm *ServicesManager) RegisterService(serviceCfg model.GSLBConfig) (*servic
 				slog.Any("service", newService),
 			)
 		}
-		sm.serviceGroups[newService.MemberOf].OnServiceHealthChange(newService, healthy)
+		sm.mutex.RLock()
+		group := sm.serviceGroups[newService.MemberOf]
+		sm.mutex.RUnlock()
+		group.OnServiceHealthChange(newService, healthy)
+		sm.propagateHealth(newService.MemberOf, newService.Datacenter)
 	})
 
 	// create new scheduler if needed, and schedule service for health-checks
@@ -180,7 +203,13 @@ func (sm *ServicesManager) RegisterService(serviceCfg model.GSLBConfig) (*servic
 		serviceGroup = sm.newServiceGroup(memberOf)
 		bslog.Debug("new service group", slog.String("group", newService.MemberOf))
 	}
+	sm.mutex.Unlock()
+
+	// registering may promote the new service, and handlePromotion takes the manager lock itself,
+	// so the group is updated after unlocking. the group guards its members with its own lock
 	serviceGroup.RegisterService(newService)
+	sm.evaluateDependencies(newService)
+	sm.propagateHealth(memberOf, newService.Datacenter)
 
 	bslog.Debug("registered service", slog.Any("service", newService))
 	return newService, nil
@@ -205,6 +234,7 @@ func (sm *ServicesManager) RemoveService(id string) error {
 	if empty {
 		sm.deleteGroup(svc.MemberOf)
 	}
+	defer sm.propagateHealth(svc.MemberOf, svc.Datacenter) // the group may no longer be down in the datacenter
 
 	sm.mutex.Lock()
 	defer sm.mutex.Unlock()
@@ -239,10 +269,21 @@ func (sm *ServicesManager) updateService(old, new *service.Service) {
 
 	oldDefaultInterval, newDefaultInterval := old.GetDefaultInterval(), new.GetDefaultInterval()
 	oldMemberOf, newMemberOf := old.MemberOf, new.MemberOf
+	oldDatacenter := old.Datacenter
+	weightChanged := old.GetWeight() != new.GetWeight()
 
 	old.Assign(new) // assigning changed config variables to the registered service
 	sm.mutex.Unlock()
 
+	// the service may depend on other groups, or be in another group or datacenter, than before.
+	// evaluated when the update is done, and the manager lock is released
+	defer func() {
+		sm.applyMaintenanceTo(old)
+		sm.evaluateDependencies(old)
+		sm.propagateHealth(oldMemberOf, oldDatacenter)
+		sm.propagateHealth(newMemberOf, old.Datacenter)
+	}()
+
 	if oldMemberOf != newMemberOf {
 		sm.memberOfChanged(oldMemberOf, newMemberOf, old)
 	} else {
@@ -257,9 +298,14 @@ func (sm *ServicesManager) updateService(old, new *service.Service) {
 	}
 
 	sm.mutex.Lock()
-	defer sm.mutex.Unlock()
 
-	err := sm.svcRepo.Update(old.GSLBService())
+	gslbService := old.GSLBService()
+	group, ok := sm.serviceGroups[newMemberOf]
+	if ok {
+		gslbService.IsActive = group.IsActive(old)
+	}
+
+	err := sm.svcRepo.Update(gslbService)
 	if err != nil {
 		bslog.Error(
 			"failed to update service config persistently",
@@ -276,6 +322,19 @@ func (sm *ServicesManager) updateService(old, new *service.Service) {
 		// otherwise the service will get rescheduled back to its default interval on its own, when it is needed
 		sm.moveServiceToInterval(old, newDefaultInterval)
 	}
+	sm.mutex.Unlock()
+
+	// a new weight of a served member does not change who is active, so DNS must be told directly.
+	// the weight comes from the config and stays in the store, so DNS is told again later if the servers rolled it back
+	if err == nil && weightChanged && gslbService.IsActive && sm.updateDNS(old, true) {
+		time.AfterFunc(DEFAULT_PROMOTION_RETRY, func() {
+			select {
+			case <-sm.quit:
+			default:
+				sm.updateDNS(old, true)
+			}
+		})
+	}
 
 	bslog.Debug("updated service", slog.Any("service", old))
 }
@@ -330,11 +389,10 @@ func (sm *ServicesManager) memberOfChanged(oldMemberOf, newMemberOf string, svc
 	)
 }
 
-// re-schedules the relevant services in the PromotionEvent
+// re-schedules the relevant services in the PromotionEvent.
+// the manager is only locked while the store and the schedulers change, not while the dnsdist servers are updated,
+// so a slow dnsdist server does not hold up health checks and registrations
 func (sm *ServicesManager) handlePromotion(event *PromotionEvent) {
-	sm.mutex.Lock()
-	defer sm.mutex.Unlock()
-
 	var newID, oldID string
 	if event.NewActive != nil {
 		newID = event.NewActive.GetID()
@@ -370,22 +428,19 @@ func (sm *ServicesManager) handlePromotion(event *PromotionEvent) {
 	if event.OldActive != nil && event.NewActive != nil { // just swap, and do dns updates
 		demotedInterval = event.NewActive.ScheduledInterval
 
-		oldActiveGSLBService := event.OldActive.GSLBService()
-		oldActiveGSLBService.IsActive = false
-		err := sm.svcRepo.Update(oldActiveGSLBService)
-		if err != nil {
-			bslog.Error("failed to remove active flag from service", slog.Any("oldActive", event.OldActive))
+		if !sm.setActiveFlags(event, false) {
 			return
 		}
 
-		newActiveGSLBService := event.NewActive.GSLBService()
-		newActiveGSLBService.IsActive = true
-		err = sm.svcRepo.Update(newActiveGSLBService)
-		if err != nil {
-			bslog.Error("failed to update active flag on service", slog.Any("newActive", event.NewActive))
+		// the whole group is configured as it is in the store, so this takes DNS from the old to the new active.
+		// it is the withdrawal of the old active, which is never rolled back while the old active is unhealthy
+		if sm.updateDNS(event.OldActive, false) {
+			sm.revertPromotion(event)
 			return
 		}
 
+		sm.mutex.Lock()
+		defer sm.mutex.Unlock()
 		bslog.Warn("demoting service",
 			slog.Any("oldActive", event.OldActive),
 			slog.Group("intervalChange",
@@ -394,8 +449,6 @@ func (sm *ServicesManager) handlePromotion(event *PromotionEvent) {
 			))
 		sm.moveServiceToInterval(event.OldActive, demotedInterval)
 
-		sm.DNSUpdate(event.OldActive, false)
-
 		bslog.Warn("promoting service",
 			slog.Any("newActive", event.NewActive),
 			slog.Group("intervalChange",
@@ -403,43 +456,146 @@ func (sm *ServicesManager) handlePromotion(event *PromotionEvent) {
 				slog.String("to", baseInterval.String()),
 			))
 		sm.moveServiceToInterval(event.NewActive, baseInterval)
-		sm.DNSUpdate(event.NewActive, true)
 		return
 	}
 
 	if event.NewActive != nil { // first service to come up when all services are down
-		newActiveGSLBService := event.NewActive.GSLBService()
-		newActiveGSLBService.IsActive = true
-		err := sm.svcRepo.Update(newActiveGSLBService)
-		if err != nil {
-			bslog.Error("failed to update active flag on service", slog.Any("newActive", event.NewActive))
+		if !sm.setActiveFlags(event, false) {
 			return
 		}
-		bslog.Info("new active service", slog.Any("service", event.NewActive))
-		sm.moveServiceToInterval(event.NewActive, baseInterval)
 		if sm.DNSUpdate == nil {
 			bslog.Fatal("DNSUpdate is nil!!!!")
 		}
-		sm.DNSUpdate(event.NewActive, true)
+		if sm.updateDNS(event.NewActive, true) {
+			sm.revertPromotion(event)
+			return
+		}
+
+		sm.mutex.Lock()
+		defer sm.mutex.Unlock()
+		bslog.Info("new active service", slog.Any("service", event.NewActive))
+		sm.moveServiceToInterval(event.NewActive, baseInterval)
 		return
 	}
 
 	if event.OldActive != nil { // no service to take over
-		oldActiveGSLBService := event.OldActive.GSLBService()
-		oldActiveGSLBService.IsActive = false
-		err := sm.svcRepo.Update(oldActiveGSLBService)
-		if err != nil {
-			bslog.Error("failed to remove active flag from service", slog.Any("oldActive", event.OldActive))
+		if !sm.setActiveFlags(event, false) {
 			return
 		}
-		bslog.Warn("no available sites", slog.String("serviceGroup", event.Service))
-		sm.DNSUpdate(event.OldActive, false)
+		bslog.Warn("service demoted without replacement", slog.String("serviceGroup", event.Service), slog.Any("oldActive", event.OldActive))
+		if sm.updateDNS(event.OldActive, false) {
+			sm.revertPromotion(event)
+		}
 		return
 	}
 }
 
+// stores the active flags of the services in the event, as they are after the promotion,
+// or as they were before it when reverting. returns false if the store could not be updated
+func (sm *ServicesManager) setActiveFlags(event *PromotionEvent, revert bool) bool {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	if event.OldActive != nil {
+		oldActiveGSLBService := event.OldActive.GSLBService()
+		oldActiveGSLBService.IsActive = revert
+		if err := sm.svcRepo.Update(oldActiveGSLBService); err != nil {
+			bslog.Error("failed to update active flag on service", slog.Any("oldActive", event.OldActive), slog.String("reason", err.Error()))
+			return false
+		}
+	}
+	if event.NewActive != nil {
+		newActiveGSLBService := event.NewActive.GSLBService()
+		newActiveGSLBService.IsActive = !revert
+		if err := sm.svcRepo.Update(newActiveGSLBService); err != nil {
+			bslog.Error("failed to update active flag on service", slog.Any("newActive", event.NewActive), slog.String("reason", err.Error()))
+			return false
+		}
+	}
+	return true
+}
+
+// tells DNS about the new state of svc, and reports how the dnsdist servers took it.
+// returns true when the servers rolled the update back, the caller must then undo it in the store
+func (sm *ServicesManager) updateDNS(svc *service.Service, healthy bool) bool {
+	update, err := sm.DNSUpdate(svc, healthy)
+	switch {
+	case err == nil:
+		dnsUpdates.WithLabelValues("applied").Inc()
+		return false
+	case update.Reverted:
+		dnsUpdates.WithLabelValues("rolled_back").Inc()
+	case update.Applied:
+		dnsUpdates.WithLabelValues("partial").Inc()
+	default:
+		dnsUpdates.WithLabelValues("failed").Inc()
+	}
+
+	bslog.Error("dns update did not reach every dnsdist server",
+		slog.String("reason", err.Error()),
+		slog.Any("service", svc),
+		slog.Bool("healthy", healthy),
+		slog.Bool("applied", update.Applied),
+		slog.Bool("rolledBack", update.Reverted),
+		slog.Any("stragglers", update.Stragglers()),
+	)
+	return update.Reverted
+}
+
+// undoes a promotion the dnsdist servers rolled back, so the store and the group match what DNS still answers with,
+// and evaluates the group again after DEFAULT_PROMOTION_RETRY, which promotes the member again if it still should be
+func (sm *ServicesManager) revertPromotion(event *PromotionEvent) {
+	sm.setActiveFlags(event, true)
+
+	sm.mutex.RLock()
+	group, ok := sm.serviceGroups[event.Service]
+	sm.mutex.RUnlock()
+	if !ok {
+		return
+	}
+	group.RevertPromotion(event)
+	bslog.Warn("reverted promotion rolled back by dnsdist servers",
+		slog.String("serviceGroup", event.Service),
+		slog.Any("newActive", event.NewActive),
+		slog.Any("oldActive", event.OldActive),
+		slog.Duration("retryIn", DEFAULT_PROMOTION_RETRY))
+
+	time.AfterFunc(DEFAULT_PROMOTION_RETRY, func() {
+		select {
+		case <-sm.quit:
+		default:
+			group.Update()
+		}
+	})
+}
+
+// periodically lets service groups in ActiveActiveRoundTrip mode promote a faster member
+func (sm *ServicesManager) evaluateRoundtrips() {
+	ticker := time.NewTicker(DEFAULT_ROUNDTRIP_EVALUATION_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sm.quit:
+			return
+		case <-ticker.C:
+			sm.mutex.RLock()
+			groups := make([]*ServiceGroup, 0, len(sm.serviceGroups))
+			for _, group := range sm.serviceGroups {
+				groups = append(groups, group)
+			}
+			sm.mutex.RUnlock()
+
+			for _, group := range groups {
+				group.EvaluateRoundtrip()
+			}
+		}
+	}
+}
+
 func (sm *ServicesManager) newServiceGroup(memberOf string) *ServiceGroup {
 	newGroup := NewEmptyServiceGroup(memberOf)
+	newGroup.SetRoundtripHysteresis(sm.roundtripMinImprovement, sm.roundtripDwellTime)
 	newGroup.OnPromotion = func(event *PromotionEvent) {
 		sm.handlePromotion(event)
 	}
@@ -509,6 +665,14 @@ func (sm *ServicesManager) moveServiceToInterval(svc *service.Service, newInterv
 		slog.Any("service", svc))
 }
 
+// returns the registered service with id, or nil if there is none
+func (sm *ServicesManager) GetService(id string) *service.Service {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	_, _, svc := sm.scheduledServices.Search(id)
+	return svc
+}
+
 func (sm *ServicesManager) GetActiveForMemberOf(memberOf string) *service.Service {
 	sm.mutex.RLock()
 	defer sm.mutex.RUnlock()
@@ -519,9 +683,11 @@ func (sm *ServicesManager) GetActiveForMemberOf(memberOf string) *service.Servic
 }
 
 func (sm *ServicesManager) Failover(fqdn string, failover failover.Failover) error {
+	sm.mutex.RLock()
 	group, ok := sm.serviceGroups[fqdn]
+	sm.mutex.RUnlock()
 	if !ok {
-		return fmt.Errorf("no registered service group for: %s", fqdn)
+		return fmt.Errorf("%w: %s", ErrServiceGroupNotFound, fqdn)
 	}
 
 	err := group.Failover(fqdn, failover)
@@ -532,9 +698,27 @@ func (sm *ServicesManager) Failover(fqdn string, failover failover.Failover) err
 	return nil
 }
 
+// releases a manual failover, and lets health checks decide the active service again
+func (sm *ServicesManager) ReleaseFailover(fqdn string) error {
+	sm.mutex.RLock()
+	group, ok := sm.serviceGroups[fqdn]
+	sm.mutex.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrServiceGroupNotFound, fqdn)
+	}
+
+	err := group.ReleaseFailover()
+	if err != nil {
+		return fmt.Errorf("could not release failover for service group: %s: %w", fqdn, err)
+	}
+
+	return nil
+}
+
 func (sm *ServicesManager) BuildServiceOptions(config model.GSLBConfig) []service.ServiceOption {
 	opts := make([]service.ServiceOption, 0, 5)
 	opts = append(opts, service.WithDryRunChecks(sm.dryrun))
+	opts = append(opts, service.WithFlapDetection(config.FlapThreshold, time.Duration(config.FlapWindow)))
 
 	gslbService, err := sm.svcRepo.GetMemberInGroup(config.MemberOf, config.ServiceID)
 	if err != nil {
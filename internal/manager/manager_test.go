@@ -1,12 +1,15 @@
 package manager
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/vitistack/gslb-operator/internal/model"
 	"github.com/vitistack/gslb-operator/internal/service"
 	"github.com/vitistack/gslb-operator/internal/utils/timesutil"
+	"github.com/vitistack/gslb-operator/pkg/models/failover"
+	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
 )
 
 var genericGSLBConfig = model.GSLBConfig{
@@ -199,8 +202,8 @@ func TestServicesManager_updateService(t *testing.T) {
 			sm.Start()
 			defer sm.Stop()
 
-			sm.DNSUpdate = func(s *service.Service, b bool) {
-
+			sm.DNSUpdate = func(s *service.Service, b bool) (spoofs.FleetUpdate, error) {
+				return spoofs.FleetUpdate{}, nil
 			}
 			old, err := sm.RegisterService(tt.old)
 			if err != nil {
@@ -279,3 +282,42 @@ func TestServicesManager_moveServiceToInterval(t *testing.T) {
 	}
 }
 
+
+func TestServicesManager_RevertPromotion(t *testing.T) {
+	sm := NewManager(WithDryRun(true))
+	sm.DNSUpdate = func(*service.Service, bool) (spoofs.FleetUpdate, error) { return spoofs.FleetUpdate{}, nil }
+
+	register := func(id, datacenter string) *service.Service {
+		t.Helper()
+		svc, err := sm.RegisterService(dependencyConfig(id, "app.example.com", datacenter))
+		if err != nil {
+			t.Fatalf("could not register service: %v", err)
+		}
+		svc.OnSuccess()
+		return svc
+	}
+
+	dc1 := register("app-dc1", "dc1")
+	register("app-dc2", "dc2")
+	if active := sm.GetActiveForMemberOf("app.example.com"); active != dc1 {
+		t.Fatalf("expected the first healthy member to be active, got: %v", active)
+	}
+
+	sm.DNSUpdate = func(*service.Service, bool) (spoofs.FleetUpdate, error) {
+		return spoofs.FleetUpdate{Reverted: true}, errors.New("too few dnsdist servers took the update")
+	}
+	if err := sm.Failover("app.example.com", failover.Failover{Datacenter: "dc2"}); err != nil {
+		t.Fatalf("could not fail over: %v", err)
+	}
+
+	if active := sm.GetActiveForMemberOf("app.example.com"); active != dc1 {
+		t.Errorf("expected the rolled back promotion to be reverted in the group, got: %v", active)
+	}
+	stored, err := sm.svcRepo.GetActive("app.example.com")
+	if err != nil || stored.ID != "app-dc1" {
+		t.Errorf("expected the rolled back promotion to be reverted in the store, got: %+v, %v", stored, err)
+	}
+	if pinned := sm.serviceGroups["app.example.com"].GetPinned(); pinned != nil {
+		t.Errorf("expected the failover that did not make it to DNS to be released, got pinned: %v", pinned)
+	}
+}
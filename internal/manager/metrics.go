@@ -23,4 +23,12 @@ var (
 		},
 		[]string{"memberOf"},
 	)
+
+	dnsUpdates = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_updates_total",
+			Help: "Number of DNS updates by how the dnsdist servers took them: applied, partial, rolled_back or failed",
+		},
+		[]string{"result"},
+	)
 )
@@ -1,12 +1,21 @@
 package manager
 
-import "github.com/vitistack/gslb-operator/internal/repositories/service"
+import (
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/repositories/service"
+	"github.com/vitistack/gslb-operator/pkg/models/maintenance"
+	"github.com/vitistack/gslb-operator/pkg/persistence"
+)
 
 type managerConfig struct {
-	MinRunningWorkers     uint
-	NonBlockingBufferSize uint
-	DryRun                bool
-	repo                  *service.ServiceRepo
+	MinRunningWorkers       uint
+	NonBlockingBufferSize   uint
+	DryRun                  bool
+	RoundtripMinImprovement float64
+	RoundtripDwellTime      time.Duration
+	repo                    *service.ServiceRepo
+	maintenance             persistence.Store[maintenance.Window]
 }
 
 type serviceManagerOption func(cfg *managerConfig)
@@ -34,3 +43,25 @@ func WithServiceRepository(repo *service.ServiceRepo) serviceManagerOption {
 		cfg.repo = repo
 	}
 }
+
+// where maintenance windows are stored, in memory if not set
+func WithMaintenanceStore(store persistence.Store[maintenance.Window]) serviceManagerOption {
+	return func(cfg *managerConfig) {
+		cfg.maintenance = store
+	}
+}
+
+// hysteresis for service groups in ActiveActiveRoundTrip mode.
+// minImprovement is the relative improvement (0.2 = 20% faster) a member needs over the active member,
+// and dwellTime is how long it needs to keep that improvement before it is promoted.
+// zero leaves the default
+func WithRoundtripHysteresis(minImprovement float64, dwellTime time.Duration) serviceManagerOption {
+	return func(cfg *managerConfig) {
+		if minImprovement > 0 {
+			cfg.RoundtripMinImprovement = minImprovement
+		}
+		if dwellTime > 0 {
+			cfg.RoundtripDwellTime = dwellTime
+		}
+	}
+}
@@ -12,4 +12,5 @@ type QueryManager interface {
 
 	//write operations
 	Failover(fqdn string, failover failover.Failover) error
+	ReleaseFailover(fqdn string) error
 }
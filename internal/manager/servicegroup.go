@@ -2,10 +2,13 @@ package manager
 
 import (
 	"cmp"
+	"fmt"
 	"log/slog"
 	"slices"
 	"sync"
+	"time"
 
+	"github.com/vitistack/gslb-operator/internal/model"
 	"github.com/vitistack/gslb-operator/internal/service"
 	"github.com/vitistack/gslb-operator/pkg/bslog"
 	"github.com/vitistack/gslb-operator/pkg/models/failover"
@@ -17,7 +20,22 @@ const (
 	ActiveActive ServiceGroupMode = iota
 	ActivePassive
 	//ActiveActivePassive TODO: decide if this is necessary
-	ActiveActiveRoundTrip // TODO: When svc does not exist in DC, then smallest roundtrip time wins
+	ActiveActiveRoundTrip // the healthy member with the smallest average roundtrip time wins
+	// every healthy member in the best priority tier is served at the same time, as a multi-record answer
+	ActiveActiveMultiRecord
+	// every healthy member with a weight is served at the same time, and gets traffic in proportion to its weight
+	ActiveActiveWeighted
+)
+
+const (
+	// a member must be at least this much faster (relative to the active) to be considered for promotion
+	DEFAULT_ROUNDTRIP_MIN_IMPROVEMENT = 0.2
+	// how long a faster member must stay faster before it gets promoted
+	DEFAULT_ROUNDTRIP_DWELL_TIME = time.Second * 30
+	// how often roundtrip groups are evaluated for a faster member
+	DEFAULT_ROUNDTRIP_EVALUATION_INTERVAL = time.Second * 5
+	// how long a group waits before trying again, after the dnsdist servers rolled back a promotion
+	DEFAULT_PROMOTION_RETRY = time.Second * 30
 )
 
 func (m *ServiceGroupMode) String() string {
@@ -26,6 +44,12 @@ func (m *ServiceGroupMode) String() string {
 		return "ActiveActive"
 	case ActivePassive:
 		return "ActivePassive"
+	case ActiveActiveRoundTrip:
+		return "ActiveActiveRoundTrip"
+	case ActiveActiveMultiRecord:
+		return "ActiveActiveMultiRecord"
+	case ActiveActiveWeighted:
+		return "ActiveActiveWeighted"
 	default:
 		return "ActiveActive"
 	}
@@ -34,6 +58,7 @@ func (m *ServiceGroupMode) String() string {
 // PromotionEvent is an event that occurs when there is a new Active service in a service group.
 // It is triggered using the OnPromotion function of the ServiceGroup belonging to that service.
 // The new active service is always healthy, unless no services are healthy in the service group. Then the active service is nil in the event.
+// In ActiveActiveMultiRecord and ActiveActiveWeighted a member joining the served members only has NewActive set, and a member leaving only has OldActive set.
 type PromotionEvent struct {
 	Service   string
 	NewActive *service.Service
@@ -56,6 +81,22 @@ type ServiceGroup struct {
 	//last active service in a service group
 	lastActive *service.Service
 
+	// served are the members currently served in ActiveActiveMultiRecord and ActiveActiveWeighted, always nil in other modes.
+	// active is then the first of the served members.
+	served []*service.Service
+
+	// pinned is the service manually promoted through Failover.
+	// As long as it is healthy it keeps the active role, regardless of priority.
+	pinned *service.Service
+
+	// roundtrip hysteresis, only used in ActiveActiveRoundTrip.
+	// candidate is the faster member waiting out the dwell time, before it gets promoted.
+	minImprovement float64
+	dwellTime      time.Duration
+	candidate      *service.Service
+	candidateSince time.Time
+	roundtrip      func(*service.Service) time.Duration
+
 	// should never receive a nil promotion event
 	OnPromotion           func(*PromotionEvent)
 	prioritizedDatacenter string
@@ -64,28 +105,40 @@ type ServiceGroup struct {
 
 func NewEmptyServiceGroup(name string) *ServiceGroup {
 	return &ServiceGroup{
-		Name:       name,
-		mode:       ActiveActive,
-		Members:    make([]*service.Service, 0),
-		active:     nil,
-		lastActive: nil,
-		mu:         sync.RWMutex{},
+		Name:           name,
+		mode:           ActiveActive,
+		Members:        make([]*service.Service, 0),
+		active:         nil,
+		lastActive:     nil,
+		minImprovement: DEFAULT_ROUNDTRIP_MIN_IMPROVEMENT,
+		dwellTime:      DEFAULT_ROUNDTRIP_DWELL_TIME,
+		roundtrip:      (*service.Service).GetAverageRoundtrip,
+		mu:             sync.RWMutex{},
 	}
 }
 
-// returns the active service in ActivePassive mode,
+// configures the hysteresis used when promoting on roundtrip time
+func (sg *ServiceGroup) SetRoundtripHysteresis(minImprovement float64, dwellTime time.Duration) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	sg.minImprovement = minImprovement
+	sg.dwellTime = dwellTime
+}
+
+// returns the active service in ActivePassive and ActiveActiveRoundTrip mode,
+// the first served service in ActiveActiveMultiRecord and ActiveActiveWeighted,
 // or returns the first healthy service in ActiveActive if no explicit active is set.
 func (sg *ServiceGroup) GetActive() *service.Service {
 	sg.mu.RLock()
 	defer sg.mu.RUnlock()
 	switch sg.mode {
-	case ActivePassive:
+	case ActivePassive, ActiveActiveRoundTrip, ActiveActiveMultiRecord, ActiveActiveWeighted:
 		if sg.active != nil {
 			return sg.active
 		}
 	default:
 		for _, svc := range sg.Members {
-			if svc.IsHealthy() {
+			if sg.isHealthy(svc) {
 				return svc
 			}
 		}
@@ -94,22 +147,293 @@ func (sg *ServiceGroup) GetActive() *service.Service {
 	return sg.active
 }
 
+// reports whether svc can be given out in DNS. A member that flaps is held down,
+// unless no member is healthy and it passes its checks, withdrawing it would leave the group without a working member.
+// expects the caller to hold the lock
+func (sg *ServiceGroup) isHealthy(svc *service.Service) bool {
+	if svc.IsHealthy() {
+		return true
+	}
+	return svc.IsDampened() && svc.PassesChecks() && !slices.ContainsFunc(sg.Members, (*service.Service).IsHealthy)
+}
+
 // returns the first healthy service of the members in the group.
 // In other words, the service that SHOULD be active.
 // this is true because the members are sorted on priority.
 func (sg *ServiceGroup) firstHealthy() *service.Service {
 	sg.mu.RLock()
 	defer sg.mu.RUnlock()
+	return sg.nextHealthy()
+}
+
+// same as firstHealthy, but expects the caller to hold the lock
+func (sg *ServiceGroup) nextHealthy() *service.Service {
 	for _, svc := range sg.Members {
-		if svc.IsHealthy() {
+		if sg.isHealthy(svc) {
 			return svc
 		}
 	}
 	return nil
 }
 
+// returns the service that should hold the active role.
+// A healthy pinned service always wins, otherwise it is the first healthy service.
+func (sg *ServiceGroup) desiredActive() *service.Service {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+	if sg.pinned != nil && sg.isHealthy(sg.pinned) {
+		return sg.pinned
+	}
+
+	if sg.mode == ActiveActiveRoundTrip {
+		if sg.active != nil && sg.isHealthy(sg.active) { // switching on roundtrip is left to EvaluateRoundtrip
+			return sg.active
+		}
+		return sg.fastestHealthy()
+	}
+	return sg.nextHealthy()
+}
+
+// returns the healthy member with the lowest average roundtrip.
+// members without recorded roundtrips are only considered if no member has any.
+// expects the caller to hold the lock
+func (sg *ServiceGroup) fastestHealthy() *service.Service {
+	var fastest *service.Service
+	var fastestRoundtrip time.Duration
+	for _, svc := range sg.Members {
+		if !sg.isHealthy(svc) {
+			continue
+		}
+
+		roundtrip := sg.roundtrip(svc)
+		if roundtrip <= 0 {
+			continue
+		}
+
+		if fastest == nil || roundtrip < fastestRoundtrip {
+			fastest = svc
+			fastestRoundtrip = roundtrip
+		}
+	}
+
+	if fastest == nil {
+		return sg.nextHealthy()
+	}
+	return fastest
+}
+
+// returns the members that should be served in ActiveActiveMultiRecord:
+// every healthy member in the best priority tier, or only the pinned member during a manual failover.
+// expects the caller to hold the lock
+func (sg *ServiceGroup) bestTier() []*service.Service {
+	if sg.pinned != nil && sg.isHealthy(sg.pinned) {
+		return []*service.Service{sg.pinned}
+	}
+
+	tier := make([]*service.Service, 0)
+	for _, svc := range sg.Members { // members are sorted on priority
+		if !sg.isHealthy(svc) {
+			continue
+		}
+		if len(tier) > 0 && svc.GetPriority() != tier[0].GetPriority() {
+			break
+		}
+		tier = append(tier, svc)
+	}
+	return tier
+}
+
+// returns the members that should be served in ActiveActiveWeighted:
+// every healthy member with a weight, or only the pinned member during a manual failover.
+// when no healthy member has a weight, the best priority tier is served instead.
+// expects the caller to hold the lock
+func (sg *ServiceGroup) weightedMembers() []*service.Service {
+	if sg.pinned != nil && sg.isHealthy(sg.pinned) {
+		return []*service.Service{sg.pinned}
+	}
+
+	weighted := make([]*service.Service, 0)
+	for _, svc := range sg.Members {
+		if sg.isHealthy(svc) && svc.GetWeight() > 0 {
+			weighted = append(weighted, svc)
+		}
+	}
+
+	if len(weighted) == 0 {
+		return sg.bestTier()
+	}
+	return weighted
+}
+
+// reports whether the group serves several members at the same time.
+// expects the caller to hold the lock
+func (sg *ServiceGroup) servesMany() bool {
+	return sg.mode == ActiveActiveMultiRecord || sg.mode == ActiveActiveWeighted
+}
+
+// returns the members that should be served, when the group serves several members at the same time.
+// expects the caller to hold the lock
+func (sg *ServiceGroup) servedMembers() []*service.Service {
+	if sg.mode == ActiveActiveWeighted {
+		return sg.weightedMembers()
+	}
+	return sg.bestTier()
+}
+
+// changes the served members to desired, and returns the promotion events that gets DNS there.
+// joining members are announced before leaving members are withdrawn, so the answer never goes empty on a switch.
+// expects the caller to hold the lock
+func (sg *ServiceGroup) syncServed(desired []*service.Service) []*PromotionEvent {
+	served := sg.served
+	if served == nil && sg.active != nil { // coming from a single active mode
+		served = []*service.Service{sg.active}
+	}
+
+	events := make([]*PromotionEvent, 0)
+	for _, svc := range desired {
+		if !slices.Contains(served, svc) {
+			events = append(events, &PromotionEvent{Service: sg.Name, NewActive: svc})
+		}
+	}
+	for _, svc := range served {
+		if !slices.Contains(desired, svc) {
+			events = append(events, &PromotionEvent{Service: sg.Name, OldActive: svc})
+		}
+	}
+
+	sg.served = desired
+	sg.lastActive = sg.active
+	sg.active = nil
+	if len(desired) > 0 {
+		sg.active = desired[0]
+	}
+
+	return events
+}
+
+// reports whether svc is currently given out in DNS for the group
+func (sg *ServiceGroup) IsActive(svc *service.Service) bool {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+	if sg.servesMany() {
+		return slices.Contains(sg.served, svc)
+	}
+	return sg.active == svc
+}
+
+// undoes a promotion that did not make it to DNS, so the group serves what DNS still answers with.
+// a manual failover to the member is released, as it never took effect. nothing else changes if the group has moved on since
+func (sg *ServiceGroup) RevertPromotion(event *PromotionEvent) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	if event.NewActive != nil && sg.pinned == event.NewActive {
+		bslog.Warn("manual failover did not make it to DNS, releasing it", slog.String("group", sg.Name), slog.Any("service", sg.pinned))
+		sg.pinned = nil
+	}
+
+	if sg.servesMany() {
+		served := slices.Clone(sg.served)
+		if event.NewActive != nil {
+			served = slices.DeleteFunc(served, func(svc *service.Service) bool {
+				return svc == event.NewActive
+			})
+		}
+		if event.OldActive != nil && !slices.Contains(served, event.OldActive) {
+			served = append(served, event.OldActive)
+			slices.SortFunc(served, sortMembersFunc)
+		}
+
+		sg.served = served
+		sg.active = nil
+		if len(served) > 0 {
+			sg.active = served[0]
+		}
+		return
+	}
+
+	if sg.active != event.NewActive {
+		return
+	}
+	sg.active = event.OldActive
+}
+
+func (sg *ServiceGroup) promote(events []*PromotionEvent) {
+	for _, event := range events {
+		sg.OnPromotion(event)
+	}
+}
+
+// EvaluateRoundtrip promotes a faster member in ActiveActiveRoundTrip mode.
+// To avoid flapping on jitter, the member must be faster than the active by at least the minimum improvement,
+// and stay that way for the whole dwell time.
+func (sg *ServiceGroup) EvaluateRoundtrip() {
+	sg.mu.Lock()
+	if sg.mode != ActiveActiveRoundTrip || (sg.pinned != nil && sg.isHealthy(sg.pinned)) {
+		sg.mu.Unlock()
+		return
+	}
+
+	fastest := sg.fastestHealthy()
+	if fastest == nil || fastest == sg.active {
+		sg.candidate = nil
+		sg.mu.Unlock()
+		return
+	}
+
+	if sg.active != nil && sg.isHealthy(sg.active) {
+		activeRoundtrip := sg.roundtrip(sg.active)
+		improvement := 1.0
+		if activeRoundtrip > 0 {
+			improvement = float64(activeRoundtrip-sg.roundtrip(fastest)) / float64(activeRoundtrip)
+		}
+
+		if improvement < sg.minImprovement {
+			sg.candidate = nil
+			sg.mu.Unlock()
+			return
+		}
+
+		if sg.candidate != fastest {
+			sg.candidate = fastest
+			sg.candidateSince = time.Now()
+			sg.mu.Unlock()
+			return
+		}
+
+		if time.Since(sg.candidateSince) < sg.dwellTime {
+			sg.mu.Unlock()
+			return
+		}
+	}
+
+	event := &PromotionEvent{
+		Service:   sg.Name,
+		NewActive: fastest,
+		OldActive: sg.active,
+	}
+	bslog.Debug("promoting service with lower roundtrip", slog.Any("newActive", fastest), slog.Any("oldActive", sg.active))
+	sg.lastActive = sg.active
+	sg.active = fastest
+	sg.candidate = nil
+	sg.mu.Unlock()
+	sg.OnPromotion(event)
+}
+
 func (sg *ServiceGroup) OnServiceHealthChange(changedService *service.Service, healthy bool) {
 	sg.mu.Lock()
+	healthy = sg.isHealthy(changedService) // a dampened member is still served while no member is healthy
+	if sg.pinned != nil {
+		if !healthy && sg.pinned.GetID() == changedService.GetID() {
+			bslog.Warn("pinned service is no longer healthy, releasing failover", slog.Any("service", changedService))
+			sg.pinned = nil
+		} else if sg.isHealthy(sg.pinned) && sg.pinned == sg.active {
+			// the pinned service keeps the active role for as long as it is healthy
+			sg.mu.Unlock()
+			return
+		}
+	}
+
 	oldActive := sg.active
 	if oldActive == nil {
 		oldActive = sg.lastActive
@@ -117,7 +441,7 @@ func (sg *ServiceGroup) OnServiceHealthChange(changedService *service.Service, h
 
 	switch sg.mode {
 	case ActivePassive:
-		if !healthy && sg.active.GetID() == changedService.GetID() { // active has gone down!
+		if !healthy && sg.active != nil && sg.active.GetID() == changedService.GetID() { // active has gone down!
 			sg.lastActive = sg.active
 			sg.mu.Unlock()
 			sg.OnPromotion(sg.promoteNextHealthy())
@@ -135,60 +459,78 @@ func (sg *ServiceGroup) OnServiceHealthChange(changedService *service.Service, h
 			sg.active = changedService
 			sg.mu.Unlock()
 			sg.OnPromotion(event)
+			return
 		}
+		sg.mu.Unlock()
 
 	case ActiveActive:
 		if healthy {
 			// If prioritized DC service becomes healthy, it must become active (single DNS record).
-			if changedService.Datacenter == sg.prioritizedDatacenter && changedService != sg.active {
-				sg.mu.Unlock()
-				sg.OnPromotion(&PromotionEvent{
-					Service:   sg.Name,
-					NewActive: changedService,
-					OldActive: sg.active,
-				})
-				sg.active = changedService
-				return
-			}
 			// If there is no active or the current active is unhealthy, promote this healthy service.
-			if sg.active == nil || !sg.active.IsHealthy() {
-				sg.mu.Unlock()
-				sg.OnPromotion(&PromotionEvent{
+			if (changedService.Datacenter == sg.prioritizedDatacenter && changedService != sg.active) ||
+				sg.active == nil || !sg.isHealthy(sg.active) {
+				event := &PromotionEvent{
 					Service:   sg.Name,
 					NewActive: changedService,
 					OldActive: sg.active,
-				})
+				}
+				sg.lastActive = sg.active
 				sg.active = changedService
+				sg.mu.Unlock()
+				sg.OnPromotion(event)
 				return
 			}
+			sg.mu.Unlock()
 			return
 		}
 
 		// unhealthy
-		if changedService.GetID() == sg.active.GetID() {
+		if sg.active == nil || changedService.GetID() != sg.active.GetID() {
 			sg.mu.Unlock()
-			next := sg.firstHealthy()
-			if next != nil {
-				sg.OnPromotion(&PromotionEvent{
-					Service:   sg.Name,
-					NewActive: next,
-					OldActive: sg.active,
-				})
-				sg.lastActive = sg.active
-				sg.active = next
-				return
-			}
+			return
+		}
 
-			// all down -> signal DNS delete (single-record)
-			sg.OnPromotion(&PromotionEvent{
-				Service:   sg.Name,
-				NewActive: nil,
-				OldActive: sg.active,
-			})
-			sg.lastActive = sg.active
-			sg.active = nil
+		next := sg.nextHealthy()
+		event := &PromotionEvent{
+			Service:   sg.Name,
+			NewActive: next, // nil when all are down -> signal DNS delete (single-record)
+			OldActive: sg.active,
+		}
+		sg.lastActive = sg.active
+		sg.active = next
+		sg.mu.Unlock()
+		sg.OnPromotion(event)
+
+	case ActiveActiveMultiRecord, ActiveActiveWeighted:
+		events := sg.syncServed(sg.servedMembers())
+		sg.mu.Unlock()
+		sg.promote(events)
+
+	case ActiveActiveRoundTrip:
+		var next *service.Service
+		switch {
+		case healthy && (sg.active == nil || !sg.isHealthy(sg.active)):
+			next = changedService
+		case !healthy && sg.active != nil && changedService.GetID() == sg.active.GetID():
+			next = sg.fastestHealthy() // nil when all are down
+		default: // faster members are promoted by EvaluateRoundtrip
+			sg.mu.Unlock()
 			return
 		}
+
+		event := &PromotionEvent{
+			Service:   sg.Name,
+			NewActive: next,
+			OldActive: sg.active,
+		}
+		sg.lastActive = sg.active
+		sg.active = next
+		sg.candidate = nil
+		sg.mu.Unlock()
+		sg.OnPromotion(event)
+
+	default:
+		sg.mu.Unlock()
 	}
 }
 
@@ -220,6 +562,9 @@ func (sg *ServiceGroup) RemoveService(id string) bool {
 	})
 	if idx != -1 {
 		sg.mu.Lock()
+		if sg.pinned != nil && sg.pinned.GetID() == id {
+			sg.pinned = nil
+		}
 		sg.Members = append(members[:idx], members[idx+1:]...)
 		sg.mu.Unlock()
 		sg.Update()
@@ -241,7 +586,7 @@ func (sg *ServiceGroup) promoteNextHealthy() *PromotionEvent {
 	bestPriority := int(^uint(0) >> 1) // max int
 
 	for i, svc := range sg.Members {
-		if svc.IsHealthy() && svc.GetPriority() < bestPriority {
+		if sg.isHealthy(svc) && svc.GetPriority() < bestPriority {
 			bestIdx = i
 			bestPriority = svc.GetPriority()
 		}
@@ -266,12 +611,12 @@ func (sg *ServiceGroup) promoteNextHealthy() *PromotionEvent {
 }
 
 func (sg *ServiceGroup) triggerPromotion(service *service.Service) bool {
-	if !service.IsHealthy() {
+	if !sg.isHealthy(service) {
 		return false
 	}
 
-	if sg.active == nil || !sg.active.IsHealthy() { // if active not healthy then all other healthy services are prioritized
-		return service.IsHealthy()
+	if sg.active == nil || !sg.isHealthy(sg.active) { // if active not healthy then all other healthy services are prioritized
+		return sg.isHealthy(service)
 	}
 
 	return service.GetPriority() <= sg.active.GetPriority()
@@ -288,10 +633,20 @@ func (sg *ServiceGroup) SetGroupMode() {
 		return
 	}
 
+	// an explicitly requested mode always wins over the automatic selection
+	if explicit, ok := sg.requestedMode(); ok {
+		sg.mu.RUnlock()
+		sg.mu.Lock()
+		sg.mode = explicit
+		sg.mu.Unlock()
+		bslog.Debug("servicegroup mode set", slog.Any("mode", explicit.String()))
+		return
+	}
+
 	// If one service, default to ActiveActive but don't pre-seed active unless healthy
 	if numServices == 1 {
 		sg.mode = ActiveActive
-		if sg.Members[0].IsHealthy() {
+		if sg.isHealthy(sg.Members[0]) {
 			sg.active = sg.Members[0]
 		} else {
 			sg.active = nil
@@ -341,44 +696,126 @@ func (sg *ServiceGroup) SetGroupMode() {
 	bslog.Debug("servicegroup mode set", slog.Any("mode", sg.mode.String()))
 }
 
+// returns the mode explicitly requested by the group members, members are sorted so the highest priority decides.
+// expects the caller to hold the lock
+func (sg *ServiceGroup) requestedMode() (ServiceGroupMode, bool) {
+	for _, svc := range sg.Members {
+		switch svc.GetGroupMode() {
+		case model.GROUP_MODE_ROUNDTRIP:
+			return ActiveActiveRoundTrip, true
+		case model.GROUP_MODE_MULTI:
+			return ActiveActiveMultiRecord, true
+		case model.GROUP_MODE_WEIGHTED:
+			return ActiveActiveWeighted, true
+		}
+	}
+	return ActiveActive, false
+}
+
 func (sg *ServiceGroup) memberExists(member *service.Service) bool {
 	sg.mu.RLock()
 	defer sg.mu.RUnlock()
 	return slices.Contains(sg.Members, member)
 }
 
+// Failover manually promotes a healthy member of the group, either the member in the requested datacenter,
+// or the next healthy member after the current active one.
+// The promoted member is pinned as active until it becomes unhealthy, or the pin is released with ReleaseFailover.
 func (sg *ServiceGroup) Failover(fqdn string, failover failover.Failover) error {
-	bslog.Warn("un-implemented servicegroup.Failover(...) method")
-	/*
-		var failoverSvc *service.Service
+	sg.mu.Lock()
+
+	var failoverSvc *service.Service
+	switch {
+	case failover.Datacenter != "":
 		for _, svc := range sg.Members {
-			if svc.Datacenter == failover.Datacenter {
-				failoverSvc = svc
+			if svc.Datacenter != failover.Datacenter {
+				continue
+			}
+			failoverSvc = svc
+			if sg.isHealthy(svc) { // prefer a healthy member if there are several in the same datacenter
 				break
 			}
 		}
 
 		if failoverSvc == nil {
-			return fmt.Errorf("no service in service group registered with datacenter: %s", failover.Datacenter)
+			sg.mu.Unlock()
+			return fmt.Errorf("%w: no service registered with datacenter: %s", ErrServiceNotFoundInGroup, failover.Datacenter)
 		}
 
-		if !failoverSvc.IsHealthy() {
-			return fmt.Errorf("%w: service not considered healthy: %v", ErrCannotPromoteUnHealthyService, failoverSvc)
+	case failover.NextHealthy: // the member a promotion would pick, if the active member was down
+		candidates := slices.DeleteFunc(slices.Clone(sg.Members), func(svc *service.Service) bool {
+			return svc == sg.active || !sg.isHealthy(svc)
+		})
+		slices.SortFunc(candidates, sortMembersFunc)
+		if len(candidates) > 0 {
+			failoverSvc = candidates[0]
 		}
 
-		sg.lastActive = sg.active
-		sg.active = failoverSvc
-		//TODO: is this enough?
-		sg.OnPromotion(&PromotionEvent{
-			Service:   fqdn,
-			NewActive: failoverSvc,
-			OldActive: sg.lastActive,
-		})
+		if failoverSvc == nil {
+			sg.mu.Unlock()
+			return fmt.Errorf("%w: no healthy service to fail over to", ErrCannotPromoteUnHealthyService)
+		}
 
-	*/
+	default:
+		sg.mu.Unlock()
+		return ErrInvalidFailover
+	}
+
+	if !sg.isHealthy(failoverSvc) {
+		sg.mu.Unlock()
+		return fmt.Errorf("%w: service not considered healthy: %v", ErrCannotPromoteUnHealthyService, failoverSvc)
+	}
+
+	sg.pinned = failoverSvc
+	if sg.servesMany() { // withdraw every other served member
+		events := sg.syncServed(sg.servedMembers())
+		sg.mu.Unlock()
+
+		bslog.Info("manual failover", slog.String("group", fqdn), slog.Any("newActive", failoverSvc))
+		sg.promote(events)
+		return nil
+	}
+
+	if sg.active == failoverSvc { // already active, only pin it
+		sg.mu.Unlock()
+		return nil
+	}
+
+	sg.lastActive = sg.active
+	sg.active = failoverSvc
+	event := &PromotionEvent{
+		Service:   sg.Name,
+		NewActive: failoverSvc,
+		OldActive: sg.lastActive,
+	}
+	sg.mu.Unlock()
+
+	bslog.Info("manual failover", slog.String("group", fqdn), slog.Any("newActive", failoverSvc))
+	sg.OnPromotion(event)
 	return nil
 }
 
+// ReleaseFailover removes a pin set by Failover, and hands the active role back to the health-driven selection.
+func (sg *ServiceGroup) ReleaseFailover() error {
+	sg.mu.Lock()
+	if sg.pinned == nil {
+		sg.mu.Unlock()
+		return ErrNoActiveFailover
+	}
+	sg.pinned = nil
+	sg.mu.Unlock()
+
+	sg.Update()
+	return nil
+}
+
+// returns the manually pinned service of the group, if any
+func (sg *ServiceGroup) GetPinned() *service.Service {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+	return sg.pinned
+}
+
 func (sg *ServiceGroup) Update() {
 	sg.mu.RLock()
 	if len(sg.Members) == 0 { // dont need to do anything, group should be removed!
@@ -392,21 +829,35 @@ func (sg *ServiceGroup) Update() {
 	sg.mu.Unlock()
 
 	sg.SetGroupMode()
-	firstHealthy := sg.firstHealthy() // who should have the active role!
-	if firstHealthy != sg.active {
+	desired := sg.desiredActive() // who should have the active role!
+
+	sg.mu.Lock()
+	var events []*PromotionEvent
+	switch {
+	case sg.servesMany():
+		events = sg.syncServed(sg.servedMembers())
+
+	case sg.served != nil: // no longer serving several members, withdraw every served member except the new active
+		keep := make([]*service.Service, 0, 1)
+		if desired != nil {
+			keep = append(keep, desired)
+		}
+		events = sg.syncServed(keep)
+		sg.served = nil
+
+	case desired != sg.active:
 		// trigger promotion because whoever is active should not be active anymore!
-		sg.mu.Lock()
-		defer sg.mu.Unlock()
 		sg.lastActive = sg.active
-		sg.active = firstHealthy
-
-		event := &PromotionEvent{
+		sg.active = desired
+		events = append(events, &PromotionEvent{
 			Service:   sg.Name,
 			OldActive: sg.lastActive,
 			NewActive: sg.active,
-		}
-		sg.OnPromotion(event)
+		})
 	}
+	sg.mu.Unlock()
+
+	sg.promote(events)
 }
 
 // func passed into slices.SortFunc for sorting the groups members
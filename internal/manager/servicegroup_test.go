@@ -9,6 +9,7 @@ import (
 	"github.com/vitistack/gslb-operator/internal/model"
 	"github.com/vitistack/gslb-operator/internal/service"
 	"github.com/vitistack/gslb-operator/internal/utils/timesutil"
+	"github.com/vitistack/gslb-operator/pkg/models/failover"
 )
 
 type Test struct {
@@ -193,3 +194,381 @@ func TestServiceGroup_memberExists(t *testing.T) {
 		})
 	}
 }
+
+func newHealthyTestService(t *testing.T, id, dc string, priority int) *service.Service {
+	t.Helper()
+	svc, err := service.NewServiceFromGSLBConfig(model.GSLBConfig{
+		ServiceID:        id,
+		MemberOf:         "failover.example.com",
+		Fqdn:             "test.example.com",
+		Ip:               "192.168.1.1",
+		Port:             "80",
+		Datacenter:       dc,
+		Interval:         timesutil.Duration(5 * time.Second),
+		Priority:         priority,
+		FailureThreshold: 1,
+		CheckType:        "TCP-FULL",
+	}, service.WithDryRunChecks(true), service.WithHealthy(), service.WithFailureCount(0))
+	if err != nil {
+		t.Fatalf("could not create service during testing: %s", err.Error())
+	}
+	return svc
+}
+
+func TestServiceGroup_Failover(t *testing.T) {
+	dc1 := newHealthyTestService(t, "dc1", "dc1", 1)
+	dc2 := newHealthyTestService(t, "dc2", "dc2", 2)
+	dc3 := newHealthyTestService(t, "dc3", "dc3", 3)
+
+	var events []*PromotionEvent
+	group := NewEmptyServiceGroup("failover.example.com")
+	group.OnPromotion = func(pe *PromotionEvent) {
+		events = append(events, pe)
+	}
+	for _, svc := range []*service.Service{dc1, dc2, dc3} {
+		svc.SetHealthChangeCallback(func(healthy bool) {
+			group.OnServiceHealthChange(svc, healthy)
+		})
+		group.RegisterService(svc)
+	}
+
+	if group.GetActive() != dc1 {
+		t.Fatalf("expected dc1 to be active before failover, got: %v", group.GetActive())
+	}
+	events = nil
+
+	if err := group.Failover(group.Name, failover.Failover{Datacenter: "dc2"}); err != nil {
+		t.Fatalf("failover to dc2 failed: %s", err.Error())
+	}
+	if len(events) != 1 || events[0].NewActive != dc2 || events[0].OldActive != dc1 {
+		t.Fatalf("expected promotion event from dc1 to dc2, got: %v", events)
+	}
+
+	group.Update()
+	if group.GetActive() != dc2 {
+		t.Fatal("update reverted the manual failover")
+	}
+
+	// health changes of other members must not revert the pin
+	dc1.OnFailure(errors.New("test"))
+	dc1.OnSuccess()
+	if group.GetActive() != dc2 {
+		t.Fatal("health change of another member reverted the manual failover")
+	}
+
+	if err := group.Failover(group.Name, failover.Failover{NextHealthy: true}); err != nil {
+		t.Fatalf("failover to next healthy failed: %s", err.Error())
+	}
+	if group.GetActive() != dc1 {
+		t.Fatalf("expected next healthy to be dc1, got: %v", group.GetActive())
+	}
+
+	dc3.OnFailure(errors.New("test"))
+	err := group.Failover(group.Name, failover.Failover{Datacenter: "dc3"})
+	if !errors.Is(err, ErrCannotPromoteUnHealthyService) {
+		t.Fatalf("expected error: %v, but got: %v", ErrCannotPromoteUnHealthyService, err)
+	}
+
+	err = group.Failover(group.Name, failover.Failover{Datacenter: "dc4"})
+	if !errors.Is(err, ErrServiceNotFoundInGroup) {
+		t.Fatalf("expected error: %v, but got: %v", ErrServiceNotFoundInGroup, err)
+	}
+
+	if err := group.Failover(group.Name, failover.Failover{}); !errors.Is(err, ErrInvalidFailover) {
+		t.Fatalf("expected error: %v, but got: %v", ErrInvalidFailover, err)
+	}
+
+	// pinned member goes down: pin is released and the next healthy takes over
+	if err := group.Failover(group.Name, failover.Failover{Datacenter: "dc2"}); err != nil {
+		t.Fatalf("failover to dc2 failed: %s", err.Error())
+	}
+	dc2.OnFailure(errors.New("test"))
+	if group.GetPinned() != nil {
+		t.Fatal("pin should be released when the pinned service becomes unhealthy")
+	}
+	if group.GetActive() != dc1 {
+		t.Fatalf("expected dc1 to take over after pinned service went down, got: %v", group.GetActive())
+	}
+
+	dc2.OnSuccess()
+	if err := group.Failover(group.Name, failover.Failover{Datacenter: "dc2"}); err != nil {
+		t.Fatalf("failover to dc2 failed: %s", err.Error())
+	}
+	if err := group.ReleaseFailover(); err != nil {
+		t.Fatalf("could not release failover: %s", err.Error())
+	}
+	if group.GetActive() != dc1 {
+		t.Fatalf("expected dc1 to be active after releasing failover, got: %v", group.GetActive())
+	}
+	if err := group.ReleaseFailover(); !errors.Is(err, ErrNoActiveFailover) {
+		t.Fatalf("expected error: %v, but got: %v", ErrNoActiveFailover, err)
+	}
+
+	// the next healthy member is picked on priority, as a promotion would
+	dc3.OnSuccess()
+	group.mu.Lock()
+	group.Members = []*service.Service{dc1, dc3, dc2}
+	group.mu.Unlock()
+	if err := group.Failover(group.Name, failover.Failover{NextHealthy: true}); err != nil {
+		t.Fatalf("failover to next healthy failed: %s", err.Error())
+	}
+	if group.GetActive() != dc2 {
+		t.Fatalf("expected next healthy to be dc2 with the better priority, got: %v", group.GetActive())
+	}
+}
+
+func TestServiceGroup_EvaluateRoundtrip(t *testing.T) {
+	roundtrips := map[string]time.Duration{
+		"dc1": 100 * time.Millisecond,
+		"dc2": 90 * time.Millisecond,
+	}
+
+	dc1 := newHealthyTestService(t, "dc1", "dc1", 1)
+	dc2 := newHealthyTestService(t, "dc2", "dc2", 1)
+	roundtripConfig := model.GSLBConfig{
+		ServiceID:        "dc1",
+		MemberOf:         "failover.example.com",
+		Fqdn:             "test.example.com",
+		Ip:               "192.168.1.1",
+		Port:             "80",
+		Datacenter:       "dc1",
+		Interval:         timesutil.Duration(5 * time.Second),
+		Priority:         1,
+		FailureThreshold: 1,
+		CheckType:        "TCP-FULL",
+		GroupMode:        "roundtrip",
+	}
+	roundtripSvc, err := service.NewServiceFromGSLBConfig(roundtripConfig, service.WithDryRunChecks(true))
+	if err != nil {
+		t.Fatalf("could not create service during testing: %s", err.Error())
+	}
+	dc1.Assign(roundtripSvc)
+
+	var events []*PromotionEvent
+	group := NewEmptyServiceGroup("failover.example.com")
+	group.roundtrip = func(s *service.Service) time.Duration {
+		return roundtrips[s.GetID()]
+	}
+	group.OnPromotion = func(pe *PromotionEvent) {
+		events = append(events, pe)
+	}
+	group.RegisterService(dc1)
+	group.RegisterService(dc2)
+
+	if group.mode != ActiveActiveRoundTrip {
+		t.Fatalf("expected group mode: %v, but got: %v", ActiveActiveRoundTrip, group.mode)
+	}
+
+	if group.GetActive() != dc1 {
+		t.Fatalf("expected the first healthy service to be active, got: %v", group.GetActive())
+	}
+
+	// dc2 is faster, but not by the minimum improvement
+	events = nil
+	group.SetRoundtripHysteresis(0.2, time.Hour)
+	group.EvaluateRoundtrip()
+	if len(events) != 0 {
+		t.Fatal("promoted on jitter below the minimum improvement")
+	}
+
+	// dc2 is a lot faster, but has to wait out the dwell time
+	roundtrips["dc2"] = 10 * time.Millisecond
+	group.EvaluateRoundtrip()
+	group.EvaluateRoundtrip()
+	if len(events) != 0 {
+		t.Fatal("promoted before the dwell time has passed")
+	}
+
+	group.SetRoundtripHysteresis(0.2, 0)
+	group.EvaluateRoundtrip()
+	if len(events) != 1 || events[0].NewActive != dc2 || events[0].OldActive != dc1 {
+		t.Fatalf("expected promotion event from dc1 to dc2, got: %v", events)
+	}
+
+	// active goes down: the fastest healthy member takes over right away
+	events = nil
+	roundtrips["dc1"] = 50 * time.Millisecond
+	dc1.SetHealthChangeCallback(func(healthy bool) { group.OnServiceHealthChange(dc1, healthy) })
+	dc2.SetHealthChangeCallback(func(healthy bool) { group.OnServiceHealthChange(dc2, healthy) })
+	dc2.OnFailure(errors.New("test"))
+	if len(events) != 1 || events[0].NewActive != dc1 {
+		t.Fatalf("expected dc1 to take over when dc2 went down, got: %v", events)
+	}
+}
+
+func TestServiceGroup_MultiRecord(t *testing.T) {
+	dc1 := newHealthyTestService(t, "dc1", "dc1", 1)
+	dc2 := newHealthyTestService(t, "dc2", "dc2", 1)
+	dc3 := newHealthyTestService(t, "dc3", "dc3", 2)
+	multiSvc, err := service.NewServiceFromGSLBConfig(model.GSLBConfig{
+		ServiceID:        "dc1",
+		MemberOf:         "failover.example.com",
+		Ip:               "192.168.1.1",
+		Port:             "80",
+		Datacenter:       "dc1",
+		Priority:         1,
+		FailureThreshold: 1,
+		GroupMode:        model.GROUP_MODE_MULTI,
+	}, service.WithDryRunChecks(true))
+	if err != nil {
+		t.Fatalf("could not create service during testing: %s", err.Error())
+	}
+	dc1.Assign(multiSvc)
+
+	served := make(map[string]bool)
+	group := NewEmptyServiceGroup("failover.example.com")
+	group.OnPromotion = func(pe *PromotionEvent) {
+		if pe.NewActive != nil {
+			served[pe.NewActive.GetID()] = true
+		}
+		if pe.OldActive != nil {
+			delete(served, pe.OldActive.GetID())
+		}
+	}
+	for _, svc := range []*service.Service{dc1, dc2, dc3} {
+		svc.SetHealthChangeCallback(func(healthy bool) {
+			group.OnServiceHealthChange(svc, healthy)
+		})
+		group.RegisterService(svc)
+	}
+
+	if group.mode != ActiveActiveMultiRecord {
+		t.Fatalf("expected group mode: %v, but got: %v", ActiveActiveMultiRecord, group.mode)
+	}
+	if len(served) != 2 || !served["dc1"] || !served["dc2"] {
+		t.Fatalf("expected dc1 and dc2 to be served, got: %v", served)
+	}
+
+	dc1.OnFailure(errors.New("test"))
+	if len(served) != 1 || !served["dc2"] {
+		t.Fatalf("expected only dc2 to be served, got: %v", served)
+	}
+
+	// best tier is down, the next tier takes over
+	dc2.OnFailure(errors.New("test"))
+	if len(served) != 1 || !served["dc3"] {
+		t.Fatalf("expected only dc3 to be served, got: %v", served)
+	}
+
+	dc1.OnSuccess()
+	if len(served) != 1 || !served["dc1"] {
+		t.Fatalf("expected only dc1 to be served, got: %v", served)
+	}
+	if !group.IsActive(dc1) || group.IsActive(dc3) {
+		t.Fatal("group does not report the served members as active")
+	}
+}
+
+func TestServiceGroup_Weighted(t *testing.T) {
+	newWeighted := func(id string, priority, weight int) *service.Service {
+		svc, err := service.NewServiceFromGSLBConfig(model.GSLBConfig{
+			ServiceID:        id,
+			MemberOf:         "weighted.example.com",
+			Ip:               "192.168.1.1",
+			Port:             "80",
+			Datacenter:       id,
+			Priority:         priority,
+			FailureThreshold: 1,
+			GroupMode:        model.GROUP_MODE_WEIGHTED,
+			Weight:           weight,
+		}, service.WithDryRunChecks(true), service.WithHealthy(), service.WithFailureCount(0))
+		if err != nil {
+			t.Fatalf("could not create service during testing: %s", err.Error())
+		}
+		return svc
+	}
+	dc1 := newWeighted("dc1", 1, 90)
+	dc2 := newWeighted("dc2", 2, 10)
+	dc3 := newWeighted("dc3", 1, 0)
+
+	served := make(map[string]bool)
+	group := NewEmptyServiceGroup("weighted.example.com")
+	group.OnPromotion = func(pe *PromotionEvent) {
+		if pe.NewActive != nil {
+			served[pe.NewActive.GetID()] = true
+		}
+		if pe.OldActive != nil {
+			delete(served, pe.OldActive.GetID())
+		}
+	}
+	for _, svc := range []*service.Service{dc1, dc2, dc3} {
+		svc.SetHealthChangeCallback(func(healthy bool) {
+			group.OnServiceHealthChange(svc, healthy)
+		})
+		group.RegisterService(svc)
+	}
+
+	if group.mode != ActiveActiveWeighted {
+		t.Fatalf("expected group mode: %v, but got: %v", ActiveActiveWeighted, group.mode)
+	}
+	// priorities do not matter, but members without weight get no traffic
+	if len(served) != 2 || !served["dc1"] || !served["dc2"] {
+		t.Fatalf("expected dc1 and dc2 to be served, got: %v", served)
+	}
+
+	dc1.OnFailure(errors.New("test"))
+	if len(served) != 1 || !served["dc2"] {
+		t.Fatalf("expected only dc2 to be served, got: %v", served)
+	}
+
+	// no healthy member with weight, fall back to the best priority tier
+	dc2.OnFailure(errors.New("test"))
+	if len(served) != 1 || !served["dc3"] {
+		t.Fatalf("expected only dc3 to be served, got: %v", served)
+	}
+
+	dc1.OnSuccess()
+	if len(served) != 1 || !served["dc1"] {
+		t.Fatalf("expected only dc1 to be served, got: %v", served)
+	}
+}
+
+func TestServiceGroup_DampenedMember(t *testing.T) {
+	newFlappingService := func(id string, priority int) *service.Service {
+		svc, err := service.NewServiceFromGSLBConfig(model.GSLBConfig{
+			ServiceID:        id,
+			MemberOf:         "flapping.example.com",
+			Fqdn:             "test.example.com",
+			Ip:               "192.168.1.1",
+			Port:             "80",
+			Datacenter:       id,
+			Interval:         timesutil.Duration(5 * time.Second),
+			Priority:         priority,
+			FailureThreshold: 1,
+			CheckType:        "TCP-FULL",
+		}, service.WithDryRunChecks(true), service.WithFlapDetection(2, time.Hour))
+		if err != nil {
+			t.Fatalf("could not create service during testing: %s", err.Error())
+		}
+		return svc
+	}
+	dc1 := newFlappingService("dc1", 1)
+	dc2 := newFlappingService("dc2", 2)
+
+	group := NewEmptyServiceGroup("flapping.example.com")
+	group.OnPromotion = func(pe *PromotionEvent) {}
+	for _, svc := range []*service.Service{dc1, dc2} {
+		svc.SetHealthChangeCallback(func(healthy bool) {
+			group.OnServiceHealthChange(svc, healthy)
+		})
+		group.RegisterService(svc)
+	}
+
+	dc1.OnSuccess()
+	dc1.OnFailure(errors.New("test"))
+	if group.GetActive() != nil {
+		t.Fatalf("expected no active member while every member is down, got: %v", group.GetActive())
+	}
+
+	// the second transition dampens dc1, but it is the only member passing its checks
+	dc1.OnSuccess()
+	if !dc1.IsDampened() || group.GetActive() != dc1 {
+		t.Fatalf("expected the dampened dc1 to be served while no other member is healthy, got: %v", group.GetActive())
+	}
+
+	// a healthy member takes over from the dampened one
+	dc2.OnSuccess()
+	if group.GetActive() != dc2 {
+		t.Fatalf("expected dc2 to take over from the dampened dc1, got: %v", group.GetActive())
+	}
+}
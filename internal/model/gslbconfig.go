@@ -1,13 +1,19 @@
 package model
 
-import "github.com/vitistack/gslb-operator/internal/utils/timesutil"
+import (
+	"reflect"
+	"strings"
+
+	"github.com/vitistack/gslb-operator/internal/utils/timesutil"
+)
 
 // JSON - object in the TXT records for the GSLB - config zone
 type GSLBConfig struct {
 	ServiceID        string             `json:"service_id"`
 	Fqdn             string             `json:"fqdn"`
 	MemberOf         string             `json:"memberOf"`
-	Ip               string             `json:"ip"`
+	Ip               string             `json:"ip"`   // IPv4 or IPv6 address
+	Ipv6             string             `json:"ipv6"` // IPv6 address of a dual-stack service, Ip is then its IPv4 address
 	Port             string             `json:"port"`
 	Datacenter       string             `json:"datacenter"`
 	Interval         timesutil.Duration `json:"interval"`
@@ -15,4 +21,113 @@ type GSLBConfig struct {
 	FailureThreshold int                `json:"failure_threshold"`
 	CheckType        string             `json:"check_type"`
 	Script           string             `json:"lua"`
+	GroupMode        string             `json:"group_mode"`           // explicitly select the mode of the service group, empty for automatic
+	Weight           int                `json:"weight"`               // share of the traffic in a WEIGHTED service group, relative to the other members
+	DNS              DNSCheckConfig     `json:"dns,omitzero"`         // query of a DNS check
+	GRPC             GRPCCheckConfig    `json:"grpc,omitzero"`        // request of a GRPC check
+	HTTP             HTTPCheckConfig    `json:"http,omitzero"`        // request of a HTTP or HTTPS check
+	Checks           []CheckConfig      `json:"checks,omitempty"`     // checks of a COMPOSITE check, combined with CheckMode. CheckType is ignored if set
+	CheckMode        string             `json:"check_mode"`           // ALL, ANY or QUORUM of Checks must succeed, ALL if empty
+	CheckQuorum      int                `json:"check_quorum"`         // how many of Checks must succeed with the QUORUM mode
+	DependsOn        []string           `json:"depends_on,omitempty"` // service groups (memberOf) that must have a healthy member in the datacenter of the service
+	FlapThreshold    int                `json:"flap_threshold"`       // health transitions within FlapWindow that dampen the service, never if zero or negative
+	FlapWindow       timesutil.Duration `json:"flap_window"`          // 10m if zero
+}
+
+// reports whether both configs configure the same service in the same way
+func (c GSLBConfig) Equal(other GSLBConfig) bool {
+	return reflect.DeepEqual(c, other)
+}
+
+// returns the single check of the config, the check of the service unless it is a COMPOSITE check
+func (c GSLBConfig) Check() CheckConfig {
+	return CheckConfig{
+		CheckType: c.CheckType,
+		Script:    c.Script,
+		DNS:       c.DNS,
+		GRPC:      c.GRPC,
+		HTTP:      c.HTTP,
+	}
+}
+
+// one check of a COMPOSITE check
+type CheckConfig struct {
+	Name      string          `json:"name"` // reported in logs and metrics, the lower case check type if empty
+	CheckType string          `json:"check_type"`
+	Port      string          `json:"port"` // port to check, the port of the service if empty
+	Script    string          `json:"lua"`
+	DNS       DNSCheckConfig  `json:"dns,omitzero"`
+	GRPC      GRPCCheckConfig `json:"grpc,omitzero"`
+	HTTP      HTTPCheckConfig `json:"http,omitzero"`
+}
+
+func (c CheckConfig) Equal(other CheckConfig) bool {
+	return reflect.DeepEqual(c, other)
 }
+
+// returns the name the check is reported with
+func (c CheckConfig) GetName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	if c.CheckType == "" {
+		return "tcp-full" // the default check type
+	}
+	return strings.ToLower(c.CheckType)
+}
+
+// request sent to the address of the service by a HTTP or HTTPS check, and the status it should answer with
+type HTTPCheckConfig struct {
+	Host           string            `json:"host"`            // Host header, the fqdn of the service if empty
+	SNI            string            `json:"sni"`             // server name of the TLS handshake, the host if empty
+	Method         string            `json:"method"`          // GET if empty
+	Path           string            `json:"path"`            // / if empty
+	Headers        map[string]string `json:"headers"`         // extra request headers
+	Body           string            `json:"body"`            // request body
+	ExpectedStatus []int             `json:"expected_status"` // every status but 503 is healthy if empty
+	TLS            TLSCheckConfig    `json:"tls,omitzero"`    // TLS options of a HTTPS check
+}
+
+// TLS options of a check, the files are read from the file system of the operator
+type TLSCheckConfig struct {
+	Verify        bool               `json:"verify"`         // verify the certificate for the server name, always when a CA bundle or client certificate is set
+	CAFile        string             `json:"ca_file"`        // PEM bundle of the CAs to verify against, the system roots if empty
+	CertFile      string             `json:"cert_file"`      // PEM client certificate, for services that require mTLS
+	KeyFile       string             `json:"key_file"`       // PEM key of the client certificate
+	ExpiryWarning timesutil.Duration `json:"expiry_warning"` // warn when the certificate expires within, never if zero
+	ExpiryFailure timesutil.Duration `json:"expiry_failure"` // fail the check when the certificate expires within, never if zero
+}
+
+func (c HTTPCheckConfig) Equal(other HTTPCheckConfig) bool {
+	return reflect.DeepEqual(c, other)
+}
+
+// query sent to the service by a DNS check, and the answer it should give
+type DNSCheckConfig struct {
+	Name    string `json:"name"`    // name to query, the fqdn of the service if empty
+	Type    string `json:"type"`    // qtype to query, A if empty
+	Net     string `json:"net"`     // udp or tcp, udp if empty
+	Rcode   string `json:"rcode"`   // expected rcode, NOERROR if empty
+	Answers string `json:"answers"` // comma separated rdata that must be in the answer, e.g. "10.0.0.1,10.0.0.2". not checked if empty
+}
+
+// request sent to the service by a GRPC check, with the grpc.health.v1 protocol
+type GRPCCheckConfig struct {
+	Service    string         `json:"service"`              // name of the grpc service to check, the health of the whole server if empty
+	TLS        bool           `json:"tls"`                  // connect with TLS, the fqdn of the service is used as server name
+	TLSOptions TLSCheckConfig `json:"tls_options,omitzero"` // TLS options of the check, when TLS is set
+}
+
+// modes of a COMPOSITE check, that can be selected with CheckMode
+const (
+	CHECK_MODE_ALL    = "ALL"    // every check must succeed
+	CHECK_MODE_ANY    = "ANY"    // one check must succeed
+	CHECK_MODE_QUORUM = "QUORUM" // CheckQuorum of the checks must succeed
+)
+
+// service group modes that can be explicitly requested with GroupMode
+const (
+	GROUP_MODE_ROUNDTRIP = "ROUNDTRIP" // the healthy member with the lowest measured roundtrip is active
+	GROUP_MODE_MULTI     = "MULTI"     // every healthy member in the best priority tier is active, as a multi-record answer
+	GROUP_MODE_WEIGHTED  = "WEIGHTED"  // every healthy member with a weight is active, and gets traffic in proportion to its weight
+)
@@ -1,34 +1,89 @@
 package model
 
 import (
+	"cmp"
+	"slices"
+
 	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
 )
 
 type GSLBServiceGroup []GSLBService
 
+// returns the spoofs that should be configured for the service group.
+// a group with several active services (ActiveActiveMultiRecord) results in a single multi-record spoof,
+// unless every active service has a weight (ActiveActiveWeighted), then there is one weighted spoof per service.
+// an overridden service takes all the traffic, whatever the group type.
+func (g GSLBServiceGroup) Spoofs() []spoofs.Spoof {
+	active := make([]spoofs.Spoof, 0, 1)
+	weighted := true
+	for _, svc := range g {
+		if svc.IsActive && svc.HasOverride {
+			spoof := svc.Spoof()
+			spoof.Weight = 0
+			return []spoofs.Spoof{spoof}
+		}
+		if svc.IsActive {
+			active = append(active, svc.Spoof())
+			weighted = weighted && svc.Weight > 0
+		}
+	}
+
+	switch {
+	case len(active) > 1 && weighted:
+		slices.SortFunc(active, func(a, b spoofs.Spoof) int {
+			return cmp.Compare(a.DC, b.DC)
+		})
+		return active
+	case len(active) > 1:
+		return []spoofs.Spoof{spoofs.Merge(active[0].FQDN, active...)}
+	case len(active) == 1:
+		active[0].Weight = 0 // a single service gets all the traffic
+	}
+	return active
+}
+
 // storage representation of service
 // services that are configured with gslb config end up as a service.Service
 type GSLBService struct {
-	ID           string `json:"id"`
-	MemberOf     string `json:"memberOf"`
-	Fqdn         string `json:"fqdn"`
-	Datacenter   string `json:"datacenter"`
-	IP           string `json:"ip"`
-	IsHealthy    bool   `json:"isHealthy"`
-	FailureCount int    `json:"failureCount"`
-	IsActive     bool   `json:"isActive"`
-	HasOverride  bool   `json:"hasOverride"`
+	ID           string   `json:"id"`
+	MemberOf     string   `json:"memberOf"`
+	Fqdn         string   `json:"fqdn"`
+	Datacenter   string   `json:"datacenter"`
+	IP           string   `json:"ip"`
+	IPs          []string `json:"ips,omitempty"` // every address of a dual-stack service, IP is then the first of them
+	IsHealthy    bool     `json:"isHealthy"`
+	FailureCount int      `json:"failureCount"`
+	IsActive     bool     `json:"isActive"`
+	HasOverride  bool     `json:"hasOverride"`
+	Weight       int      `json:"weight,omitempty"` // only set for members of a weighted service group
+
+	// service groups the service depends on without a healthy member in its datacenter,
+	// the service is treated as unhealthy while there are any
+	UnmetDependencies []string `json:"unmetDependencies,omitempty"`
+
+	// open maintenance window the service is in, it is treated as unhealthy until the window ends
+	Maintenance string `json:"maintenance,omitempty"`
 }
 
 func (s GSLBService) Key() string {
 	return s.MemberOf
 }
 
-// returns spoof representation of GSLBService
-func (s GSLBService) Spoof() spoofs.Spoof {
-	return spoofs.Spoof{
-		FQDN: s.MemberOf,
-		IP:   s.IP,
-		DC:   s.Datacenter,
+// returns every address of the service
+func (s GSLBService) Addresses() []string {
+	if len(s.IPs) > 0 {
+		return s.IPs
+	}
+	if s.IP == "" {
+		return nil
 	}
+	return []string{s.IP}
+}
+
+// returns spoof representation of GSLBService.
+// a dual-stack service spoofs both its addresses, dnsdist answers A and AAAA queries with the matching ones
+func (s GSLBService) Spoof() spoofs.Spoof {
+	spoof := spoofs.NewSpoof(s.MemberOf, s.Datacenter, s.Addresses()...)
+	spoof.Weight = s.Weight
+	return spoof
 }
@@ -0,0 +1,40 @@
+package model
+
+import "testing"
+
+func TestGSLBServiceGroup_SpoofsWithOverride(t *testing.T) {
+	tests := []struct {
+		name  string
+		group GSLBServiceGroup
+	}{
+		{
+			name: "multi-record",
+			group: GSLBServiceGroup{
+				{MemberOf: "app.example.com", Datacenter: "dc1", IP: "192.168.1.10", IsActive: true, HasOverride: true},
+				{MemberOf: "app.example.com", Datacenter: "dc2", IP: "10.0.0.2", IsActive: true},
+			},
+		},
+		{
+			name: "weighted",
+			group: GSLBServiceGroup{
+				{MemberOf: "app.example.com", Datacenter: "dc1", IP: "10.0.0.1", IsActive: true, Weight: 1},
+				{MemberOf: "app.example.com", Datacenter: "dc2", IP: "192.168.1.10", IsActive: true, HasOverride: true, Weight: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.group.Spoofs()
+			if len(got) != 1 {
+				t.Fatalf("expected a single spoof for the overridden service, got: %+v", got)
+			}
+			if got[0].IP != "192.168.1.10" || len(got[0].IPs) > 0 {
+				t.Errorf("expected only the override address, got: %+v", got[0])
+			}
+			if got[0].Weight != 0 {
+				t.Errorf("expected the overridden service to get all the traffic, got weight: %d", got[0].Weight)
+			}
+		})
+	}
+}
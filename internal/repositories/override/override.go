@@ -0,0 +1,98 @@
+package override
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vitistack/gslb-operator/pkg/models/spoofs"
+	"github.com/vitistack/gslb-operator/pkg/persistence"
+)
+
+// repository for the active overrides, and the audit trail of every change to them
+type OverrideRepo struct {
+	active  persistence.Store[spoofs.Override]
+	history persistence.Store[spoofs.OverrideEvent]
+}
+
+func NewOverrideRepo(active persistence.Store[spoofs.Override], history persistence.Store[spoofs.OverrideEvent]) *OverrideRepo {
+	return &OverrideRepo{
+		active:  active,
+		history: history,
+	}
+}
+
+// returns the active override for the service group, false if there is none
+func (r *OverrideRepo) Read(memberOf string) (spoofs.Override, bool, error) {
+	override, err := r.active.Load(memberOf)
+	if err != nil {
+		return spoofs.Override{}, false, fmt.Errorf("failed to read from storage: %w", err)
+	}
+	return override, override.MemberOf != "", nil
+}
+
+func (r *OverrideRepo) Save(override spoofs.Override) error {
+	if err := r.active.Save(override.MemberOf, override); err != nil {
+		return fmt.Errorf("failed to store override: %w", err)
+	}
+	return nil
+}
+
+func (r *OverrideRepo) Delete(memberOf string) error {
+	if err := r.active.Delete(memberOf); err != nil {
+		return fmt.Errorf("failed to delete override: %w", err)
+	}
+	return nil
+}
+
+// returns the active overrides that have expired at now
+func (r *OverrideRepo) Expired(now time.Time) ([]spoofs.Override, error) {
+	overrides, err := r.active.LoadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from storage: %w", err)
+	}
+
+	return slices.DeleteFunc(overrides, func(o spoofs.Override) bool {
+		return o.ExpiresAt.IsZero() || now.Before(o.ExpiresAt)
+	}), nil
+}
+
+// adds the action taken on override to the audit trail
+func (r *OverrideRepo) Record(action, user string, override spoofs.Override) error {
+	event := spoofs.OverrideEvent{
+		ID:        uuid.NewString(),
+		Time:      time.Now(),
+		Action:    action,
+		User:      user,
+		MemberOf:  override.MemberOf,
+		ExpiresAt: override.ExpiresAt,
+		Reason:    override.Reason,
+	}
+	if override.IP != nil {
+		event.IP = override.IP.String()
+	}
+
+	if err := r.history.Save(event.ID, event); err != nil {
+		return fmt.Errorf("failed to store override event: %w", err)
+	}
+	return nil
+}
+
+// returns the audit trail matching params, the most recent first
+func (r *OverrideRepo) History(params spoofs.OverrideHistoryParams) ([]spoofs.OverrideEvent, error) {
+	events, err := r.history.LoadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from storage: %w", err)
+	}
+
+	events = slices.DeleteFunc(events, func(e spoofs.OverrideEvent) bool {
+		return (params.MemberOf != "" && e.MemberOf != params.MemberOf) ||
+			(params.User != "" && e.User != params.User)
+	})
+	slices.SortFunc(events, func(a, b spoofs.OverrideEvent) int {
+		return cmp.Or(b.Time.Compare(a.Time), cmp.Compare(a.ID, b.ID))
+	})
+	return events, nil
+}
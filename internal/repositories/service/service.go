@@ -93,6 +93,7 @@ func (sr *ServiceRepo) Update(new *model.GSLBService) error {
 
 	if group[idx].IsActive && override {
 		new.IP = group[idx].IP
+		new.IPs = group[idx].IPs
 		new.HasOverride = true
 	}
 
@@ -107,6 +108,7 @@ func (sr *ServiceRepo) Update(new *model.GSLBService) error {
 
 func (sr *ServiceRepo) UpdateOverride(ip string, service *model.GSLBService) error {
 	service.IP = ip
+	service.IPs = nil // an override spoofs a single address
 
 	group, err := sr.Read(service.MemberOf)
 	if err != nil {
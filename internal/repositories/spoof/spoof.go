@@ -35,10 +35,8 @@ func (r *SpoofRepo) Read(id string) (spoofs.Spoof, error) {
 		return spoofs.Spoof{}, fmt.Errorf("failed to read from storage: %w", err)
 	}
 
-	for _, svc := range group {
-		if svc.IsActive {
-			return svc.Spoof(), nil
-		}
+	if groupSpoofs := group.Spoofs(); len(groupSpoofs) > 0 {
+		return groupSpoofs[0], nil
 	}
 
 	return spoofs.Spoof{}, nil
@@ -50,15 +48,23 @@ func (r *SpoofRepo) ReadMemberOf(memberOf string) (spoofs.Spoof, error) {
 		return spoofs.Spoof{}, fmt.Errorf("failed to read from storage: %w", err)
 	}
 
-	for _, svc := range group {
-		if svc.IsActive {
-			return svc.Spoof(), nil
-		}
+	if groupSpoofs := group.Spoofs(); len(groupSpoofs) > 0 {
+		return groupSpoofs[0], nil
 	}
 
 	return spoofs.Spoof{}, fmt.Errorf("%w: fqdn: %s", ErrSpoofInServiceGroupNotFound, memberOf)
 }
 
+// returns every spoof that should be configured for the service group
+func (r *SpoofRepo) ReadGroup(memberOf string) ([]spoofs.Spoof, error) {
+	group, err := r.store.Load(memberOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from storage: %w", err)
+	}
+
+	return group.Spoofs(), nil
+}
+
 func (r *SpoofRepo) ReadAll() ([]spoofs.Spoof, error) {
 	groups, err := r.store.LoadAll()
 	if err != nil {
@@ -67,11 +73,7 @@ func (r *SpoofRepo) ReadAll() ([]spoofs.Spoof, error) {
 
 	spoofs := make([]spoofs.Spoof, 0)
 	for _, group := range groups {
-		for _, svc := range group {
-			if svc.IsActive {
-				spoofs = append(spoofs, svc.Spoof())
-			}
-		}
+		spoofs = append(spoofs, group.Spoofs()...)
 	}
 
 	return spoofs, nil
@@ -6,8 +6,23 @@ import (
 )
 
 var (
-	ErrInvalidGslbConfig   = errors.New("invalid GSLB - config")
-	ErrEmptyServiceId      = fmt.Errorf("%w: empty service id", ErrInvalidGslbConfig)
-	ErrUnableToParseIpAddr = fmt.Errorf("%w: unable to parse ip address", ErrInvalidGslbConfig)
-	ErrUnableToResolveAddr = fmt.Errorf("%w: unable to resolve address", ErrInvalidGslbConfig)
+	ErrInvalidGslbConfig     = errors.New("invalid GSLB - config")
+	ErrEmptyServiceId        = fmt.Errorf("%w: empty service id", ErrInvalidGslbConfig)
+	ErrUnableToParseIpAddr   = fmt.Errorf("%w: unable to parse ip address", ErrInvalidGslbConfig)
+	ErrUnableToResolveAddr   = fmt.Errorf("%w: unable to resolve address", ErrInvalidGslbConfig)
+	ErrInvalidDualStack      = fmt.Errorf("%w: dual-stack service needs an IPv4 ip and an IPv6 ipv6", ErrInvalidGslbConfig)
+	ErrNegativeWeight        = fmt.Errorf("%w: weight can not be negative", ErrInvalidGslbConfig)
+	ErrUnknownCheckType      = fmt.Errorf("%w: unknown check type", ErrInvalidGslbConfig)
+	ErrUnknownGroupMode      = fmt.Errorf("%w: unknown group mode", ErrInvalidGslbConfig)
+	ErrIntervalOutOfRange    = fmt.Errorf("%w: interval out of range", ErrInvalidGslbConfig)
+	ErrNegativePriority      = fmt.Errorf("%w: priority can not be negative", ErrInvalidGslbConfig)
+	ErrInvalidPort           = fmt.Errorf("%w: invalid port", ErrInvalidGslbConfig)
+	ErrInvalidFqdn           = fmt.Errorf("%w: invalid fqdn", ErrInvalidGslbConfig)
+	ErrInvalidScript         = fmt.Errorf("%w: lua script does not compile", ErrInvalidGslbConfig)
+	ErrMissingScript         = fmt.Errorf("%w: lua check needs a lua script", ErrInvalidGslbConfig)
+	ErrInvalidDNSCheck       = fmt.Errorf("%w: invalid dns check", ErrInvalidGslbConfig)
+	ErrInvalidHTTPCheck      = fmt.Errorf("%w: invalid http check", ErrInvalidGslbConfig)
+	ErrInvalidGRPCCheck      = fmt.Errorf("%w: invalid grpc check", ErrInvalidGslbConfig)
+	ErrInvalidCompositeCheck = fmt.Errorf("%w: invalid composite check", ErrInvalidGslbConfig)
+	ErrInvalidDependency     = fmt.Errorf("%w: invalid dependency", ErrInvalidGslbConfig)
 )
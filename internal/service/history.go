@@ -0,0 +1,178 @@
+package service
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/vitistack/gslb-operator/pkg/bslog"
+)
+
+const (
+	HISTORY_SIZE        = 50 // check results kept per service
+	DEFAULT_FLAP_WINDOW = 10 * time.Minute
+)
+
+// result of a single health check of a service
+type CheckResult struct {
+	Time    time.Time
+	Latency time.Duration
+	Err     string // empty if the check succeeded
+}
+
+// bounded history of the checks of a service, and the health transitions that decide whether it flaps.
+// a nil history records nothing and never flaps, as of a service that is not created from a config
+type history struct {
+	mu            sync.Mutex
+	results       []CheckResult // ring buffer of HISTORY_SIZE results, next is the oldest once it is full
+	next          int
+	transitions   []time.Time   // health transitions within the flap window
+	dampenedSince time.Time     // zero while the service is not dampened
+	threshold     int           // flap detection is off unless it is positive
+	window        time.Duration // DEFAULT_FLAP_WINDOW if zero
+}
+
+func newHistory() *history {
+	return &history{results: make([]CheckResult, 0, HISTORY_SIZE)}
+}
+
+// health transitions within window dampen the service, once there are threshold of them.
+// flap detection is off by default, and a threshold of zero or less keeps it off. a zero window leaves DEFAULT_FLAP_WINDOW
+func WithFlapDetection(threshold int, window time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.history.threshold = threshold
+		if window > 0 {
+			s.history.window = window
+		}
+	}
+}
+
+func (h *history) record(result CheckResult) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.results) < HISTORY_SIZE {
+		h.results = append(h.results, result)
+		return
+	}
+	h.results[h.next] = result
+	h.next = (h.next + 1) % HISTORY_SIZE
+}
+
+// the results, oldest first
+func (h *history) checkResults() []CheckResult {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	results := make([]CheckResult, 0, len(h.results))
+	results = append(results, h.results[h.next:]...)
+	return append(results, h.results[:h.next]...)
+}
+
+// records a health transition, and reports whether it dampens the service
+func (h *history) transition(now time.Time) bool {
+	if h == nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.transitions = append(h.pruned(now), now)
+	if h.threshold <= 0 || !h.dampenedSince.IsZero() || len(h.transitions) < h.threshold {
+		return false
+	}
+	h.dampenedSince = now
+	return true
+}
+
+// releases the dampening once there has been no transition for the flap window, and reports whether it did
+func (h *history) release(now time.Time) bool {
+	if h == nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.dampenedSince.IsZero() {
+		return false
+	}
+	if h.transitions = h.pruned(now); len(h.transitions) > 0 {
+		return false
+	}
+	h.dampenedSince = time.Time{}
+	return true
+}
+
+// the transitions within the flap window before now
+func (h *history) pruned(now time.Time) []time.Time {
+	for i, transition := range h.transitions {
+		if now.Sub(transition) < h.flapWindow() {
+			return h.transitions[i:]
+		}
+	}
+	return h.transitions[:0]
+}
+
+func (h *history) flapWindow() time.Duration {
+	if h.window == 0 {
+		return DEFAULT_FLAP_WINDOW
+	}
+	return h.window
+}
+
+func (h *history) flapState() (dampenedSince time.Time, transitions int) {
+	if h == nil {
+		return time.Time{}, 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.dampenedSince, len(h.pruned(time.Now()))
+}
+
+func (h *history) dampened() bool {
+	if h == nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return !h.dampenedSince.IsZero()
+}
+
+// records a health transition of the checks of the service, and dampens it when it flaps.
+// expects the caller to hold the lock
+func (s *Service) transition(now time.Time) {
+	if s.history.transition(now) {
+		bslog.Warn("service is flapping, it is held down until it is stable",
+			slog.Any("service", s),
+			slog.Duration("window", s.history.flapWindow()))
+	}
+}
+
+// releases the dampening of a service that has been stable for the flap window.
+// expects the caller to hold the lock
+func (s *Service) releaseDampening(now time.Time) {
+	if s.history.release(now) {
+		bslog.Info("service is stable again, it is no longer held down", slog.Any("service", s))
+	}
+}
+
+// the last HISTORY_SIZE check results of the service, oldest first
+func (s *Service) GetHistory() []CheckResult {
+	return s.history.checkResults()
+}
+
+// reports whether the service is held down because its health flaps
+func (s *Service) IsDampened() bool {
+	return s.history.dampened()
+}
+
+// when the service was dampened, zero if it is not, and the health transitions within the flap window
+func (s *Service) GetFlapState() (dampenedSince time.Time, transitions int) {
+	return s.history.flapState()
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/checks"
+	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/internal/utils/timesutil"
+)
+
+func TestService_FlapDetection(t *testing.T) {
+	svc, err := NewServiceFromGSLBConfig(model.GSLBConfig{
+		ServiceID:        "flapping",
+		MemberOf:         "app.example.com",
+		Ip:               "10.0.0.1",
+		Port:             "443",
+		Datacenter:       "DC1",
+		Interval:         timesutil.Duration(time.Second * 5),
+		FailureThreshold: 1,
+		CheckType:        checks.TCP_FULL,
+	}, WithFlapDetection(3, 50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("could not create service: %v", err)
+	}
+
+	changes := make([]bool, 0)
+	svc.SetHealthChangeCallback(func(healthy bool) {
+		changes = append(changes, healthy)
+	})
+
+	svc.OnSuccess()                      // up
+	svc.OnFailure(errors.New("timeout")) // down
+	svc.OnSuccess()                      // up, the third transition dampens the service
+	if svc.IsHealthy() || !svc.IsDampened() {
+		t.Fatal("expected a flapping service to be held down")
+	}
+	if len(changes) != 3 || changes[2] {
+		t.Errorf("expected the service to stay down while it is dampened, got changes: %v", changes)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	svc.OnSuccess()
+	if !svc.IsHealthy() || svc.IsDampened() {
+		t.Error("expected the service to be released once it is stable for the flap window")
+	}
+	if len(changes) != 4 || !changes[3] {
+		t.Errorf("expected the release to be notified, got changes: %v", changes)
+	}
+
+	history := svc.GetHistory()
+	if len(history) != 4 || history[1].Err != "timeout" || history[3].Err != "" {
+		t.Errorf("expected the history of every check, oldest first, got: %v", history)
+	}
+
+	for range HISTORY_SIZE + 5 {
+		svc.OnSuccess()
+	}
+	if history := svc.GetHistory(); len(history) != HISTORY_SIZE {
+		t.Errorf("expected the history to be bounded to %d results, got: %d", HISTORY_SIZE, len(history))
+	}
+}
@@ -1,9 +1,14 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/vitistack/gslb-operator/internal/checks"
@@ -18,12 +23,23 @@ type HealthChangeCallback func(healthy bool)
 type ServiceOption func(s *Service)
 
 type Service struct {
+	// guards the health of the service, which is checked, put in maintenance, and told about its dependencies from different goroutines:
+	// isHealthy, failureCount, maintenance, unmetDependencies, lastCheck, lastError, lastLatency and healthChangeCallback
+	mu                   sync.RWMutex
 	id                   string
-	addr                 *net.TCPAddr
+	addrs                []*net.TCPAddr // the IPv4 address first for a dual-stack service
 	Fqdn                 string
 	MemberOf             string
 	Datacenter           string
 	checkType            string
+	groupMode            string
+	check                model.CheckConfig   // the check of the service, unless it is a COMPOSITE check
+	subChecks            []model.CheckConfig // the checks of a COMPOSITE check
+	checkQuorum          int                 // how many of the checks of a COMPOSITE check must succeed
+	dependsOn            []string            // service groups the service depends on in its datacenter
+	unmetDependencies    []string            // service groups of dependsOn without a healthy member in the datacenter
+	maintenance          string              // id of the open maintenance window the service is in, empty if none
+	weight               int
 	ScheduledInterval    timesutil.Duration
 	defaultInterval      timesutil.Duration
 	priority             int
@@ -33,31 +49,59 @@ type Service struct {
 	healthChangeCallback HealthChangeCallback
 	isHealthy            bool
 	dryRun               bool
+	lastCheck            time.Time // when the last health check completed
+	lastError            string    // reason of the last failed health check, empty after a successful check
+	lastLatency          time.Duration
+	history              *history // results of the last checks, and whether the service flaps
 }
 
 func NewServiceFromGSLBConfig(config model.GSLBConfig, opts ...ServiceOption) (*Service, error) {
-	ip := net.ParseIP(config.Ip)
-	if ip == nil {
-		return nil, ErrUnableToParseIpAddr
+	if config.Weight < 0 {
+		return nil, ErrNegativeWeight
 	}
 
-	addr, err := net.ResolveTCPAddr("tcp", fmt.Sprintf("%v:%v", ip.String(), config.Port))
-	if err != nil {
-		return nil, ErrUnableToResolveAddr
+	ips := []string{config.Ip}
+	if config.Ipv6 != "" {
+		ips = append(ips, config.Ipv6)
+	}
+
+	addrs := make([]*net.TCPAddr, 0, len(ips))
+	for _, rawIP := range ips {
+		ip := net.ParseIP(rawIP)
+		if ip == nil {
+			return nil, ErrUnableToParseIpAddr
+		}
+
+		addr, err := net.ResolveTCPAddr("tcp", net.JoinHostPort(ip.String(), config.Port))
+		if err != nil {
+			return nil, ErrUnableToResolveAddr
+		}
+		addrs = append(addrs, addr)
 	}
 
 	if config.ServiceID == "" {
 		return nil, ErrEmptyServiceId
 	}
 
+	checkType := strings.ToUpper(config.CheckType)
+	if len(config.Checks) > 0 {
+		checkType = checks.COMPOSITE
+	}
+
 	interval := CalculateInterval(config.Priority, config.Interval)
 	svc := &Service{
 		id:                config.ServiceID,
-		addr:              addr,
+		addrs:             addrs,
 		Fqdn:              config.Fqdn,
 		MemberOf:          config.MemberOf,
 		Datacenter:        config.Datacenter,
-		checkType:         config.CheckType,
+		checkType:         checkType,
+		groupMode:         strings.ToUpper(config.GroupMode),
+		check:             config.Check(),
+		subChecks:         slices.Clone(config.Checks),
+		checkQuorum:       checkQuorum(config),
+		dependsOn:         slices.Clone(config.DependsOn),
+		weight:            config.Weight,
 		ScheduledInterval: interval,
 		defaultInterval:   interval,
 		priority:          config.Priority,
@@ -65,30 +109,26 @@ func NewServiceFromGSLBConfig(config model.GSLBConfig, opts ...ServiceOption) (*
 		failureCount:      config.FailureThreshold, // need to succeed check N times before healthy!
 		isHealthy:         false,
 		dryRun:            false,
+		history:           newHistory(),
 	}
 
 	for _, opt := range opts {
 		opt(svc)
 	}
 
+	var err error
 	switch {
 	case svc.dryRun:
 		svc.checker = &checks.DryRun{}
 
-	case config.CheckType == checks.HTTPS:
-		svc.checker = checks.NewHTTPChecker("https://"+svc.Fqdn, checks.DEFAULT_TIMEOUT, config.Script)
-
-	case config.CheckType == checks.HTTP:
-		svc.checker = checks.NewHTTPChecker("https://"+svc.Fqdn, checks.DEFAULT_TIMEOUT, config.Script)
-
-	case config.CheckType == checks.TCP_FULL:
-		svc.checker = checks.NewTCPFullChecker(svc.addr.String(), checks.DEFAULT_TIMEOUT)
-
-	case config.CheckType == checks.TCP_HALF:
-		svc.checker = checks.NewTCPHalfChecker(svc.addr.String(), checks.DEFAULT_TIMEOUT)
+	case checkType == checks.COMPOSITE:
+		svc.checker, err = svc.newCompositeChecker()
 
 	default:
-		svc.checker = checks.NewTCPFullChecker(svc.addr.String(), checks.DEFAULT_TIMEOUT)
+		svc.checker, err = svc.newChecker(svc.check)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	return svc, nil
@@ -153,7 +193,12 @@ func (s *Service) GetBaseInterval() timesutil.Duration {
 
 // checks health of service
 func (s *Service) Execute() error {
-	return s.checker.Check()
+	start := time.Now()
+	err := s.checker.Check()
+	s.mu.Lock()
+	s.lastLatency = time.Since(start)
+	s.mu.Unlock()
+	return err
 }
 
 /*
@@ -191,53 +236,165 @@ OnFailure : count = 3, healthy = false -> update DNS
 // called when healthcheck is successful
 func (s *Service) OnSuccess() {
 	bslog.Debug("Health-Check Successfull", slog.Any("service", s))
-	if s.isHealthy { // already healthy
-		s.failureCount = 0
-		return
-	}
-
-	if s.failureCount > 0 {
-		s.failureCount--
-	}
-
-	if s.failureCount == 0 {
-		s.isHealthy = true
-		s.healthChangeCallback(true)
-	}
+	s.updateHealth(func() {
+		s.lastCheck = time.Now()
+		s.lastError = ""
+		s.history.record(CheckResult{Time: s.lastCheck, Latency: s.lastLatency})
+		s.releaseDampening(s.lastCheck)
+		if s.isHealthy { // already healthy
+			s.failureCount = 0
+			return
+		}
+
+		if s.failureCount > 0 {
+			s.failureCount--
+		}
+
+		if s.failureCount == 0 {
+			s.isHealthy = true
+			s.transition(s.lastCheck)
+		}
+	})
 }
 
 // called when healthcheck fails
 func (s *Service) OnFailure(err error) {
 	bslog.Debug("Health-Check Failed", slog.Any("service", s), slog.String("error", err.Error()))
-	if !s.isHealthy { // already unhealthy
-		s.failureCount = s.FailureThreshold
-		return
-	}
-
-	if s.failureCount < s.FailureThreshold {
-		s.failureCount++
-	}
-
-	if s.failureCount == s.FailureThreshold { // threshold reached, service is considered down
-		s.isHealthy = false
-		s.healthChangeCallback(false)
-	}
+	s.updateHealth(func() {
+		s.lastCheck = time.Now()
+		s.lastError = err.Error()
+		s.history.record(CheckResult{Time: s.lastCheck, Latency: s.lastLatency, Err: s.lastError})
+		s.releaseDampening(s.lastCheck)
+		if !s.isHealthy { // already unhealthy
+			s.failureCount = s.FailureThreshold
+			return
+		}
+
+		if s.failureCount < s.FailureThreshold {
+			s.failureCount++
+		}
+
+		if s.failureCount == s.FailureThreshold { // threshold reached, service is considered down
+			s.isHealthy = false
+			s.transition(s.lastCheck)
+		}
+	})
 }
 
 func (s *Service) SetHealthChangeCallback(callback HealthChangeCallback) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.healthChangeCallback = callback
 }
 
+// reports whether the checks of the service succeed, it does not flap, it is not in maintenance,
+// and every service group it depends on is up in its datacenter
 func (s *Service) IsHealthy() bool {
-	return s.isHealthy
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.healthy()
+}
+
+// reports whether the checks of the service succeed, it is not in maintenance, and every service group it depends on is up,
+// even while it is dampened because its health flaps
+func (s *Service) PassesChecks() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.passesChecks()
+}
+
+// see IsHealthy, expects the caller to hold the lock
+func (s *Service) healthy() bool {
+	return s.passesChecks() && !s.history.dampened()
+}
+
+// see PassesChecks, expects the caller to hold the lock
+func (s *Service) passesChecks() bool {
+	return s.isHealthy && s.maintenance == "" && len(s.unmetDependencies) == 0
+}
+
+// runs change under the lock, and calls the health change callback after unlocking if the change made the service healthy or unhealthy.
+// the callback is also called when a dampened service starts or stops passing its checks, its group may still serve it
+func (s *Service) updateHealth(change func()) {
+	s.mu.Lock()
+	wasHealthy, wasPassing := s.healthy(), s.passesChecks()
+	change()
+	healthy, passing := s.healthy(), s.passesChecks()
+	callback := s.healthChangeCallback
+	s.mu.Unlock()
+
+	if callback != nil && (healthy != wasHealthy || passing != wasPassing) { // nil until the service is registered
+		callback(healthy)
+	}
+}
+
+// returns the service groups the service depends on
+func (s *Service) GetDependsOn() []string {
+	return s.dependsOn
+}
+
+// returns the service groups the service depends on, that have no healthy member in its datacenter
+func (s *Service) GetUnmetDependencies() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.unmetDependencies
+}
+
+// sets the service groups the service depends on, that have no healthy member in its datacenter.
+// the service is unhealthy while any of them is down, without waiting for its own checks to fail
+func (s *Service) SetUnmetDependencies(unmet []string) {
+	s.updateHealth(func() {
+		if slices.Equal(s.unmetDependencies, unmet) {
+			return
+		}
+
+		s.unmetDependencies = unmet
+		if len(unmet) > 0 {
+			bslog.Warn("service depends on service group that is down", slog.Any("service", s), slog.Any("groups", unmet))
+		}
+	})
+}
+
+// returns the id of the open maintenance window the service is in, empty if none
+func (s *Service) GetMaintenance() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.maintenance
+}
+
+// sets the open maintenance window the service is in, empty when there is none.
+// the service is administratively down while it is in maintenance, but its checks keep running
+func (s *Service) SetMaintenance(windowID string) {
+	s.updateHealth(func() {
+		if s.maintenance == windowID {
+			return
+		}
+
+		s.maintenance = windowID
+		if windowID != "" {
+			bslog.Info("service is in maintenance", slog.Any("service", s), slog.String("window", windowID))
+		} else {
+			bslog.Info("service is out of maintenance", slog.Any("service", s))
+		}
+	})
 }
 
 func (s *Service) GetPriority() int {
 	return s.priority
 }
 
+// returns the first address of the service, the IPv4 address of a dual-stack service
 func (s *Service) GetIP() string {
-	return s.addr.IP.String()
+	return s.addrs[0].IP.String()
+}
+
+// returns every address of the service
+func (s *Service) GetIPs() []string {
+	ips := make([]string, 0, len(s.addrs))
+	for _, addr := range s.addrs {
+		ips = append(ips, addr.IP.String())
+	}
+	return ips
 }
 
 func (s *Service) GetDefaultInterval() timesutil.Duration {
@@ -248,21 +405,78 @@ func (s *Service) GetID() string {
 	return s.id
 }
 
+// returns the service group mode requested in the GSLB - config, empty when not set
+func (s *Service) GetGroupMode() string {
+	return s.groupMode
+}
+
+// returns the share of the traffic the service gets in a weighted service group
+func (s *Service) GetWeight() int {
+	return s.weight
+}
+
 func (s *Service) GetFailureCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.failureCount
 }
 
+// returns when the last health check completed, zero if the service has not been checked yet
+func (s *Service) GetLastCheck() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastCheck
+}
+
+// returns the reason the last health check failed, empty if it succeeded
+func (s *Service) GetLastError() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastError
+}
+
 func (s *Service) GetAverageRoundtrip() time.Duration {
 	return s.checker.Roundtrip()
 }
 
+// the type of the check of the service, TCP-FULL unless another type is configured
+func (s *Service) GetCheckType() string {
+	if s.checkType == "" {
+		return checks.TCP_FULL
+	}
+	return s.checkType
+}
+
+// results of every check of a COMPOSITE check, after its last check
+func (s *Service) GetCheckResults() []checks.Result {
+	if reporter, ok := s.checker.(checks.ResultReporter); ok {
+		return reporter.Results()
+	}
+	return nil
+}
+
+// when the certificate of the service expires, zero if its check does not connect with TLS
+func (s *Service) GetCertificateExpiry() time.Time {
+	if checker, ok := s.checker.(checks.CertificateChecker); ok {
+		return checker.CertificateExpiry()
+	}
+	return time.Time{}
+}
+
 func (s *Service) ConfigChanged(other *Service) bool {
 	if s.Fqdn != other.Fqdn ||
-		s.addr.String() != other.addr.String() ||
+		!slices.Equal(s.GetIPs(), other.GetIPs()) ||
+		s.addrs[0].Port != other.addrs[0].Port ||
 		s.Datacenter != other.Datacenter ||
 		s.FailureThreshold != other.FailureThreshold ||
 		s.priority != other.priority ||
-		s.checkType != other.checkType {
+		s.checkType != other.checkType ||
+		s.groupMode != other.groupMode ||
+		!s.check.Equal(other.check) ||
+		!slices.EqualFunc(s.subChecks, other.subChecks, model.CheckConfig.Equal) ||
+		s.checkQuorum != other.checkQuorum ||
+		!slices.Equal(s.dependsOn, other.dependsOn) ||
+		s.weight != other.weight {
 		return true
 	}
 	return false
@@ -270,12 +484,20 @@ func (s *Service) ConfigChanged(other *Service) bool {
 
 // updates the configuration values of s with the values of new
 func (s *Service) Assign(new *Service) {
-	s.addr = new.addr
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addrs = new.addrs
 	s.Fqdn = new.Fqdn
 	s.checker = new.checker
 	s.MemberOf = new.MemberOf
 	s.priority = new.priority
 	s.checkType = new.checkType
+	s.groupMode = new.groupMode
+	s.check = new.check
+	s.subChecks = new.subChecks
+	s.checkQuorum = new.checkQuorum
+	s.dependsOn = new.dependsOn
+	s.weight = new.weight
 	s.Datacenter = new.Datacenter
 	s.defaultInterval = new.defaultInterval
 	s.FailureThreshold = new.FailureThreshold
@@ -291,17 +513,20 @@ func (s *Service) LogValue() slog.Value {
 		slog.String("memberOf", s.MemberOf),
 		slog.String("fqdn", s.Fqdn),
 		slog.String("datacenter", s.Datacenter),
-		slog.String("ip", s.GetIP()),
+		slog.String("ip", strings.Join(s.GetIPs(), ",")),
 	)
 }
 
 // satisfies the stringer interface to allow passing s for %v in formatted strings
 func (s *Service) String() string {
-	return fmt.Sprintf("%s:%s:%s:%s:%s", s.id, s.MemberOf, s.Fqdn, s.Datacenter, s.GetIP())
+	return fmt.Sprintf("%s:%s:%s:%s:%s", s.id, s.MemberOf, s.Fqdn, s.Datacenter, strings.Join(s.GetIPs(), ","))
 }
 
 func (s *Service) GSLBService() *model.GSLBService {
-	return &model.GSLBService{
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	gslbService := &model.GSLBService{
 		ID:           s.id,
 		MemberOf:     s.MemberOf,
 		Fqdn:         s.Fqdn,
@@ -310,4 +535,185 @@ func (s *Service) GSLBService() *model.GSLBService {
 		IsHealthy:    s.isHealthy,
 		FailureCount: s.failureCount,
 	}
+
+	if len(s.unmetDependencies) > 0 {
+		gslbService.UnmetDependencies = s.unmetDependencies
+	}
+	gslbService.Maintenance = s.maintenance
+	if len(s.addrs) > 1 {
+		gslbService.IPs = s.GetIPs()
+	}
+	if s.groupMode == model.GROUP_MODE_WEIGHTED {
+		gslbService.Weight = s.weight
+	}
+	return gslbService
+}
+
+// creates the checker of a single check of the service
+func (s *Service) newChecker(check model.CheckConfig) (checks.Checker, error) {
+	addrs := make([]string, 0, len(s.addrs))
+	for _, addr := range s.addrs {
+		port := strconv.Itoa(addr.Port)
+		if check.Port != "" {
+			port = check.Port
+		}
+		addrs = append(addrs, net.JoinHostPort(addr.IP.String(), port))
+	}
+
+	switch checkType := strings.ToUpper(check.CheckType); checkType {
+	case checks.HTTPS, checks.HTTP:
+		request := httpRequest(check, s.Fqdn, strings.ToLower(checkType))
+		if err := request.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidHTTPCheck, err.Error())
+		}
+		var errs error // the TLS files can change after the request is validated
+		checker := perAddress(addrs, func(addr string) checks.Checker {
+			checker, err := checks.NewHTTPChecker(addr, request, checks.DEFAULT_TIMEOUT, check.Script)
+			errs = errors.Join(errs, err)
+			return checker
+		})
+		if errs != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidHTTPCheck, errs.Error())
+		}
+		return checker, nil
+
+	case checks.TCP_HALF:
+		return perAddress(addrs, tcpChecker(checks.NewTCPHalfChecker)), nil
+
+	case checks.DNS:
+		query := dnsQuery(check, s.Fqdn)
+		if err := query.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidDNSCheck, err.Error())
+		}
+		return perAddress(addrs, func(addr string) checks.Checker {
+			checker, _ := checks.NewDNSChecker(addr, checks.DEFAULT_TIMEOUT, query) // the query is validated above
+			return checker
+		}), nil
+
+	case checks.LUA:
+		if check.Script == "" {
+			return nil, ErrMissingScript
+		}
+		return perAddress(addrs, func(addr string) checks.Checker {
+			return checks.NewLuaChecker(addr, s.Fqdn, check.Script, checks.DEFAULT_TIMEOUT)
+		}), nil
+
+	case checks.GRPC:
+		var options *checks.TLSOptions
+		if check.GRPC.TLS {
+			tlsOpts := tlsOptions(check.GRPC.TLSOptions)
+			options = &tlsOpts
+		}
+		var errs error // the TLS files can change after the check is validated
+		checker := perAddress(addrs, func(addr string) checks.Checker {
+			checker, err := checks.NewGRPCChecker(addr, check.GRPC.Service, s.Fqdn, options, checks.DEFAULT_TIMEOUT)
+			errs = errors.Join(errs, err)
+			return checker
+		})
+		if errs != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidGRPCCheck, errs.Error())
+		}
+		return checker, nil
+
+	default:
+		return perAddress(addrs, tcpChecker(checks.NewTCPFullChecker)), nil
+	}
+}
+
+// creates the checker of a COMPOSITE check, that runs every check of the service
+func (s *Service) newCompositeChecker() (checks.Checker, error) {
+	subChecks := make([]checks.SubCheck, 0, len(s.subChecks))
+	for _, check := range s.subChecks {
+		checker, err := s.newChecker(check)
+		if err != nil {
+			return nil, fmt.Errorf("check %s: %w", check.GetName(), err)
+		}
+		subChecks = append(subChecks, checks.SubCheck{Name: check.GetName(), Checker: checker})
+	}
+
+	return checks.NewCompositeChecker(s.checkQuorum, subChecks...), nil
+}
+
+// how many of the checks of a COMPOSITE check must succeed
+func checkQuorum(config model.GSLBConfig) int {
+	switch strings.ToUpper(config.CheckMode) {
+	case model.CHECK_MODE_ANY:
+		return 1
+	case model.CHECK_MODE_QUORUM:
+		return config.CheckQuorum
+	default:
+		return len(config.Checks)
+	}
+}
+
+// health-checks every address of the service, a dual-stack service is only healthy if both its addresses are
+func perAddress(addrs []string, newChecker func(addr string) checks.Checker) checks.Checker {
+	if len(addrs) == 1 {
+		return newChecker(addrs[0])
+	}
+
+	checkers := make([]checks.Checker, 0, len(addrs))
+	for _, addr := range addrs {
+		checkers = append(checkers, newChecker(addr))
+	}
+	return checks.NewDualStackChecker(checkers...)
+}
+
+func tcpChecker(newChecker func(addr string, timeout time.Duration) checks.Checker) func(addr string) checks.Checker {
+	return func(addr string) checks.Checker {
+		return newChecker(addr, checks.DEFAULT_TIMEOUT)
+	}
+}
+
+// returns the request of a HTTP or HTTPS check, sent to the fqdn of the service unless another host is configured
+func httpRequest(check model.CheckConfig, fqdn, scheme string) checks.HTTPRequest {
+	request := checks.HTTPRequest{
+		Scheme:         scheme,
+		Host:           check.HTTP.Host,
+		SNI:            check.HTTP.SNI,
+		Method:         strings.ToUpper(check.HTTP.Method),
+		Path:           check.HTTP.Path,
+		Headers:        check.HTTP.Headers,
+		Body:           check.HTTP.Body,
+		ExpectedStatus: check.HTTP.ExpectedStatus,
+		TLS:            tlsOptions(check.HTTP.TLS),
+	}
+	if request.Host == "" {
+		request.Host = fqdn
+	}
+
+	return request
+}
+
+// returns the TLS options of a check
+func tlsOptions(config model.TLSCheckConfig) checks.TLSOptions {
+	return checks.TLSOptions{
+		Verify:        config.Verify,
+		CAFile:        config.CAFile,
+		CertFile:      config.CertFile,
+		KeyFile:       config.KeyFile,
+		ExpiryWarning: time.Duration(config.ExpiryWarning),
+		ExpiryFailure: time.Duration(config.ExpiryFailure),
+	}
+}
+
+// returns the query of a DNS check, the fqdn of the service is queried unless another name is configured
+func dnsQuery(check model.CheckConfig, fqdn string) checks.DNSQuery {
+	query := checks.DNSQuery{
+		Name:  check.DNS.Name,
+		Type:  check.DNS.Type,
+		Net:   check.DNS.Net,
+		Rcode: check.DNS.Rcode,
+	}
+	if query.Name == "" {
+		query.Name = fqdn
+	}
+
+	for answer := range strings.SplitSeq(check.DNS.Answers, ",") {
+		if answer = strings.TrimSpace(answer); answer != "" {
+			query.Answers = append(query.Answers, answer)
+		}
+	}
+
+	return query
 }
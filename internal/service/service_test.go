@@ -2,6 +2,10 @@ package service
 
 import (
 	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -12,7 +16,7 @@ import (
 
 type Test struct {
 	Name            string
-	InputService    Service
+	InputService    *Service
 	ExpectedHealthy bool
 	FailureCount    int
 }
@@ -45,7 +49,7 @@ func TestCalculateInterval(t *testing.T) {
 }
 
 func TestOnSuccess(t *testing.T) {
-	svc0 := Service{
+	svc0 := &Service{
 		failureCount: 0,
 		healthChangeCallback: func(health bool) {
 
@@ -53,7 +57,7 @@ func TestOnSuccess(t *testing.T) {
 		FailureThreshold: 3,
 		isHealthy:        false,
 	}
-	svc1 := Service{
+	svc1 := &Service{
 		failureCount: 1,
 		healthChangeCallback: func(health bool) {
 
@@ -61,7 +65,7 @@ func TestOnSuccess(t *testing.T) {
 		FailureThreshold: 3,
 		isHealthy:        false,
 	}
-	svc2 := Service{
+	svc2 := &Service{
 		failureCount: 2,
 		healthChangeCallback: func(health bool) {
 
@@ -69,7 +73,7 @@ func TestOnSuccess(t *testing.T) {
 		FailureThreshold: 3,
 		isHealthy:        false,
 	}
-	svc3 := Service{
+	svc3 := &Service{
 		failureCount: 3,
 		healthChangeCallback: func(health bool) {
 
@@ -77,7 +81,7 @@ func TestOnSuccess(t *testing.T) {
 		FailureThreshold: 3,
 		isHealthy:        false,
 	}
-	svc4 := Service{
+	svc4 := &Service{
 		failureCount: 0,
 		healthChangeCallback: func(health bool) {
 
@@ -148,7 +152,7 @@ func TestOnSuccess(t *testing.T) {
 }
 
 func TestOnFailure(t *testing.T) {
-	svc0 := Service{
+	svc0 := &Service{
 		failureCount: 0,
 		healthChangeCallback: func(health bool) {
 
@@ -156,7 +160,7 @@ func TestOnFailure(t *testing.T) {
 		FailureThreshold: 3,
 		isHealthy:        true,
 	}
-	svc1 := Service{
+	svc1 := &Service{
 		failureCount: 1,
 		healthChangeCallback: func(health bool) {
 
@@ -164,7 +168,7 @@ func TestOnFailure(t *testing.T) {
 		FailureThreshold: 3,
 		isHealthy:        true,
 	}
-	svc2 := Service{
+	svc2 := &Service{
 		failureCount: 2,
 		healthChangeCallback: func(health bool) {
 
@@ -172,7 +176,7 @@ func TestOnFailure(t *testing.T) {
 		FailureThreshold: 3,
 		isHealthy:        true,
 	}
-	svc3 := Service{
+	svc3 := &Service{
 		failureCount: 3,
 		healthChangeCallback: func(health bool) {
 
@@ -180,7 +184,7 @@ func TestOnFailure(t *testing.T) {
 		FailureThreshold: 3,
 		isHealthy:        true,
 	}
-	svc4 := Service{
+	svc4 := &Service{
 		failureCount: 0,
 		healthChangeCallback: func(health bool) {
 
@@ -335,3 +339,110 @@ func TestService_GetBaseInterval(t *testing.T) {
 		})
 	}
 }
+
+func TestNewServiceFromGSLBConfig_DualStack(t *testing.T) {
+	svc, err := NewServiceFromGSLBConfig(model.GSLBConfig{
+		ServiceID:  "dual",
+		MemberOf:   "app.example.com",
+		Ip:         "10.0.0.1",
+		Ipv6:       "2001:db8::1",
+		Port:       "443",
+		Datacenter: "DC1",
+		Interval:   timesutil.Duration(time.Second * 5),
+		CheckType:  checks.TCP_FULL,
+	})
+	if err != nil {
+		t.Fatalf("could not create dual-stack service: %v", err)
+	}
+
+	if _, ok := svc.checker.(*checks.DualStackChecker); !ok {
+		t.Errorf("expected both addresses to be health-checked, got: %T", svc.checker)
+	}
+
+	spoof := svc.GSLBService().Spoof()
+	if addresses := spoof.Addresses(); len(addresses) != 2 || addresses[0] != "10.0.0.1" || addresses[1] != "2001:db8::1" {
+		t.Errorf("expected the spoof to answer with both addresses, got: %v", addresses)
+	}
+}
+
+func TestNewServiceFromGSLBConfig_Composite(t *testing.T) {
+	config := model.GSLBConfig{
+		ServiceID:  "composite",
+		Fqdn:       "app.example.com",
+		MemberOf:   "app.example.com",
+		Ip:         "10.0.0.1",
+		Port:       "443",
+		Datacenter: "DC1",
+		Interval:   timesutil.Duration(time.Second * 5),
+		CheckType:  checks.HTTPS,
+		Checks: []model.CheckConfig{
+			{CheckType: checks.TCP_FULL},
+			{Name: "healthz", CheckType: checks.HTTP, Port: "8080", HTTP: model.HTTPCheckConfig{Path: "/healthz"}},
+		},
+		CheckMode: model.CHECK_MODE_ANY,
+	}
+
+	svc, err := NewServiceFromGSLBConfig(config)
+	if err != nil {
+		t.Fatalf("could not create composite service: %v", err)
+	}
+	if svc.GetCheckType() != checks.COMPOSITE || svc.checkQuorum != 1 {
+		t.Errorf("expected a composite check where any check must succeed, got: %s with quorum %d", svc.GetCheckType(), svc.checkQuorum)
+	}
+	if _, ok := svc.checker.(*checks.CompositeChecker); !ok {
+		t.Errorf("expected the checks to be combined, got: %T", svc.checker)
+	}
+
+	config.Checks[1].HTTP.Path = "/ready"
+	changed, err := NewServiceFromGSLBConfig(config)
+	if err != nil {
+		t.Fatalf("could not create composite service: %v", err)
+	}
+	if !svc.ConfigChanged(changed) {
+		t.Error("expected a changed check of the composite check to change the config")
+	}
+}
+
+func TestService_ConcurrentHealthChanges(t *testing.T) {
+	svc := &Service{
+		addrs:            []*net.TCPAddr{{IP: net.IPv4(10, 0, 0, 1), Port: 443}},
+		FailureThreshold: 1,
+		failureCount:     1,
+	}
+	var healthy atomic.Bool
+	svc.SetHealthChangeCallback(func(h bool) {
+		// the callback runs after the service is unlocked, so it can read the service
+		if svc.IsHealthy() != h {
+			t.Errorf("expected the service to report the health it was called with: %v", h)
+		}
+		healthy.Store(h)
+	})
+
+	wg := sync.WaitGroup{}
+	wg.Go(func() {
+		for range 100 {
+			svc.OnSuccess()
+			svc.OnFailure(errors.New("connection refused"))
+		}
+		svc.OnSuccess()
+	})
+	wg.Go(func() {
+		for i := range 100 {
+			svc.SetMaintenance("window")
+			svc.SetMaintenance("")
+			svc.SetUnmetDependencies([]string{"db" + strconv.Itoa(i)})
+			svc.SetUnmetDependencies(nil)
+		}
+	})
+	wg.Go(func() {
+		for range 100 {
+			svc.IsHealthy()
+			svc.GSLBService()
+		}
+	})
+	wg.Wait()
+
+	if !svc.IsHealthy() || !healthy.Load() {
+		t.Errorf("expected the service to end up healthy, and the callback to be told so")
+	}
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/checks"
+	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/pkg/lua"
+)
+
+// ValidateGSLBConfig checks every field of the config, and returns all the reasons it is not valid joined together.
+// a config that passes can be registered as a service.
+func ValidateGSLBConfig(config model.GSLBConfig) error {
+	errs := make([]error, 0)
+
+	if config.ServiceID == "" {
+		errs = append(errs, ErrEmptyServiceId)
+	}
+
+	if err := validateFqdn(config.Fqdn); err != nil {
+		errs = append(errs, err)
+	}
+
+	ip := net.ParseIP(config.Ip)
+	if ip == nil {
+		errs = append(errs, fmt.Errorf("%w: %q", ErrUnableToParseIpAddr, config.Ip))
+	}
+
+	if config.Ipv6 != "" {
+		ipv6 := net.ParseIP(config.Ipv6)
+		if ipv6 == nil || ipv6.To4() != nil || (ip != nil && ip.To4() == nil) {
+			errs = append(errs, fmt.Errorf("%w: ip: %q, ipv6: %q", ErrInvalidDualStack, config.Ip, config.Ipv6))
+		}
+	}
+
+	if port, err := strconv.Atoi(config.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("%w: %q, expected 1-65535", ErrInvalidPort, config.Port))
+	}
+
+	interval := time.Duration(config.Interval)
+	if interval < checks.MIN_CHECK_INTERVAL || interval > checks.MAX_CHECK_INTERVAL {
+		errs = append(errs, fmt.Errorf("%w: %s, expected %s-%s", ErrIntervalOutOfRange, interval, checks.MIN_CHECK_INTERVAL, checks.MAX_CHECK_INTERVAL))
+	}
+
+	if config.Priority < 0 {
+		errs = append(errs, ErrNegativePriority)
+	}
+
+	if config.Weight < 0 {
+		errs = append(errs, ErrNegativeWeight)
+	}
+
+	if len(config.Checks) == 0 {
+		errs = append(errs, validateCheck(config.Check(), config.Fqdn)...)
+	} else {
+		errs = append(errs, validateCompositeCheck(config)...)
+	}
+
+	for _, dependency := range config.DependsOn {
+		switch {
+		case dependency == "":
+			errs = append(errs, fmt.Errorf("%w: empty service group", ErrInvalidDependency))
+		case dependency == config.MemberOf:
+			errs = append(errs, fmt.Errorf("%w: %q can not depend on itself", ErrInvalidDependency, dependency))
+		}
+	}
+
+	switch strings.ToUpper(config.GroupMode) {
+	case "", model.GROUP_MODE_ROUNDTRIP, model.GROUP_MODE_MULTI, model.GROUP_MODE_WEIGHTED:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", ErrUnknownGroupMode, config.GroupMode))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateCheck(check model.CheckConfig, fqdn string) []error {
+	errs := make([]error, 0)
+	switch strings.ToUpper(check.CheckType) {
+	case "", checks.TCP_FULL, checks.TCP_HALF:
+	case checks.GRPC:
+		if check.GRPC.TLS {
+			if err := tlsOptions(check.GRPC.TLSOptions).Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidGRPCCheck, err.Error()))
+			}
+		}
+	case checks.HTTP, checks.HTTPS:
+		if err := httpRequest(check, fqdn, strings.ToLower(check.CheckType)).Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidHTTPCheck, err.Error()))
+		}
+	case checks.LUA:
+		if check.Script == "" {
+			errs = append(errs, ErrMissingScript)
+		}
+	case checks.DNS:
+		if err := dnsQuery(check, fqdn).Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidDNSCheck, err.Error()))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", ErrUnknownCheckType, check.CheckType))
+	}
+
+	if check.Script != "" {
+		if err := lua.CheckSyntax(check.Script); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidScript, err.Error()))
+		}
+	}
+
+	return errs
+}
+
+// the checks of a COMPOSITE check must have unique names, and its quorum must be reachable.
+// the check type of the service is ignored when it has checks
+func validateCompositeCheck(config model.GSLBConfig) []error {
+	errs := make([]error, 0)
+	switch strings.ToUpper(config.CheckMode) {
+	case "", model.CHECK_MODE_ALL, model.CHECK_MODE_ANY:
+	case model.CHECK_MODE_QUORUM:
+		if config.CheckQuorum < 1 || config.CheckQuorum > len(config.Checks) {
+			errs = append(errs, fmt.Errorf("%w: quorum %d, expected 1-%d", ErrInvalidCompositeCheck, config.CheckQuorum, len(config.Checks)))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%w: unknown check mode %q", ErrInvalidCompositeCheck, config.CheckMode))
+	}
+
+	names := make(map[string]bool, len(config.Checks))
+	for _, check := range config.Checks {
+		name := check.GetName()
+		if names[name] {
+			errs = append(errs, fmt.Errorf("%w: duplicate check name %q", ErrInvalidCompositeCheck, name))
+		}
+		names[name] = true
+
+		if strings.EqualFold(check.CheckType, checks.COMPOSITE) {
+			errs = append(errs, fmt.Errorf("%w: check %q can not be %s", ErrInvalidCompositeCheck, name, checks.COMPOSITE))
+			continue
+		}
+
+		if port, err := strconv.Atoi(check.Port); check.Port != "" && (err != nil || port < 1 || port > 65535) {
+			errs = append(errs, fmt.Errorf("%w: check %q: %q, expected 1-65535", ErrInvalidPort, name, check.Port))
+		}
+
+		for _, err := range validateCheck(check, config.Fqdn) {
+			errs = append(errs, fmt.Errorf("check %q: %w", name, err))
+		}
+	}
+
+	return errs
+}
+
+// a fully qualified domain name, with or without the trailing dot
+func validateFqdn(fqdn string) error {
+	name := strings.TrimSuffix(fqdn, ".")
+	if name == "" || len(name) > 253 {
+		return fmt.Errorf("%w: %q", ErrInvalidFqdn, fqdn)
+	}
+
+	for label := range strings.SplitSeq(name, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("%w: %q", ErrInvalidFqdn, fqdn)
+		}
+
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' {
+				return fmt.Errorf("%w: %q", ErrInvalidFqdn, fqdn)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/internal/utils/timesutil"
+)
+
+func TestValidateGSLBConfig(t *testing.T) {
+	valid := model.GSLBConfig{
+		ServiceID: "svc-1",
+		Fqdn:      "app.example.com",
+		MemberOf:  "app.example.com",
+		Ip:        "10.0.0.1",
+		Port:      "443",
+		Interval:  timesutil.Duration(time.Second * 10),
+		CheckType: "https",
+		Script:    "return true",
+	}
+
+	tests := []struct {
+		name   string
+		modify func(config *model.GSLBConfig)
+		want   []error
+	}{
+		{"valid", func(config *model.GSLBConfig) {}, nil},
+		{"fqdn with trailing dot", func(config *model.GSLBConfig) { config.Fqdn = "app.example.com." }, nil},
+		{"ipv6", func(config *model.GSLBConfig) { config.Ip = "2001:db8::1" }, nil},
+		{"dual-stack", func(config *model.GSLBConfig) { config.Ipv6 = "2001:db8::1" }, nil},
+		{"dual-stack without IPv6", func(config *model.GSLBConfig) { config.Ipv6 = "10.0.0.2" }, []error{ErrInvalidDualStack}},
+		{"unknown check type", func(config *model.GSLBConfig) { config.CheckType = "ICMP" }, []error{ErrUnknownCheckType}},
+		{"http check", func(config *model.GSLBConfig) {
+			config.CheckType = "https"
+			config.HTTP = model.HTTPCheckConfig{Method: "head", Path: "/healthz", ExpectedStatus: []int{200, 204}}
+		}, nil},
+		{"http check with invalid status", func(config *model.GSLBConfig) {
+			config.CheckType = "HTTP"
+			config.HTTP = model.HTTPCheckConfig{Path: "healthz", ExpectedStatus: []int{0}}
+		}, []error{ErrInvalidHTTPCheck}},
+		{"composite check", func(config *model.GSLBConfig) {
+			config.Checks = []model.CheckConfig{
+				{CheckType: "tcp-full", Port: "443"},
+				{CheckType: "http", Port: "80", HTTP: model.HTTPCheckConfig{Path: "/healthz"}},
+				{CheckType: "dns", Port: "53"},
+			}
+			config.CheckMode = "quorum"
+			config.CheckQuorum = 2
+		}, nil},
+		{"composite check with unreachable quorum", func(config *model.GSLBConfig) {
+			config.Checks = []model.CheckConfig{{CheckType: "tcp-full"}, {CheckType: "tcp-half"}}
+			config.CheckMode = "QUORUM"
+			config.CheckQuorum = 3
+		}, []error{ErrInvalidCompositeCheck}},
+		{"composite check with duplicate names", func(config *model.GSLBConfig) {
+			config.Checks = []model.CheckConfig{{CheckType: "tcp-full"}, {Port: "8443"}, {CheckType: "ICMP", Port: "0"}}
+		}, []error{ErrInvalidCompositeCheck, ErrUnknownCheckType, ErrInvalidPort}},
+		{"dependency", func(config *model.GSLBConfig) { config.DependsOn = []string{"db.example.com"} }, nil},
+		{"dependency on itself", func(config *model.GSLBConfig) { config.DependsOn = []string{config.MemberOf} }, []error{ErrInvalidDependency}},
+		{"lua check", func(config *model.GSLBConfig) { config.CheckType = "lua" }, nil},
+		{"lua check without script", func(config *model.GSLBConfig) {
+			config.CheckType = "LUA"
+			config.Script = ""
+		}, []error{ErrMissingScript}},
+		{"dns check", func(config *model.GSLBConfig) {
+			config.CheckType = "dns"
+			config.DNS = model.DNSCheckConfig{Type: "AAAA", Net: "tcp"}
+		}, nil},
+		{"dns check with unknown qtype", func(config *model.GSLBConfig) {
+			config.CheckType = "DNS"
+			config.DNS = model.DNSCheckConfig{Type: "NOPE"}
+		}, []error{ErrInvalidDNSCheck}},
+		{"interval too short", func(config *model.GSLBConfig) { config.Interval = timesutil.Duration(time.Second) }, []error{ErrIntervalOutOfRange}},
+		{"interval missing", func(config *model.GSLBConfig) { config.Interval = 0 }, []error{ErrIntervalOutOfRange}},
+		{"negative priority", func(config *model.GSLBConfig) { config.Priority = -1 }, []error{ErrNegativePriority}},
+		{"port out of range", func(config *model.GSLBConfig) { config.Port = "65536" }, []error{ErrInvalidPort}},
+		{"invalid fqdn", func(config *model.GSLBConfig) { config.Fqdn = "app..example.com" }, []error{ErrInvalidFqdn}},
+		{"script does not compile", func(config *model.GSLBConfig) { config.Script = "return (" }, []error{ErrInvalidScript}},
+		{"every reason is reported", func(config *model.GSLBConfig) {
+			config.Ip = "10.0.0"
+			config.Port = "http"
+		}, []error{ErrUnableToParseIpAddr, ErrInvalidPort}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			tt.modify(&config)
+
+			err := ValidateGSLBConfig(config)
+			if len(tt.want) == 0 && err != nil {
+				t.Fatalf("expected config to be valid, got: %v", err)
+			}
+			for _, want := range tt.want {
+				if !errors.Is(err, want) {
+					t.Errorf("expected %v, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package source
+
+import "errors"
+
+var (
+	ErrUnsupportedFormat  = errors.New("unsupported config file format")
+	ErrMissingServiceID   = errors.New("GSLB - config is missing service id")
+	ErrDuplicateServiceID = errors.New("service id is defined more than once")
+)
@@ -0,0 +1,303 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/internal/service"
+	"go.yaml.in/yaml/v2"
+)
+
+const DEFAULT_DIRECTORY_POLL_INTERVAL = time.Second * 10
+
+// FileSource reads GSLB - configs from a single JSON or YAML file once, when started.
+type FileSource struct {
+	path string
+}
+
+func NewFileSource(path string) *FileSource {
+	return &FileSource{
+		path: path,
+	}
+}
+
+func (f *FileSource) Name() string {
+	return "file:" + f.path
+}
+
+func (f *FileSource) Start(ctx context.Context) (<-chan Update, <-chan error) {
+	updates := make(chan Update)
+	errs := make(chan error)
+
+	go func() {
+		defer close(updates)
+		defer close(errs)
+
+		configs, err := ReadConfigFile(f.path)
+		if err != nil {
+			send(ctx, errs, err)
+		} else {
+			valid, rejected := Validate(f.Name(), recordsOf(f.path, configs))
+			send(ctx, updates, Update{Source: f.Name(), Kind: SNAPSHOT, Configs: valid, Rejections: rejected})
+		}
+
+		<-ctx.Done()
+	}()
+
+	return updates, errs
+}
+
+// DirectorySource reads GSLB - configs from every JSON and YAML file in a directory,
+// and emits a new snapshot when files are added, changed or removed.
+// If any file is invalid, the last valid snapshot is kept until it is fixed.
+type DirectorySource struct {
+	dir      string
+	interval time.Duration
+}
+
+func NewDirectorySource(dir string, interval time.Duration) *DirectorySource {
+	return &DirectorySource{
+		dir:      dir,
+		interval: interval,
+	}
+}
+
+func (d *DirectorySource) Name() string {
+	return "directory:" + d.dir
+}
+
+func (d *DirectorySource) Start(ctx context.Context) (<-chan Update, <-chan error) {
+	updates := make(chan Update)
+	errs := make(chan error)
+
+	go func() {
+		defer close(updates)
+		defer close(errs)
+
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		lastFingerprint := ""
+		for {
+			fingerprint, err := d.fingerprint()
+			if err != nil {
+				send(ctx, errs, err)
+			} else if fingerprint != lastFingerprint {
+				lastFingerprint = fingerprint
+				records, err := d.read()
+				if err != nil {
+					send(ctx, errs, err)
+				} else {
+					valid, rejected := Validate(d.Name(), records)
+					send(ctx, updates, Update{Source: d.Name(), Kind: SNAPSHOT, Configs: valid, Rejections: rejected})
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return updates, errs
+}
+
+// returns the config files in the directory, sorted by name
+func (d *DirectorySource) files() ([]string, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config directory: %w", err)
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".json", ".yaml", ".yml":
+			files = append(files, filepath.Join(d.dir, entry.Name()))
+		}
+	}
+	slices.Sort(files)
+
+	return files, nil
+}
+
+// returns a string that changes whenever a config file in the directory is added, changed or removed
+func (d *DirectorySource) fingerprint() (string, error) {
+	files, err := d.files()
+	if err != nil {
+		return "", err
+	}
+
+	fingerprint := strings.Builder{}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", fmt.Errorf("could not stat config file: %w", err)
+		}
+		fmt.Fprintf(&fingerprint, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+	}
+
+	return fingerprint.String(), nil
+}
+
+func (d *DirectorySource) read() ([]Record, error) {
+	files, err := d.files()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0)
+	definedIn := make(map[string]string) // service id: file
+	for _, file := range files {
+		fileConfigs, err := ReadConfigFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, config := range fileConfigs {
+			if other, ok := definedIn[config.ServiceID]; ok {
+				return nil, fmt.Errorf("%w: %s is defined in both %s and %s", ErrDuplicateServiceID, config.ServiceID, other, file)
+			}
+			definedIn[config.ServiceID] = file
+		}
+		records = append(records, recordsOf(file, fileConfigs)...)
+	}
+
+	return records, nil
+}
+
+// names every config after the file it is in, and its position in the file
+func recordsOf(file string, configs []model.GSLBConfig) []Record {
+	records := make([]Record, 0, len(configs))
+	for i, config := range configs {
+		records = append(records, Record{Name: fmt.Sprintf("%s[%d]", file, i), Config: config})
+	}
+	return records
+}
+
+// reads the GSLB - configs in a JSON or YAML file
+func ReadConfigFile(path string) ([]model.GSLBConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file: %w", err)
+	}
+
+	configs, err := ParseConfigs(data, filepath.Ext(path))
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file: %s: %w", path, err)
+	}
+
+	return configs, nil
+}
+
+// parses GSLB - configs, as JSON or YAML depending on the file extension.
+// the data is either a single config, or a list of configs.
+// YAML uses the same keys as the JSON in the GSLB - config zone.
+func ParseConfigs(data []byte, ext string) ([]model.GSLBConfig, error) {
+	switch strings.ToLower(ext) {
+	case ".json":
+	case ".yaml", ".yml":
+		var err error
+		data, err = yamlToJSON(data)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, ext)
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+
+	rawConfigs := []json.RawMessage{}
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &rawConfigs); err != nil {
+			return nil, fmt.Errorf("could not parse configs: %w", err)
+		}
+	} else {
+		rawConfigs = append(rawConfigs, data)
+	}
+
+	configs := make([]model.GSLBConfig, 0, len(rawConfigs))
+	for _, raw := range rawConfigs {
+		config := model.GSLBConfig{
+			FailureThreshold: service.DEFAULT_FAILURE_THRESHOLD,
+		}
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return nil, fmt.Errorf("could not parse config: %w", err)
+		}
+
+		if config.ServiceID == "" {
+			return nil, fmt.Errorf("%w: member-of: %s", ErrMissingServiceID, config.MemberOf)
+		}
+		configs = append(configs, config)
+	}
+
+	return configs, nil
+}
+
+// converts YAML to JSON, so configs are parsed the same way no matter the format
+func yamlToJSON(data []byte) ([]byte, error) {
+	var value any
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("could not parse yaml: %w", err)
+	}
+
+	converted, err := jsonCompatible(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(converted)
+}
+
+// yaml decodes maps with keys of any type, json only supports string keys
+func jsonCompatible(value any) (any, error) {
+	switch v := value.(type) {
+	case map[any]any:
+		converted := make(map[string]any, len(v))
+		for key, val := range v {
+			strKey, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("could not parse yaml: key is not a string: %v", key)
+			}
+
+			convertedVal, err := jsonCompatible(val)
+			if err != nil {
+				return nil, err
+			}
+			converted[strKey] = convertedVal
+		}
+		return converted, nil
+
+	case []any:
+		converted := make([]any, len(v))
+		for i, val := range v {
+			convertedVal, err := jsonCompatible(val)
+			if err != nil {
+				return nil, err
+			}
+			converted[i] = convertedVal
+		}
+		return converted, nil
+
+	default:
+		return v, nil
+	}
+}
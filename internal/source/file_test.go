@@ -0,0 +1,121 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/service"
+)
+
+func TestParseConfigs(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		ext     string
+		want    int
+		wantErr error
+	}{
+		{
+			name: "json object",
+			data: `{"service_id": "1", "memberOf": "app.example.com", "ip": "10.0.0.1", "interval": "5s"}`,
+			ext:  ".json",
+			want: 1,
+		},
+		{
+			name: "json list",
+			data: `[{"service_id": "1"}, {"service_id": "2"}]`,
+			ext:  ".json",
+			want: 2,
+		},
+		{
+			name: "yaml list",
+			data: "- service_id: \"1\"\n  memberOf: app.example.com\n  interval: 5s\n  priority: 1\n- service_id: \"2\"\n",
+			ext:  ".yml",
+			want: 2,
+		},
+		{
+			name:    "missing service id",
+			data:    "memberOf: app.example.com\n",
+			ext:     ".yaml",
+			wantErr: ErrMissingServiceID,
+		},
+		{
+			name:    "unsupported format",
+			data:    "service_id = 1",
+			ext:     ".toml",
+			wantErr: ErrUnsupportedFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs, err := ParseConfigs([]byte(tt.data), tt.ext)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseConfigs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(configs) != tt.want {
+				t.Fatalf("ParseConfigs() returned %d configs, want %d", len(configs), tt.want)
+			}
+			for _, config := range configs {
+				if config.FailureThreshold != service.DEFAULT_FAILURE_THRESHOLD {
+					t.Errorf("expected default failure threshold, got: %d", config.FailureThreshold)
+				}
+			}
+		})
+	}
+}
+
+func TestDirectorySource(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatalf("could not write config file: %v", err)
+		}
+	}
+	write("a.json", `{"service_id": "1", "fqdn": "app.example.com", "ip": "10.0.0.1", "port": "443", "interval": "5s"}`)
+	write("b.yaml", "service_id: \"2\"\nfqdn: app.example.com\nip: 10.0.0.2\nport: \"443\"\ninterval: 5s\n")
+	write("ignored.txt", "not a config")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, errs := NewDirectorySource(dir, time.Millisecond*10).Start(ctx)
+
+	if snapshot := <-updates; len(snapshot.Configs) != 2 {
+		t.Fatalf("expected snapshot of 2 configs, got: %+v", snapshot)
+	}
+
+	// a duplicate service id keeps the last valid snapshot
+	write("c.json", `{"service_id": "2"}`)
+	if err := <-errs; !errors.Is(err, ErrDuplicateServiceID) {
+		t.Fatalf("expected duplicate service id error, got: %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "c.json")); err != nil {
+		t.Fatalf("could not remove config file: %v", err)
+	}
+	if snapshot := <-updates; len(snapshot.Configs) != 2 {
+		t.Fatalf("expected snapshot of 2 configs when the duplicate is removed, got: %+v", snapshot)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "a.json")); err != nil {
+		t.Fatalf("could not remove config file: %v", err)
+	}
+	if snapshot := <-updates; len(snapshot.Configs) != 1 || snapshot.Configs[0].ServiceID != "2" {
+		t.Fatalf("expected snapshot without the removed file, got: %+v", snapshot)
+	}
+
+	// an invalid config is rejected, without affecting the others
+	write("d.json", `{"service_id": "3", "fqdn": "app.example.com", "ip": "not an ip", "port": "443", "interval": "5s"}`)
+	snapshot := <-updates
+	if len(snapshot.Configs) != 1 || len(snapshot.Rejections) != 1 {
+		t.Fatalf("expected the invalid config to be rejected, got: %+v", snapshot)
+	}
+	if rejection := snapshot.Rejections[0]; rejection.ServiceID != "3" || rejection.Record != filepath.Join(dir, "d.json")+"[0]" {
+		t.Errorf("expected rejection of the config in d.json, got: %+v", rejection)
+	}
+}
@@ -0,0 +1,176 @@
+package source
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/pkg/bslog"
+	"github.com/vitistack/gslb-operator/pkg/models/rejections"
+)
+
+// MergedSource combines several sources into one, emitting a snapshot of every config on each update.
+// Sources are prioritized in the order they are given:
+// when two sources configure the same ServiceID differently, the first source wins and the conflict is reported.
+type MergedSource struct {
+	sources    []ConfigSource
+	configs    []map[string]model.GSLBConfig // service id: config, for each source
+	rejections [][]rejections.Rejection      // rejected configs, for each source
+	conflicts  map[string]string             // service id: name of the source that lost
+	mu         sync.Mutex
+}
+
+func NewMergedSource(sources ...ConfigSource) *MergedSource {
+	configs := make([]map[string]model.GSLBConfig, len(sources))
+	for i := range configs {
+		configs[i] = make(map[string]model.GSLBConfig)
+	}
+
+	return &MergedSource{
+		sources:    sources,
+		configs:    configs,
+		rejections: make([][]rejections.Rejection, len(sources)),
+		conflicts:  make(map[string]string),
+	}
+}
+
+func (m *MergedSource) Name() string {
+	return "merged"
+}
+
+func (m *MergedSource) Start(ctx context.Context) (<-chan Update, <-chan error) {
+	updates := make(chan Update)
+	errs := make(chan error)
+
+	// the latest merged snapshot is sent by a single goroutine after the lock is released,
+	// so a slow consumer does not block the sources, and the snapshots stay in order.
+	// a snapshot replaced before it was sent is skipped, as the next one holds every config
+	var latest *Update
+	pending := make(chan struct{}, 1)
+
+	wg := sync.WaitGroup{}
+	for idx, src := range m.sources {
+		srcUpdates, srcErrs := src.Start(ctx)
+
+		wg.Go(func() {
+			for update := range srcUpdates {
+				m.mu.Lock()
+				snapshot := m.apply(idx, update)
+				latest = &snapshot
+				m.mu.Unlock()
+
+				select {
+				case pending <- struct{}{}:
+				default: // the sender has not picked up the previous snapshot yet, and will get this one instead
+				}
+			}
+		})
+
+		wg.Go(func() {
+			for err := range srcErrs {
+				send(ctx, errs, fmt.Errorf("config source: %s: %w", src.Name(), err))
+			}
+		})
+	}
+
+	go func() {
+		defer close(updates)
+		for range pending {
+			m.mu.Lock()
+			snapshot := latest
+			latest = nil
+			m.mu.Unlock()
+
+			if snapshot != nil {
+				send(ctx, updates, *snapshot)
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(pending)
+		close(errs)
+	}()
+
+	return updates, errs
+}
+
+// applies the update from the source at idx, and returns a snapshot of the merged configs.
+// expects the caller to hold the lock
+func (m *MergedSource) apply(idx int, update Update) Update {
+	configs := m.configs[idx]
+	switch update.Kind {
+	case SNAPSHOT:
+		clear(configs)
+		fallthrough
+	case UPSERT:
+		for _, config := range update.Configs {
+			configs[config.ServiceID] = config
+		}
+	case DELETE:
+		for _, config := range update.Configs {
+			delete(configs, config.ServiceID)
+		}
+	}
+	m.rejections[idx] = update.Rejections
+
+	return Update{
+		Source:     m.Name(),
+		Kind:       SNAPSHOT,
+		Configs:    m.merge(),
+		Rejections: slices.Concat(m.rejections...),
+	}
+}
+
+// expects the caller to hold the lock
+func (m *MergedSource) merge() []model.GSLBConfig {
+	merged := make(map[string]model.GSLBConfig)
+	owners := make(map[string]int) // service id: index of the source the config is from
+	conflicts := make(map[string]string)
+
+	for idx, configs := range m.configs {
+		for _, id := range slices.Sorted(maps.Keys(configs)) {
+			config := configs[id]
+			owner, claimed := owners[id]
+			if !claimed {
+				merged[id] = config
+				owners[id] = idx
+				continue
+			}
+
+			if merged[id].Equal(config) { // same config from several sources is not a conflict
+				continue
+			}
+
+			loser := m.sources[idx].Name()
+			conflicts[id] = loser
+			if _, known := m.conflicts[id]; !known {
+				bslog.Warn("conflicting GSLB - config for service",
+					slog.String("serviceID", id),
+					slog.String("used", m.sources[owner].Name()),
+					slog.String("ignored", loser),
+				)
+			}
+		}
+	}
+
+	m.conflicts = conflicts
+	configConflicts.Set(float64(len(conflicts)))
+
+	return slices.SortedFunc(maps.Values(merged), func(a, b model.GSLBConfig) int {
+		return cmp.Compare(a.ServiceID, b.ServiceID)
+	})
+}
+
+// returns the service ids that are configured differently by several sources
+func (m *MergedSource) Conflicts() map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.conflicts)
+}
@@ -0,0 +1,78 @@
+package source
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vitistack/gslb-operator/internal/model"
+)
+
+// source emitting the updates sent on its channel
+type fakeSource struct {
+	name    string
+	updates chan Update
+}
+
+func (f *fakeSource) Name() string {
+	return f.name
+}
+
+func (f *fakeSource) Start(ctx context.Context) (<-chan Update, <-chan error) {
+	updates := make(chan Update)
+	errs := make(chan error)
+	go func() {
+		defer close(updates)
+		defer close(errs)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update := <-f.updates:
+				send(ctx, updates, update)
+			}
+		}
+	}()
+	return updates, errs
+}
+
+func testConfig(id, ip string) model.GSLBConfig {
+	return model.GSLBConfig{ServiceID: id, MemberOf: "app.example.com", Ip: ip, Port: "80"}
+}
+
+func TestMergedSource(t *testing.T) {
+	primary := &fakeSource{name: "primary", updates: make(chan Update)}
+	secondary := &fakeSource{name: "secondary", updates: make(chan Update)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	merged := NewMergedSource(primary, secondary)
+	updates, _ := merged.Start(ctx)
+
+	primary.updates <- Update{Kind: SNAPSHOT, Configs: []model.GSLBConfig{testConfig("1", "10.0.0.1"), testConfig("2", "10.0.0.2")}}
+	if snapshot := <-updates; snapshot.Kind != SNAPSHOT || len(snapshot.Configs) != 2 {
+		t.Fatalf("expected snapshot of the primary configs, got: %+v", snapshot)
+	}
+
+	// service 2 conflicts with the primary, service 3 is only in the secondary
+	secondary.updates <- Update{Kind: SNAPSHOT, Configs: []model.GSLBConfig{testConfig("2", "10.0.1.2"), testConfig("3", "10.0.1.3")}}
+	snapshot := <-updates
+	if len(snapshot.Configs) != 3 {
+		t.Fatalf("expected 3 merged configs, got: %+v", snapshot.Configs)
+	}
+	if snapshot.Configs[1].Ip != "10.0.0.2" {
+		t.Errorf("expected the primary source to win the conflict, got: %+v", snapshot.Configs[1])
+	}
+	if conflicts := merged.Conflicts(); conflicts["2"] != "secondary" {
+		t.Errorf("expected conflict on service 2 to be reported, got: %v", conflicts)
+	}
+
+	primary.updates <- Update{Kind: DELETE, Configs: []model.GSLBConfig{{ServiceID: "2"}}}
+	snapshot = <-updates
+	if len(snapshot.Configs) != 3 || snapshot.Configs[1].Ip != "10.0.1.2" {
+		t.Errorf("expected the secondary config to be used when the primary deletes it, got: %+v", snapshot.Configs)
+	}
+	if conflicts := merged.Conflicts(); len(conflicts) != 0 {
+		t.Errorf("expected no conflicts, got: %v", conflicts)
+	}
+}
@@ -0,0 +1,13 @@
+package source
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	configConflicts = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "config_source_conflicts",
+		Help: "Number of services configured differently by several config sources",
+	})
+)
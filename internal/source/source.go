@@ -0,0 +1,60 @@
+// Package source defines where GSLB - configs come from.
+// Every source emits updates of model.GSLBConfig, either as full snapshots or as add/update/delete events,
+// and several sources can be merged into one.
+package source
+
+import (
+	"context"
+
+	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/pkg/models/rejections"
+)
+
+type UpdateKind int
+
+const (
+	SNAPSHOT UpdateKind = iota // Configs are every config of the source, configs not in it no longer exist
+	UPSERT                     // Configs are added or updated
+	DELETE                     // Configs no longer exist, only ServiceID needs to be set
+)
+
+func (k UpdateKind) String() string {
+	switch k {
+	case SNAPSHOT:
+		return "snapshot"
+	case UPSERT:
+		return "upsert"
+	case DELETE:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// change in the GSLB - configs of a source
+type Update struct {
+	Source     string
+	Kind       UpdateKind
+	Configs    []model.GSLBConfig
+	Rejections []rejections.Rejection // every config of the source that is currently rejected, no matter the kind of update
+}
+
+// ConfigSource emits the GSLB - configs of the services that should be health checked.
+type ConfigSource interface {
+	// name of the source, used in logs and conflicts
+	Name() string
+
+	// starts emitting updates until ctx is cancelled, both channels are closed when the source stops.
+	// errors are not fatal, the source keeps running and emits updates when it recovers.
+	Start(ctx context.Context) (<-chan Update, <-chan error)
+}
+
+// sends value on ch, unless ctx is cancelled first
+func send[T any](ctx context.Context, ch chan<- T, value T) bool {
+	select {
+	case ch <- value:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,74 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/model"
+	"github.com/vitistack/gslb-operator/internal/service"
+	"github.com/vitistack/gslb-operator/pkg/models/rejections"
+)
+
+// GSLB - config as it is defined in a source
+type Record struct {
+	Name   string // where the config is defined in the source, e.g. a file or the owner name of a TXT record
+	Config model.GSLBConfig
+	Err    error // reason the record could not be parsed as a config, if any
+}
+
+// Validate returns the configs of the valid records, and the rejections of the others.
+// a service id defined by several records is ambiguous, so every one of them is rejected.
+func Validate(source string, records []Record) ([]model.GSLBConfig, []rejections.Rejection) {
+	definedIn := make(map[string][]string) // service id: records
+	for _, record := range records {
+		if record.Err == nil && record.Config.ServiceID != "" {
+			definedIn[record.Config.ServiceID] = append(definedIn[record.Config.ServiceID], record.Name)
+		}
+	}
+
+	configs := make([]model.GSLBConfig, 0, len(records))
+	rejected := make([]rejections.Rejection, 0)
+	for _, record := range records {
+		err := record.Err
+		if err == nil {
+			err = service.ValidateGSLBConfig(record.Config)
+			if names := definedIn[record.Config.ServiceID]; len(names) > 1 {
+				err = errors.Join(err, fmt.Errorf("%w: %s is defined in %v", ErrDuplicateServiceID, record.Config.ServiceID, names))
+			}
+		}
+
+		if err != nil {
+			rejected = append(rejected, NewRejection(source, record.Name, record.Config, err))
+			continue
+		}
+		configs = append(configs, record.Config)
+	}
+
+	return configs, rejected
+}
+
+// NewRejection reports why the config of a record is not used, every joined error is a reason of its own
+func NewRejection(source, record string, config model.GSLBConfig, err error) rejections.Rejection {
+	return rejections.Rejection{
+		Source:    source,
+		Record:    record,
+		ServiceID: config.ServiceID,
+		MemberOf:  config.MemberOf,
+		Reasons:   reasonsOf(err),
+		Since:     time.Now(),
+	}
+}
+
+func reasonsOf(err error) []string {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []string{err.Error()}
+	}
+
+	reasons := make([]string, 0)
+	for _, err := range joined.Unwrap() {
+		reasons = append(reasons, reasonsOf(err)...)
+	}
+	return reasons
+}
@@ -0,0 +1,43 @@
+package source
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vitistack/gslb-operator/internal/utils/timesutil"
+)
+
+func TestValidate(t *testing.T) {
+	valid := testConfig("1", "10.0.0.1")
+	valid.Fqdn = "app.example.com"
+	valid.Interval = timesutil.Duration(time.Second * 5)
+
+	duplicate := valid
+	duplicate.MemberOf = "other.example.com"
+
+	other := valid
+	other.ServiceID = "2"
+
+	configs, rejected := Validate("test", []Record{
+		{Name: "a", Config: valid},
+		{Name: "b", Config: duplicate},
+		{Name: "c", Config: other},
+		{Name: "d", Err: errors.New("could not parse GSLB - config")},
+	})
+
+	if len(configs) != 1 || configs[0].ServiceID != "2" {
+		t.Fatalf("expected only the config without duplicates to be valid, got: %+v", configs)
+	}
+	if len(rejected) != 3 {
+		t.Fatalf("expected both duplicates and the unparsable record to be rejected, got: %+v", rejected)
+	}
+	for _, rejection := range rejected[:2] {
+		if rejection.ServiceID != "1" || len(rejection.Reasons) != 1 {
+			t.Errorf("expected rejection of the duplicate service id, got: %+v", rejection)
+		}
+	}
+	if rejected[2].Record != "d" || rejected[2].ServiceID != "" {
+		t.Errorf("expected rejection of the unparsable record, got: %+v", rejected[2])
+	}
+}
@@ -17,14 +17,20 @@ func WithTokenValidation(logger *slog.Logger) middleware.MiddlewareFunc {
 			ctx := context.WithValue(r.Context(), "request_method", r.Method)
 			ctx = context.WithValue(ctx, "request_route", r.URL.String())
 
-			resp, err := jwt.Validate(ctx, strings.Split(r.Header.Get("Authorization"), "Bearer")[1])
+			claims, resp, err := jwt.Validate(ctx, strings.Split(r.Header.Get("Authorization"), "Bearer")[1])
 			if err != nil {
 				logger.Error("token-validation failed", slog.String("reason", err.Error()))
 				w.WriteHeader(resp.Code)
 				json.NewEncoder(w).Encode(resp)
 				return
 			}
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "user", claims.Name)))
 		}
 	}
 }
+
+// the name of the caller, set by WithTokenValidation
+func User(ctx context.Context) string {
+	user, _ := ctx.Value("user").(string)
+	return user
+}
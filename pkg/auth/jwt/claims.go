@@ -38,7 +38,8 @@ func getUserClaims(name string) (UserClaims, bool) {
 	return UserClaims{}, false
 }
 
-func Validate(ctx context.Context, tokenString string) (*JWTError, error) {
+// validates the token for the request in ctx, and returns the claims of the caller
+func Validate(ctx context.Context, tokenString string) (*UserClaims, *JWTError, error) {
 	tokenString = strings.Trim(tokenString, " ")
 	token, err := jwt.ParseWithClaims(
 		tokenString,
@@ -54,45 +55,45 @@ func Validate(ctx context.Context, tokenString string) (*JWTError, error) {
 		}),
 	)
 	if err != nil {
-		return Errors[ErrUnAuthorized], fmt.Errorf("invalid token: %w", err)
+		return nil, Errors[ErrUnAuthorized], fmt.Errorf("invalid token: %w", err)
 	}
 
 	requestClaims, ok := token.Claims.(*UserClaims)
 	if !ok {
-		return Errors[ErrUnAuthorized], fmt.Errorf("invalid claims: unable to locate user claims section")
+		return nil, Errors[ErrUnAuthorized], fmt.Errorf("invalid claims: unable to locate user claims section")
 	}
 
 	userClaims, ok := getUserClaims(requestClaims.Name)
 	if !ok {
-		return Errors[ErrForbidden], fmt.Errorf("invalid role: %s not registered as a service role", requestClaims.Name)
+		return nil, Errors[ErrForbidden], fmt.Errorf("invalid role: %s not registered as a service role", requestClaims.Name)
 	}
 
 	method, ok := ctx.Value("request_method").(string)
 	if !ok {
-		return Errors[ErrForbidden], fmt.Errorf("could not parse request method")
+		return nil, Errors[ErrForbidden], fmt.Errorf("could not parse request method")
 	}
 
 	if !slices.Contains(userClaims.AllowedMethods, method) {
-		return Errors[ErrUnAuthorized], fmt.Errorf("not allowed to perform %s action", method)
+		return nil, Errors[ErrUnAuthorized], fmt.Errorf("not allowed to perform %s action", method)
 	}
 
 	route, ok := ctx.Value("request_route").(string)
 	if !ok {
-		return Errors[ErrForbidden], fmt.Errorf("could not parse request route")
+		return nil, Errors[ErrForbidden], fmt.Errorf("could not parse request route")
 	}
 
 	for _, allowedRoute := range userClaims.AllowedRoutes {
 		match, err := regexp.MatchString(allowedRoute, route)
 		if err != nil {
-			return Errors[ErrForbidden], fmt.Errorf("failed to match regex: %w", err)
+			return nil, Errors[ErrForbidden], fmt.Errorf("failed to match regex: %w", err)
 		}
 
 		if match {
-			return nil, nil
+			return requestClaims, nil, nil
 		}
 	}
 
-	return Errors[ErrForbidden], fmt.Errorf("no metrics matched: default deny")
+	return nil, Errors[ErrForbidden], fmt.Errorf("no metrics matched: default deny")
 }
 
 var roleMethod = map[Role][]string{
@@ -134,6 +135,7 @@ var endpointUserClaims = map[Role][]UserClaims{
 			AllowedMethods: roleMethod[RW],
 			AllowedRoutes: []string{
 				fmt.Sprintf("^%s$", routes.OVERRIDE),
+				fmt.Sprintf("^%s/.*$", routes.OVERRIDE),
 			},
 		},
 		{
@@ -0,0 +1,154 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+// Schedule is a parsed cron expression with the five fields minute, hour, day of month, month and day of week,
+// e.g. "0 2 * * 6" for 02:00 every saturday. fields are *, a value, a range a-b, a step */n or a-b/n, or a list of them.
+// like in cron, a day matches either of day of month and day of week when both are restricted.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // bit n is set if value n matches
+	domStar, dowStar              bool
+}
+
+var macros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+type bounds struct {
+	name     string
+	min, max int
+}
+
+var fieldBounds = []bounds{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // sunday is both 0 and 7
+}
+
+func Parse(expr string) (Schedule, error) {
+	if macro, ok := macros[strings.TrimSpace(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != len(fieldBounds) {
+		return Schedule{}, fmt.Errorf("%w: %q: expected %d fields, got %d", ErrInvalidExpression, expr, len(fieldBounds), len(fields))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		var err error
+		if bits[i], err = parseField(field, fieldBounds[i]); err != nil {
+			return Schedule{}, fmt.Errorf("%w: %q: %w", ErrInvalidExpression, expr, err)
+		}
+	}
+
+	dow := bits[4]
+	if dow&(1<<7) != 0 {
+		dow |= 1 // sunday
+	}
+
+	return Schedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     dow,
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for part := range strings.SplitSeq(field, ",") {
+		rng, step, hasStep := strings.Cut(part, "/")
+
+		first, last := b.min, b.max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if first, err = parseValue(from, b); err != nil {
+				return 0, err
+			}
+			last = first
+			if isRange {
+				if last, err = parseValue(to, b); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				last = b.max // a/n is a-max/n
+			}
+			if first > last {
+				return 0, fmt.Errorf("%s: range %s is empty", b.name, rng)
+			}
+		}
+
+		increment := 1
+		if hasStep {
+			var err error
+			if increment, err = strconv.Atoi(step); err != nil || increment < 1 {
+				return 0, fmt.Errorf("%s: invalid step %q", b.name, step)
+			}
+		}
+
+		for value := first; value <= last; value += increment {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(value string, b bounds) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < b.min || n > b.max {
+		return 0, fmt.Errorf("%s: %q is not within %d-%d", b.name, value, b.min, b.max)
+	}
+	return n, nil
+}
+
+// the first time after t the schedule matches, in the location of t.
+// zero if it never matches within five years, e.g. for the 31st of february
+func (s Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,46 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	from := time.Date(2026, time.March, 4, 10, 30, 0, 0, time.UTC) // a wednesday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, time.March, 4, 10, 31, 0, 0, time.UTC)},
+		{"0 2 * * 6", time.Date(2026, time.March, 7, 2, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, time.March, 4, 10, 45, 0, 0, time.UTC)},
+		{"0 9-17/4 * * 1-5", time.Date(2026, time.March, 4, 13, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 0", time.Date(2026, time.March, 8, 0, 0, 0, 0, time.UTC)}, // day of month or sunday
+		{"0 0 * * 7", time.Date(2026, time.March, 8, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("could not parse: %v", err)
+			}
+			if next := schedule.Next(from); !next.Equal(tt.want) {
+				t.Errorf("expected %v, got: %v", tt.want, next)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := Parse(expr); !errors.Is(err, ErrInvalidExpression) {
+			t.Errorf("expected %q to be invalid, got: %v", expr, err)
+		}
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/nacl/secretbox"
@@ -24,7 +26,8 @@ const (
 )
 
 type Client struct {
-	conn    net.Conn //raw connection to configured Host and Port
+	mu      sync.Mutex // one command at a time on the connection
+	conn    net.Conn   //raw connection to configured Host and Port
 	key     [KEY_LEN]byte
 	host    net.IP
 	port    string
@@ -104,7 +107,7 @@ func (c *Client) ensureConnected() error {
 
 // connect does the handshake to initialize the reading and writing nonce
 func (c *Client) connect() error {
-	addr, err := net.ResolveTCPAddr("tcp", fmt.Sprintf("%v:%v", c.host.String(), c.port))
+	addr, err := net.ResolveTCPAddr("tcp", net.JoinHostPort(c.host.String(), c.port))
 	if err != nil {
 		return errors.Join(ErrCouldNotParseAddr, err)
 	}
@@ -146,7 +149,7 @@ func (c *Client) connect() error {
 	copy(c.wNonce[:halfNonce], c.sNonce[:halfNonce])
 	copy(c.wNonce[halfNonce:], c.cNonce[halfNonce:])
 
-	resp, err := c.command("") // test handshake
+	resp, err := c.sendCommand("") // test handshake, caller holds the lock
 	if err != nil {
 		return errors.Join(ErrCouldNotSendCommand, err)
 	}
@@ -190,6 +193,9 @@ func (c *Client) decrypt(data []byte) (string, bool) {
 }
 
 func (c *Client) command(cmd string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if err := c.ensureConnected(); err != nil {
 		return "", err
 	}
@@ -255,9 +261,23 @@ func incrementNonce(nonce *[NONCE_LEN]byte) {
 	binary.BigEndian.PutUint32(nonce[:4], value)
 }
 
-func (c *Client) AddDomainSpoof(ruleName, domain, ip string) error {
-	// addAction(QNameRule('example.com'), SpoofAction({"192.168.1.0"}), {name="example.com:DC"})
-	cmd := fmt.Sprintf("addAction(QNameRule('%v'), SpoofAction({'%s'}, {ttl=3600}), {name='%s'})", domain, ip, ruleName)
+// adds a rule answering queries for domain with every ip.
+// several ips gives a multi-record answer.
+func (c *Client) AddDomainSpoof(ruleName, domain string, ips ...string) error {
+	// addAction(QNameRule('example.com'), SpoofAction({"192.168.1.0", "192.168.1.1"}), {name="example.com:DC"})
+	cmd := fmt.Sprintf("addAction(QNameRule('%v'), SpoofAction({%s}, {ttl=3600}), {name='%s'})", domain, quoteList(ips), ruleName)
+	return Must(c.command(cmd))
+}
+
+// adds a rule answering queries for domain with every ip, but only for the given share of the queries that reach the rule.
+// a probability of 1 or more matches every query, as AddDomainSpoof.
+func (c *Client) AddWeightedDomainSpoof(ruleName, domain string, probability float64, ips ...string) error {
+	if probability >= 1 {
+		return c.AddDomainSpoof(ruleName, domain, ips...)
+	}
+
+	// addAction(AndRule({QNameRule('example.com'), ProbaRule(0.900000)}), SpoofAction({"192.168.1.0"}), {name="example.com:DC@90"})
+	cmd := fmt.Sprintf("addAction(AndRule({QNameRule('%v'), ProbaRule(%f)}), SpoofAction({%s}, {ttl=3600}), {name='%s'})", domain, probability, quoteList(ips), ruleName)
 	return Must(c.command(cmd))
 }
 
@@ -272,3 +292,12 @@ func (c *Client) RmRuleWithIndex(idx int) error {
 func (c *Client) ShowRules() (string, error) {
 	return c.command("showRules()")
 }
+
+// creates a comma separated list of single quoted lua strings
+func quoteList(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, val := range values {
+		quoted = append(quoted, "'"+val+"'")
+	}
+	return strings.Join(quoted, ", ")
+}
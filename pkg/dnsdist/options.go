@@ -28,7 +28,7 @@ func WithHostName(hostname string) clientOption {
 			return fmt.Errorf("DNS - lookup failed: %w", err)
 		}
 
-		c.host = net.IP(ips[0])
+		c.host = net.ParseIP(ips[0])
 		return nil
 	}
 }
@@ -1,51 +1,81 @@
 package lua
 
 import (
-	"runtime"
-	"sync"
+	"errors"
+	"fmt"
+	"time"
 
 	glua "github.com/yuin/gopher-lua"
 )
 
+var ErrNoVM = errors.New("no lua VM available")
+
 type LuaBucket struct {
-	mu  sync.Mutex
-	max int
-	vms chan *glua.LState
+	limits Limits
+	vms    chan *glua.LState
 }
 
-var bucket = LuaBucket{
-	mu:  sync.Mutex{},
-	max: runtime.NumCPU(),
-	vms: make(chan *glua.LState, runtime.NumCPU()),
+var bucket = newBucket(DefaultLimits)
+
+func newBucket(limits Limits) *LuaBucket {
+	return &LuaBucket{
+		limits: limits,
+		vms:    make(chan *glua.LState, limits.PoolSize),
+	}
 }
 
-func (pl *LuaBucket) get() *glua.LState {
-	pl.mu.Lock()
-	defer pl.mu.Unlock()
+func (pl *LuaBucket) fill() {
+	for range pl.limits.PoolSize {
+		pl.vms <- pl.new()
+	}
+}
 
-	return <-pl.vms
+// waits at most PoolWait for a free VM, so a busy pool fails the script instead of blocking its health-check
+func (pl *LuaBucket) get() (*glua.LState, error) {
+	select {
+	case L := <-pl.vms:
+		return L, nil
+	default:
+	}
+
+	timer := time.NewTimer(pl.limits.PoolWait)
+	defer timer.Stop()
+
+	select {
+	case L := <-pl.vms:
+		return L, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("%w: all %d are busy after %s", ErrNoVM, pl.limits.PoolSize, pl.limits.PoolWait)
+	}
 }
 
 func (pl *LuaBucket) new() *glua.LState {
-	L := glua.NewState(glua.Options{
+	return glua.NewState(glua.Options{
 		SkipOpenLibs:        true,
 		IncludeGoStackTrace: true,
-		MinimizeStackMemory: true,
+		CallStackSize:       pl.limits.MaxCallDepth,
+		RegistryMaxSize:     pl.limits.MaxStackSize,
 	})
-	L.SetGlobal("sandbox", (*glua.LTable)(sandBox))
-	return L
 }
 
 func (pl *LuaBucket) put(L *glua.LState) {
-	pl.mu.Lock()
-	defer pl.mu.Unlock()
 	pl.vms <- L
 }
 
+// replaces a VM whose script failed, so nothing it left behind leaks into the next script
+func (pl *LuaBucket) replace(L *glua.LState) {
+	L.Close()
+	pl.vms <- pl.new()
+}
+
+// closes the VMs that are not running a script
 func (pl *LuaBucket) shutdown() {
-	pl.mu.Lock()
-	defer pl.mu.Unlock()
-	for L := range pl.vms {
-		L.Close()
+	for {
+		select {
+		case L := <-pl.vms:
+			L.Close()
+		default:
+			return
+		}
 	}
 }
@@ -0,0 +1,181 @@
+package lua
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	glua "github.com/yuin/gopher-lua"
+)
+
+// helpers every script can use on top of the sandbox:
+//
+//	json.decode(text) -> value | nil, err
+//	json.encode(value) -> text | nil, err
+//	regexp.match(pattern, text) -> bool | nil, err
+//	regexp.find(pattern, text) -> {match, submatches...} | nil, err
+//
+// patterns are RE2, so matching takes linear time in the size of the text.
+// json null decodes to nil, and a table encodes as an array when its keys are 1..n.
+
+// string.rep, that fails when the result would be larger than maxSize
+func stringRep(maxSize int) glua.LGFunction {
+	return func(L *glua.LState) int {
+		str := L.CheckString(1)
+		n := L.CheckInt(2)
+		if n <= 0 || str == "" {
+			L.Push(glua.LString(""))
+			return 1
+		}
+		if n > maxSize/len(str) {
+			L.RaiseError("%s: string.rep of %d bytes, the limit is %d", ErrMemoryLimit, len(str)*n, maxSize)
+			return 0
+		}
+		L.Push(glua.LString(strings.Repeat(str, n)))
+		return 1
+	}
+}
+
+func jsonDecode(maxSize int) glua.LGFunction {
+	return func(L *glua.LState) int {
+		text := L.CheckString(1)
+		if len(text) > maxSize {
+			return fail(L, fmt.Errorf("%w: json of %d bytes, the limit is %d", ErrMemoryLimit, len(text), maxSize))
+		}
+
+		var value any
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return fail(L, err)
+		}
+		L.Push(toLua(L, value))
+		return 1
+	}
+}
+
+func jsonEncode(maxSize int) glua.LGFunction {
+	return func(L *glua.LState) int {
+		value, err := fromLua(L.CheckAny(1), 0)
+		if err != nil {
+			return fail(L, err)
+		}
+
+		text, err := json.Marshal(value)
+		if err != nil {
+			return fail(L, err)
+		}
+		if len(text) > maxSize {
+			return fail(L, fmt.Errorf("%w: json of %d bytes, the limit is %d", ErrMemoryLimit, len(text), maxSize))
+		}
+		L.Push(glua.LString(text))
+		return 1
+	}
+}
+
+func toLua(L *glua.LState, value any) glua.LValue {
+	switch value := value.(type) {
+	case bool:
+		return glua.LBool(value)
+	case float64:
+		return glua.LNumber(value)
+	case string:
+		return glua.LString(value)
+	case []any:
+		table := L.CreateTable(len(value), 0)
+		for i, item := range value {
+			table.RawSetInt(i+1, toLua(L, item)) // keeps the position of the items after a null
+		}
+		return table
+	case map[string]any:
+		table := L.CreateTable(0, len(value))
+		for key, item := range value {
+			table.RawSetString(key, toLua(L, item))
+		}
+		return table
+	default:
+		return glua.LNil
+	}
+}
+
+const maxEncodeDepth = 100
+
+func fromLua(value glua.LValue, depth int) (any, error) {
+	if depth > maxEncodeDepth {
+		return nil, fmt.Errorf("tables nested deeper than %d", maxEncodeDepth)
+	}
+
+	switch value := value.(type) {
+	case *glua.LNilType:
+		return nil, nil
+	case glua.LBool:
+		return bool(value), nil
+	case glua.LNumber:
+		if math.IsInf(float64(value), 0) || math.IsNaN(float64(value)) {
+			return nil, fmt.Errorf("can not encode %s", value)
+		}
+		return float64(value), nil
+	case glua.LString:
+		return string(value), nil
+	case *glua.LTable:
+		if n := value.Len(); n > 0 && isArray(value, n) {
+			array := make([]any, 0, n)
+			for i := 1; i <= n; i++ {
+				item, err := fromLua(value.RawGetInt(i), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, item)
+			}
+			return array, nil
+		}
+
+		object := make(map[string]any)
+		var err error
+		value.ForEach(func(key, item glua.LValue) {
+			if err != nil {
+				return
+			}
+			object[key.String()], err = fromLua(item, depth+1)
+		})
+		return object, err
+	default:
+		return nil, fmt.Errorf("can not encode a %s", value.Type())
+	}
+}
+
+// reports whether the keys of table are exactly 1..n
+func isArray(table *glua.LTable, n int) bool {
+	keys := 0
+	table.ForEach(func(glua.LValue, glua.LValue) { keys++ })
+	return keys == n
+}
+
+func regexpMatch(L *glua.LState) int {
+	re, err := regexp.Compile(L.CheckString(1))
+	if err != nil {
+		return fail(L, err)
+	}
+	L.Push(glua.LBool(re.MatchString(L.CheckString(2))))
+	return 1
+}
+
+func regexpFind(L *glua.LState) int {
+	re, err := regexp.Compile(L.CheckString(1))
+	if err != nil {
+		return fail(L, err)
+	}
+
+	match := re.FindStringSubmatch(L.CheckString(2))
+	if match == nil {
+		L.Push(glua.LNil)
+		return 1
+	}
+
+	result := L.CreateTable(len(match), 0)
+	for _, group := range match {
+		result.Append(glua.LString(group))
+	}
+	L.Push(result)
+	return 1
+}
@@ -0,0 +1,95 @@
+package lua
+
+import (
+	"runtime"
+	"time"
+)
+
+// Limits bound the resources of every script run, and of the pool of VMs scripts run in
+type Limits struct {
+	PoolSize        int           // VMs running scripts at once
+	PoolWait        time.Duration // how long a script waits for a free VM before it fails
+	Timeout         time.Duration // run time of a script that is not bounded by its caller
+	MaxInstructions int64         // lua instructions a script may run, 0 for no limit
+	MaxStackSize    int           // values on the stack of a VM, which bounds the memory of locals and temporaries
+	MaxCallDepth    int           // nested function calls
+	MaxStringSize   int           // bytes of a string built by string.rep or decoded by the helpers
+}
+
+var DefaultLimits = Limits{
+	PoolSize:        runtime.NumCPU(),
+	PoolWait:        100 * time.Millisecond,
+	Timeout:         150 * time.Millisecond,
+	MaxInstructions: 1_000_000,
+	MaxStackSize:    256 * 1024,
+	MaxCallDepth:    200,
+	MaxStringSize:   maxBodySize,
+}
+
+type Option func(l *Limits)
+
+// zero leaves the default
+func WithPoolSize(size int) Option {
+	return func(l *Limits) {
+		if size > 0 {
+			l.PoolSize = size
+		}
+	}
+}
+
+// zero leaves the default
+func WithPoolWait(wait time.Duration) Option {
+	return func(l *Limits) {
+		if wait > 0 {
+			l.PoolWait = wait
+		}
+	}
+}
+
+// zero leaves the default
+func WithTimeout(timeout time.Duration) Option {
+	return func(l *Limits) {
+		if timeout > 0 {
+			l.Timeout = timeout
+		}
+	}
+}
+
+// zero leaves the default, a negative limit disables it
+func WithMaxInstructions(instructions int64) Option {
+	return func(l *Limits) {
+		switch {
+		case instructions > 0:
+			l.MaxInstructions = instructions
+		case instructions < 0:
+			l.MaxInstructions = 0
+		}
+	}
+}
+
+// zero leaves the default
+func WithMaxStackSize(values int) Option {
+	return func(l *Limits) {
+		if values > 0 {
+			l.MaxStackSize = values
+		}
+	}
+}
+
+// zero leaves the default
+func WithMaxCallDepth(calls int) Option {
+	return func(l *Limits) {
+		if calls > 0 {
+			l.MaxCallDepth = calls
+		}
+	}
+}
+
+// zero leaves the default
+func WithMaxStringSize(bytes int) Option {
+	return func(l *Limits) {
+		if bytes > 0 {
+			l.MaxStringSize = bytes
+		}
+	}
+}
@@ -2,15 +2,18 @@ package lua
 
 import (
 	"fmt"
+	"strings"
 
 	glua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
 )
 
 type SandboxConfig glua.LTable
 
 var sandBox *SandboxConfig
 
-func LoadSandboxConfig(filename string) error {
+// loads the sandbox every script runs in, and fills the pool of VMs with the limits of opts
+func LoadSandboxConfig(filename string, opts ...Option) error {
 	vm := glua.NewState()
 	defer vm.Close()
 
@@ -26,31 +29,34 @@ func LoadSandboxConfig(filename string) error {
 
 	sandBox = (*SandboxConfig)(envValue.(*glua.LTable))
 
-	for range bucket.max {
-		bucket.vms <- bucket.new()
+	limits := DefaultLimits
+	for _, opt := range opts {
+		opt(&limits)
 	}
 
-	return nil
-}
-
-// get sandbox environment for VM
-func GetSandBox(vm *glua.LState) *glua.LTable {
-	env := vm.GetGlobal("sandbox")
+	bucket.shutdown()
+	bucket = newBucket(limits)
+	bucket.fill()
 
-	if sandbox, ok := env.(*glua.LTable); ok {
-		return sandbox
-	}
 	return nil
 }
 
-func Get() *glua.LState {
-	return bucket.get()
+func Shutdown() {
+	bucket.shutdown()
 }
 
-func Put(L *glua.LState) {
-	bucket.put(L)
+// checks that the script compiles, without running it
+func CheckSyntax(script string) error {
+	_, err := Compile(script)
+	return err
 }
 
-func Shutdown() {
-	bucket.shutdown()
+// compiles the script, so it can be run in any VM
+func Compile(script string) (*glua.FunctionProto, error) {
+	chunk, err := parse.Parse(strings.NewReader(script), "<script>")
+	if err != nil {
+		return nil, err
+	}
+
+	return glua.Compile(chunk, "<script>")
 }
@@ -0,0 +1,16 @@
+package lua
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	scriptRuns = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "lua_script_runs_total",
+			Help: "Number of lua script runs, by their result",
+		},
+		[]string{"result"}, // ok, compile_error, no_vm, timeout, instruction_limit, memory_limit, runtime_error or returned_false
+	)
+)
@@ -0,0 +1,282 @@
+package lua
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"codeberg.org/miekg/dns"
+	"codeberg.org/miekg/dns/dnsutil"
+	glua "github.com/yuin/gopher-lua"
+)
+
+const maxBodySize = 1 * 1024 * 1024 // 1MB
+
+const tcpConnType = "tcp_conn"
+
+// Probe is the api of a script that checks a member itself.
+// every call connects to the address of the member, and is bounded by the context of the VM running the script:
+//
+//	tcp.connect([port]) -> conn | nil, err
+//	conn:send(data) -> true | nil, err
+//	conn:receive() -> line | nil, err
+//	conn:expect(text) -> first line containing text | nil, err
+//	conn:close()
+//	http.get(path [, headers]) -> {status, body, headers} | nil, err
+//	http.post(path, body [, headers]) -> {status, body, headers} | nil, err
+//	dns.query(name [, qtype [, port]]) -> {rcode, answers} | nil, err
+//
+// the path of a http request can also be a url, its host is then sent as Host header, while the member is still connected to.
+// dns queries go to port 53 of the member, unless another port is given.
+type Probe struct {
+	Addr string // ip:port of the member
+	Host string // Host header of http requests, the address if empty
+
+	mu    sync.Mutex
+	conns []net.Conn // closed when the script is done
+}
+
+type tcpConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// adds the probe api to the environment of a script run, e.g. as setup of Script.Run
+func (p *Probe) Register(L *glua.LState, env *glua.LTable) {
+	env.RawSetString("tcp", L.SetFuncs(L.NewTable(), map[string]glua.LGFunction{
+		"connect": p.tcpConnect,
+	}))
+	env.RawSetString("http", L.SetFuncs(L.NewTable(), map[string]glua.LGFunction{
+		"get":  p.httpGet,
+		"post": p.httpPost,
+	}))
+	env.RawSetString("dns", L.SetFuncs(L.NewTable(), map[string]glua.LGFunction{
+		"query": p.dnsQuery,
+	}))
+
+	if L.GetTypeMetatable(tcpConnType) == glua.LNil {
+		connMeta := L.NewTypeMetatable(tcpConnType)
+		connMeta.RawSetString("__index", L.SetFuncs(L.NewTable(), map[string]glua.LGFunction{
+			"send":    tcpSend,
+			"receive": tcpReceive,
+			"expect":  tcpExpect,
+			"close":   tcpClose,
+		}))
+	}
+}
+
+// closes every connection the script left open
+func (p *Probe) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range p.conns {
+		conn.Close()
+	}
+	p.conns = nil
+}
+
+func (p *Probe) tcpConnect(L *glua.LState) int {
+	addr := p.Addr
+	if port := L.OptString(1, ""); port != "" {
+		host, _, err := net.SplitHostPort(p.Addr)
+		if err != nil {
+			return fail(L, err)
+		}
+		addr = net.JoinHostPort(host, port)
+	}
+
+	ctx := contextOf(L)
+	conn, err := new(net.Dialer).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fail(L, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	p.mu.Lock()
+	p.conns = append(p.conns, conn)
+	p.mu.Unlock()
+
+	ud := L.NewUserData()
+	ud.Value = &tcpConn{conn: conn, reader: bufio.NewReader(io.LimitReader(conn, maxBodySize))}
+	L.SetMetatable(ud, L.GetTypeMetatable(tcpConnType))
+	L.Push(ud)
+	return 1
+}
+
+func checkConn(L *glua.LState) *tcpConn {
+	if conn, ok := L.CheckUserData(1).Value.(*tcpConn); ok {
+		return conn
+	}
+	L.ArgError(1, "tcp connection expected")
+	return nil
+}
+
+func tcpSend(L *glua.LState) int {
+	conn := checkConn(L)
+	if _, err := conn.conn.Write([]byte(L.CheckString(2))); err != nil {
+		return fail(L, err)
+	}
+	L.Push(glua.LTrue)
+	return 1
+}
+
+func tcpReceive(L *glua.LState) int {
+	line, err := checkConn(L).readLine()
+	if err != nil {
+		return fail(L, err)
+	}
+	L.Push(glua.LString(line))
+	return 1
+}
+
+func tcpExpect(L *glua.LState) int {
+	conn := checkConn(L)
+	text := L.CheckString(2)
+	for {
+		line, err := conn.readLine()
+		if err != nil {
+			return fail(L, fmt.Errorf("did not receive %q: %w", text, err))
+		}
+		if strings.Contains(line, text) {
+			L.Push(glua.LString(line))
+			return 1
+		}
+	}
+}
+
+func tcpClose(L *glua.LState) int {
+	checkConn(L).conn.Close()
+	return 0
+}
+
+// reads a line, without the line ending
+func (c *tcpConn) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (p *Probe) httpGet(L *glua.LState) int {
+	return p.httpDo(L, http.MethodGet, L.CheckString(1), "", L.OptTable(2, nil))
+}
+
+func (p *Probe) httpPost(L *glua.LState) int {
+	return p.httpDo(L, http.MethodPost, L.CheckString(1), L.CheckString(2), L.OptTable(3, nil))
+}
+
+func (p *Probe) httpDo(L *glua.LState, method, path, body string, headers *glua.LTable) int {
+	url := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		host := p.Host
+		if host == "" {
+			host = p.Addr
+		}
+		url = "http://" + host + "/" + strings.TrimPrefix(path, "/")
+	}
+
+	req, err := http.NewRequestWithContext(contextOf(L), method, url, strings.NewReader(body))
+	if err != nil {
+		return fail(L, err)
+	}
+	if headers != nil {
+		headers.ForEach(func(key, value glua.LValue) {
+			req.Header.Set(key.String(), value.String())
+		})
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return new(net.Dialer).DialContext(ctx, network, p.Addr) // the url only decides the Host header
+			},
+			TLSClientConfig:   &tls.Config{ServerName: req.URL.Hostname(), InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fail(L, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return fail(L, err)
+	}
+
+	respHeaders := L.NewTable()
+	for key, values := range resp.Header {
+		if len(values) > 0 {
+			respHeaders.RawSetString(key, glua.LString(values[0]))
+		}
+	}
+
+	result := L.NewTable()
+	result.RawSetString("status", glua.LNumber(resp.StatusCode))
+	result.RawSetString("body", glua.LString(respBody))
+	result.RawSetString("headers", respHeaders)
+	L.Push(result)
+	return 1
+}
+
+func (p *Probe) dnsQuery(L *glua.LState) int {
+	name := L.CheckString(1)
+	qtype, ok := dns.StringToType[strings.ToUpper(L.OptString(2, "A"))]
+	if !ok {
+		L.ArgError(2, "unknown qtype")
+		return 0
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr)
+	if err != nil {
+		return fail(L, err)
+	}
+	server := net.JoinHostPort(host, L.OptString(3, "53"))
+
+	resp, _, err := new(dns.Client).Exchange(contextOf(L), dns.NewMsg(dnsutil.Fqdn(name), qtype), "udp", server)
+	if err != nil {
+		return fail(L, err)
+	}
+
+	answers := L.NewTable()
+	for _, record := range resp.Answer {
+		if dns.RRToType(record) == qtype {
+			answers.Append(glua.LString(record.Data().String()))
+		}
+	}
+
+	result := L.NewTable()
+	result.RawSetString("rcode", glua.LString(dns.RcodeToString[resp.Rcode]))
+	result.RawSetString("answers", answers)
+	L.Push(result)
+	return 1
+}
+
+// returns nil and the reason, the lua convention for a call that failed
+func fail(L *glua.LState, err error) int {
+	L.Push(glua.LNil)
+	L.Push(glua.LString(err.Error()))
+	return 2
+}
+
+// the context of the VM, that bounds every call of the script
+func contextOf(L *glua.LState) context.Context {
+	if ctx := L.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
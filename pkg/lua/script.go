@@ -0,0 +1,200 @@
+package lua
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	glua "github.com/yuin/gopher-lua"
+)
+
+var (
+	ErrCompile          = errors.New("lua script does not compile")
+	ErrTimeout          = errors.New("lua script timed out")
+	ErrInstructionLimit = errors.New("lua script exceeded its instruction limit")
+	ErrMemoryLimit      = errors.New("lua script exceeded its memory limit")
+	ErrRuntime          = errors.New("lua script failed")
+	ErrReturnedFalse    = errors.New("lua script returned false")
+)
+
+// errors the VM raises when a script runs out of stack, and the error of the helpers for too large strings
+var memoryErrors = []string{"registry overflow", "stack overflow", "callstack overflow", ErrMemoryLimit.Error()}
+
+// Script is compiled on its first run, and then runs in any VM of the pool
+type Script struct {
+	source string
+
+	once  sync.Once
+	proto *glua.FunctionProto
+	err   error
+}
+
+func NewScript(source string) *Script {
+	return &Script{source: source}
+}
+
+// runs the script in a new environment on top of the sandbox, within the limits of the pool.
+// setup adds the values of this run to the environment, e.g. a response or a probe.
+// the script fails when it returns nil or false, with its optional second return value as reason.
+// the run is bounded by the timeout of the limits, unless ctx has a deadline
+func (s *Script) Run(ctx context.Context, setup func(L *glua.LState, env *glua.LTable)) error {
+	s.once.Do(func() {
+		s.proto, s.err = Compile(s.source)
+	})
+	if s.err != nil {
+		scriptRuns.WithLabelValues("compile_error").Inc()
+		return fmt.Errorf("%w: %w", ErrCompile, s.err)
+	}
+
+	pool := bucket
+	L, err := pool.get()
+	if err != nil {
+		scriptRuns.WithLabelValues("no_vm").Inc()
+		return err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, pool.limits.Timeout)
+		defer cancel()
+	}
+	budget := newInstructionBudget(ctx, pool.limits.MaxInstructions)
+	L.SetContext(budget)
+
+	env := newEnv(L, pool.limits)
+	if setup != nil {
+		setup(L, env)
+	}
+
+	fn := L.NewFunctionFromProto(s.proto)
+	L.SetFEnv(fn, env)
+	L.Push(fn)
+	err = L.PCall(0, 2, nil)
+	L.RemoveContext()
+
+	healthy, reason := glua.LValue(glua.LNil), glua.LValue(glua.LNil)
+	if err != nil {
+		pool.replace(L)
+	} else {
+		healthy, reason = L.Get(-2), L.Get(-1)
+		L.Pop(2)
+		pool.put(L)
+	}
+
+	switch {
+	case budget.exhausted():
+		scriptRuns.WithLabelValues("instruction_limit").Inc()
+		return fmt.Errorf("%w of %d", ErrInstructionLimit, pool.limits.MaxInstructions)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded): // also when the script returned after a call that ran out of time
+		scriptRuns.WithLabelValues("timeout").Inc()
+		return ErrTimeout
+	case err != nil && isMemoryError(err):
+		scriptRuns.WithLabelValues("memory_limit").Inc()
+		return fmt.Errorf("%w: %w", ErrMemoryLimit, err)
+	case err != nil:
+		scriptRuns.WithLabelValues("runtime_error").Inc()
+		return fmt.Errorf("%w: %w", ErrRuntime, err)
+	}
+
+	if healthy == glua.LNil || healthy == glua.LFalse {
+		scriptRuns.WithLabelValues("returned_false").Inc()
+		if reason != glua.LNil {
+			return fmt.Errorf("%w: %s", ErrReturnedFalse, reason.String())
+		}
+		return fmt.Errorf("%w: %s", ErrReturnedFalse, healthy.String())
+	}
+
+	scriptRuns.WithLabelValues("ok").Inc()
+	return nil
+}
+
+func isMemoryError(err error) bool {
+	for _, msg := range memoryErrors {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// context that is done once the script has run its instructions, or when its parent is done.
+// the VM checks Done before every instruction, so every call counts as one
+type instructionBudget struct {
+	context.Context
+	remaining atomic.Int64
+	limited   bool
+
+	once sync.Once
+	done chan struct{}
+}
+
+func newInstructionBudget(parent context.Context, instructions int64) *instructionBudget {
+	budget := &instructionBudget{
+		Context: parent,
+		limited: instructions > 0,
+		done:    make(chan struct{}),
+	}
+	budget.remaining.Store(instructions)
+	return budget
+}
+
+func (b *instructionBudget) Done() <-chan struct{} {
+	if b.limited && b.remaining.Add(-1) < 0 {
+		b.once.Do(func() { close(b.done) })
+		return b.done
+	}
+	return b.Context.Done()
+}
+
+func (b *instructionBudget) Err() error {
+	if b.exhausted() {
+		return ErrInstructionLimit
+	}
+	return b.Context.Err()
+}
+
+func (b *instructionBudget) exhausted() bool {
+	return b.limited && b.remaining.Load() < 0
+}
+
+// a copy of the sandbox with the helpers, so a script can not change what the next script sees
+func newEnv(L *glua.LState, limits Limits) *glua.LTable {
+	env := L.NewTable()
+	if sandBox != nil {
+		env = copyTable(L, (*glua.LTable)(sandBox), make(map[*glua.LTable]*glua.LTable))
+	}
+
+	if str, ok := env.RawGetString("string").(*glua.LTable); ok && str.RawGetString("rep") != glua.LNil {
+		str.RawSetString("rep", L.NewFunction(stringRep(limits.MaxStringSize)))
+	}
+	env.RawSetString("json", L.SetFuncs(L.NewTable(), map[string]glua.LGFunction{
+		"decode": jsonDecode(limits.MaxStringSize),
+		"encode": jsonEncode(limits.MaxStringSize),
+	}))
+	env.RawSetString("regexp", L.SetFuncs(L.NewTable(), map[string]glua.LGFunction{
+		"match": regexpMatch,
+		"find":  regexpFind,
+	}))
+
+	return env
+}
+
+// copies table deeply, tables that are referenced more than once are copied once
+func copyTable(L *glua.LState, table *glua.LTable, copies map[*glua.LTable]*glua.LTable) *glua.LTable {
+	copied := L.NewTable()
+	copies[table] = copied
+	table.ForEach(func(key, value glua.LValue) {
+		if nested, ok := value.(*glua.LTable); ok {
+			if nestedCopy, ok := copies[nested]; ok {
+				value = nestedCopy
+			} else {
+				value = copyTable(L, nested, copies)
+			}
+		}
+		copied.RawSet(key, value)
+	})
+	return copied
+}
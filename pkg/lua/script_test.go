@@ -0,0 +1,86 @@
+package lua
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	glua "github.com/yuin/gopher-lua"
+)
+
+func TestScript_Run(t *testing.T) {
+	if err := LoadSandboxConfig("../../sandbox.lua",
+		WithPoolSize(1),
+		WithPoolWait(10*time.Millisecond),
+		WithTimeout(time.Second),
+		WithMaxInstructions(10_000),
+		WithMaxCallDepth(50),
+		WithMaxStringSize(1024),
+	); err != nil {
+		t.Fatalf("could not load sandbox: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		script  string
+		wantErr error
+	}{
+		{"healthy", `return true`, nil},
+		{"returned false", `return false, "not ready"`, ErrReturnedFalse},
+		{"returned nil", `local x = 1`, ErrReturnedFalse},
+		{"does not compile", `return (`, ErrCompile},
+		{"runtime error", `error("boom")`, ErrRuntime},
+		{"endless loop", `while true do end`, ErrInstructionLimit},
+		{"endless loop in pcall", `while true do pcall(function() while true do end end) end`, ErrInstructionLimit},
+		{"deep recursion", `local function f() return f() + 1 end return f()`, ErrMemoryLimit},
+		{"large string", `return string.rep("x", 2048)`, ErrMemoryLimit},
+		{"json decode", `
+			local value = json.decode('{"status": "ok", "checks": [1, 2, 3]}')
+			return value.status == "ok" and #value.checks == 3`, nil},
+		{"json invalid", `
+			local value, err = json.decode("{")
+			return value == nil and err ~= nil`, nil},
+		{"json encode", `return json.encode({1, 2}) == "[1,2]" and json.encode({a = "b"}) == '{"a":"b"}'`, nil},
+		{"regexp", `
+			local version = regexp.find([[^v(\d+)\.(\d+)]], "v2.13")
+			return regexp.match("^ok", "ok!") and version[2] == "2" and version[3] == "13"`, nil},
+		{"sandbox is copied", `string.upper = nil return true`, nil},
+		{"sandbox is intact", `return string.upper("a") == "A"`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewScript(tt.script).Run(context.Background(), nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := NewScript(`return wait()`).Run(ctx, func(L *glua.LState, env *glua.LTable) {
+			env.RawSetString("wait", L.NewFunction(func(L *glua.LState) int {
+				<-L.Context().Done()
+				return 0
+			}))
+		})
+		if !errors.Is(err, ErrTimeout) {
+			t.Errorf("expected error: %v, got: %v", ErrTimeout, err)
+		}
+	})
+
+	t.Run("pool exhausted", func(t *testing.T) {
+		L, err := bucket.get()
+		if err != nil {
+			t.Fatalf("could not take the VM: %v", err)
+		}
+		defer bucket.put(L)
+
+		if err := NewScript(`return true`).Run(context.Background(), nil); !errors.Is(err, ErrNoVM) {
+			t.Errorf("expected error: %v, got: %v", ErrNoVM, err)
+		}
+	})
+}
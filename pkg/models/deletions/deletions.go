@@ -0,0 +1,16 @@
+package deletions
+
+import "time"
+
+// removal of services held back, because it would remove too many of the known services at once
+type PendingDeletion struct {
+	Source        string    `json:"source"`
+	ServiceIDs    []string  `json:"serviceIds"`
+	KnownServices int       `json:"knownServices"` // number of known services when the removal was held back
+	Since         time.Time `json:"since"`
+}
+
+// services removed after the pending deletion was confirmed
+type Confirmation struct {
+	Removed []string `json:"removed"`
+}
@@ -0,0 +1,22 @@
+package history
+
+import "time"
+
+// health of a member of a service group, with the results of its last health checks
+type MemberHistory struct {
+	ServiceID     string        `json:"serviceId"`
+	MemberOf      string        `json:"memberOf"`
+	Fqdn          string        `json:"fqdn"`
+	Datacenter    string        `json:"datacenter"`
+	Healthy       bool          `json:"healthy"`
+	Dampened      bool          `json:"dampened"`                // held down because its health flaps, until it is stable for the flap window
+	DampenedSince *time.Time    `json:"dampenedSince,omitempty"` // only set while dampened
+	Transitions   int           `json:"transitions"`             // health transitions within the flap window
+	Checks        []CheckResult `json:"checks"`                  // oldest first
+}
+
+type CheckResult struct {
+	Time      time.Time `json:"time"`
+	LatencyMs float64   `json:"latencyMs"`
+	Error     string    `json:"error,omitempty"` // empty if the check succeeded
+}
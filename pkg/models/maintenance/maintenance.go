@@ -0,0 +1,18 @@
+package maintenance
+
+import "time"
+
+// Window takes members out of rotation while it is open, their health checks keep running.
+// it covers the member ServiceID, or every member of MemberOf and/or in Datacenter.
+type Window struct {
+	ID         string    `json:"id"` // set when the window is created
+	ServiceID  string    `json:"serviceId,omitempty"`
+	MemberOf   string    `json:"memberOf,omitempty"`
+	Datacenter string    `json:"datacenter,omitempty"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	// cron expression in UTC of when the window opens again, for as long as from Start to End each time.
+	// a window without recurrence is removed once it has ended
+	Recurrence string `json:"recurrence,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
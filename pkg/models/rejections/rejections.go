@@ -0,0 +1,13 @@
+package rejections
+
+import "time"
+
+// GSLB - config in a config source that is not used, and why
+type Rejection struct {
+	Source    string    `json:"source"`
+	Record    string    `json:"record"`              // where the config is defined in the source
+	ServiceID string    `json:"serviceId,omitempty"` // empty if the config could not be parsed
+	MemberOf  string    `json:"memberOf,omitempty"`
+	Reasons   []string  `json:"reasons"`
+	Since     time.Time `json:"since"` // first time the record was rejected for these reasons
+}
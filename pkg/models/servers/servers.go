@@ -0,0 +1,19 @@
+package servers
+
+import "time"
+
+// reconciliation state of a dnsdist server
+type ServerStatus struct {
+	Name         string    `json:"name"`
+	Connected    bool      `json:"connected"` // whether the last sync could read the rules of the server
+	LastSync     time.Time `json:"lastSync,omitzero"`
+	LastError    string    `json:"lastError,omitempty"`
+	ObservedHash string    `json:"observedHash,omitempty"`
+	DesiredHash  string    `json:"desiredHash,omitempty"`
+	InSync       bool      `json:"inSync"`
+	DriftCount   int       `json:"driftCount"` // syncs that found the server out of sync, since the operator started
+
+	// names of the spoof rules that differ from the desired rules, as found by the last sync before it reconciled
+	Missing []string `json:"missing,omitempty"`
+	Extra   []string `json:"extra,omitempty"`
+}
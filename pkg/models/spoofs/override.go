@@ -1,8 +1,40 @@
 package spoofs
 
-import "net"
+import (
+	"net"
+	"time"
+)
+
+// what happened to an override
+const (
+	OVERRIDE_CREATED = "created"
+	OVERRIDE_UPDATED = "updated"
+	OVERRIDE_DELETED = "deleted"
+	OVERRIDE_EXPIRED = "expired"
+)
 
 type Override struct {
-	MemberOf string `json:"memberOf"`
-	IP       net.IP `json:"ip,omitempty"`
+	MemberOf  string    `json:"memberOf"`
+	IP        net.IP    `json:"ip,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt,omitzero"` // the health-driven active member is restored at this time, never if zero
+	Reason    string    `json:"reason,omitempty"`
+	CreatedBy string    `json:"createdBy,omitempty"` // set from the token of the caller
+	CreatedAt time.Time `json:"createdAt,omitzero"`
+}
+
+// an entry in the audit trail of overrides
+type OverrideEvent struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	User      string    `json:"user,omitempty"` // empty when the override expired
+	MemberOf  string    `json:"memberOf"`
+	IP        string    `json:"ip,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+type OverrideHistoryParams struct {
+	MemberOf string `param:"memberOf"`
+	User     string `param:"user"`
 }
@@ -1,13 +1,76 @@
 package spoofs
 
 import (
+	"slices"
+	"strconv"
+	"strings"
+
 	"github.com/vitistack/gslb-operator/pkg/models/pagination"
 )
 
+// separates the datacenters of a multi-record spoof, e.g. "DC1+DC2"
+const DC_SEPARATOR = "+"
+
+// separates the datacenter and the weight of a weighted spoof, e.g. "DC1@90"
+const WEIGHT_SEPARATOR = "@"
+
 type Spoof struct {
-	FQDN string `json:"fqdn"`
-	IP   string `json:"ip"`
-	DC   string `json:"datacenter"` // when active override, DC == "OVERRIDE"
+	FQDN string   `json:"fqdn"`
+	IP   string   `json:"ip"`
+	IPs  []string `json:"ips,omitempty"` // only set for multi-record spoofs, IP is then the first of IPs
+	DC   string   `json:"datacenter"`    // when active override, DC == "OVERRIDE"
+	// share of the traffic relative to the other spoofs for the same fqdn, 0 when the spoof gets all traffic
+	Weight int `json:"weight,omitempty"`
+}
+
+// creates a spoof that answers with all ips.
+// the ips are sorted, so the same set of addresses always gives the same spoof
+func NewSpoof(fqdn, dc string, ips ...string) Spoof {
+	sorted := slices.Clone(ips)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	spoof := Spoof{
+		FQDN: fqdn,
+		DC:   dc,
+	}
+	if len(sorted) > 0 {
+		spoof.IP = sorted[0]
+	}
+	if len(sorted) > 1 {
+		spoof.IPs = sorted
+	}
+
+	return spoof
+}
+
+// merges spoofs for the same fqdn into a single multi-record spoof
+func Merge(fqdn string, toMerge ...Spoof) Spoof {
+	dcs := make([]string, 0, len(toMerge))
+	ips := make([]string, 0, len(toMerge))
+	for _, spoof := range toMerge {
+		dcs = append(dcs, spoof.DC)
+		ips = append(ips, spoof.Addresses()...)
+	}
+	slices.Sort(dcs)
+
+	return NewSpoof(fqdn, strings.Join(slices.Compact(dcs), DC_SEPARATOR), ips...)
+}
+
+// returns every address the spoof answers with
+func (s Spoof) Addresses() []string {
+	if len(s.IPs) > 0 {
+		return s.IPs
+	}
+	if s.IP == "" {
+		return nil
+	}
+	return []string{s.IP}
+}
+
+// reports whether two spoofs result in the same dnsdist rule
+func (s Spoof) Equal(other Spoof) bool {
+	return s.Key() == other.Key() && slices.Equal(s.Addresses(), other.Addresses())
 }
 
 type SpoofResponse struct {
@@ -38,6 +101,10 @@ func NewSpoofResponse(items []Spoof, params *pagination.PaginationParams) *Spoof
 	return resp
 }
 
+// returns the name of the spoof, the weight is part of the name so a changed weight gives a new rule
 func (s Spoof) Key() string {
+	if s.Weight > 0 {
+		return s.FQDN + ":" + s.DC + WEIGHT_SEPARATOR + strconv.Itoa(s.Weight)
+	}
 	return s.FQDN + ":" + s.DC
 }
@@ -0,0 +1,33 @@
+package spoofs
+
+// outcome of configuring the spoofs of a service group on a single dnsdist server
+type ServerUpdate struct {
+	Server  string `json:"server"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+type OverrideResponse struct {
+	Override Override    `json:"override"`
+	Update   FleetUpdate `json:"update"`
+}
+
+// outcome of configuring the spoofs of a service group on every dnsdist server
+type FleetUpdate struct {
+	MemberOf string         `json:"memberOf"`
+	Servers  []ServerUpdate `json:"servers"`
+	Quorum   int            `json:"quorum"`               // servers that must take the change for it to stand
+	Applied  bool           `json:"applied"`              // a quorum took the change, the stragglers are retried
+	Reverted bool           `json:"rolledBack,omitempty"` // too few servers took the change, so it was undone on all of them and must be undone in the store
+}
+
+// returns the servers that did not take the change
+func (f FleetUpdate) Stragglers() []string {
+	stragglers := make([]string, 0)
+	for _, server := range f.Servers {
+		if !server.Success {
+			stragglers = append(stragglers, server.Server)
+		}
+	}
+	return stragglers
+}